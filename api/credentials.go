@@ -49,6 +49,21 @@ func (c *CredentialsClient) Get(ctx context.Context, path string) ([]apitypes.Cr
 	v := &url.Values{}
 	v.Set("path", path)
 
+	return c.get(ctx, v)
+}
+
+// GetVerified returns all credentials at the given path, after the daemon has
+// verified the signature and identity of every object they were read from.
+// It fails if any of them can't be verified.
+func (c *CredentialsClient) GetVerified(ctx context.Context, path string) ([]apitypes.CredentialEnvelope, error) {
+	v := &url.Values{}
+	v.Set("path", path)
+	v.Set("verify", "true")
+
+	return c.get(ctx, v)
+}
+
+func (c *CredentialsClient) get(ctx context.Context, v *url.Values) ([]apitypes.CredentialEnvelope, error) {
 	req, _, err := c.client.NewDaemonRequest("GET", "/credentials", v, nil)
 	if err != nil {
 		return nil, err
@@ -105,7 +120,7 @@ func createEnvelopeFromResp(c apitypes.CredentialResp) (*apitypes.CredentialEnve
 		}
 
 		cBody = &cBodyV1
-	case 2:
+	case 2, 3:
 		cBodyV2 := apitypes.CredentialV2{}
 		err := json.Unmarshal(c.Body, &cBodyV2)
 		if err != nil {
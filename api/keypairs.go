@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"net/url"
 
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/registry"
 )
@@ -49,3 +51,64 @@ func (k *KeyPairsClient) Revoke(ctx context.Context, orgID *identity.ID, output
 	_, err = k.client.DoWithProgress(ctx, req, nil, reqID, output)
 	return err
 }
+
+// Rotate replaces the keypairs for the user in the given org, cloning their
+// keyring memberships for the new keys and revoking the old ones.
+func (k *KeyPairsClient) Rotate(ctx context.Context, orgID *identity.ID, output ProgressFunc) error {
+	kpr := keyPairsRequest{OrgID: orgID}
+
+	req, reqID, err := k.client.NewDaemonRequest("POST", "/keypairs/rotate", nil, &kpr)
+	if err != nil {
+		return err
+	}
+
+	_, err = k.client.DoWithProgress(ctx, req, nil, reqID, output)
+	return err
+}
+
+// Verify verifies the claim tree of the given org, returning the result for
+// each user and machine owning keys in it.
+func (k *KeyPairsClient) Verify(ctx context.Context, orgID *identity.ID) ([]apitypes.KeyOwnerVerification, error) {
+	v := &url.Values{}
+	v.Set("org_id", orgID.String())
+
+	req, _, err := k.client.NewDaemonRequest("GET", "/keypairs/verify", v, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := []apitypes.KeyOwnerVerification{}
+	_, err = k.client.Do(ctx, req, &resp)
+	return resp, err
+}
+
+// Backup creates a recovery bundle holding the user's master key, so their
+// keypairs can be recovered if they forget their password.
+func (k *KeyPairsClient) Backup(ctx context.Context,
+	backupReq *apitypes.RecoveryBackupRequest) (*apitypes.RecoveryBackup, error) {
+
+	req, _, err := k.client.NewDaemonRequest("POST", "/keypairs/backup", nil, backupReq)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := apitypes.RecoveryBackup{}
+	_, err = k.client.Do(ctx, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Restore restores the master key held in a recovery bundle, encrypting it
+// with the user's new password. The user must log in again afterwards.
+func (k *KeyPairsClient) Restore(ctx context.Context, restoreReq *apitypes.RecoveryRestoreRequest) error {
+	req, _, err := k.client.NewDaemonRequest("POST", "/keypairs/restore", nil, restoreReq)
+	if err != nil {
+		return err
+	}
+
+	_, err = k.client.Do(ctx, req, nil)
+	return err
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"crypto/rand"
+	"time"
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/base64"
@@ -53,6 +54,80 @@ func (m *MachinesClient) Create(ctx context.Context, orgID, teamID *identity.ID,
 	return result, secret, nil
 }
 
+// CreateToken creates a new token for the given machine, returning the token
+// and its secret.
+func (m *MachinesClient) CreateToken(ctx context.Context, machineID *identity.ID,
+	output ProgressFunc) (*apitypes.MachineTokenSegment, *base64.Value, error) {
+
+	secret, err := createTokenSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mtcr := apitypes.MachineTokensCreateRequest{Secret: secret}
+	req, reqID, err := m.client.NewDaemonRequest("POST",
+		"/machines/"+machineID.String()+"/tokens", nil, &mtcr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &apitypes.MachineTokenSegment{}
+	_, err = m.client.DoWithProgress(ctx, req, result, reqID, output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, secret, nil
+}
+
+// ScheduleTokenRevocation asks the daemon to revoke the machine's token at
+// the given time. The daemon keeps the revocation until it has been made,
+// even if it is restarted.
+func (m *MachinesClient) ScheduleTokenRevocation(ctx context.Context, machineID,
+	tokenID *identity.ID, at time.Time) error {
+
+	mtrr := apitypes.MachineTokenRevocationRequest{RevokeAt: at}
+	req, _, err := m.client.NewDaemonRequest("POST",
+		"/machines/"+machineID.String()+"/tokens/"+tokenID.String()+"/revocation", nil, &mtrr)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.client.Do(ctx, req, nil)
+	return err
+}
+
+// Enroll enrolls this host as a machine with the given name, using an
+// enrollment code. The token secret is generated here and never leaves the
+// host; it is returned along with the new machine.
+func (m *MachinesClient) Enroll(ctx context.Context, code, name string,
+	output ProgressFunc) (*apitypes.MachineSegment, *base64.Value, error) {
+
+	secret, err := createTokenSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mer := apitypes.MachineEnrollRequest{
+		Code:   code,
+		Name:   name,
+		Secret: secret,
+	}
+
+	req, reqID, err := m.client.NewDaemonRequest("POST", "/machines/enroll", nil, &mer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &apitypes.MachineSegment{}
+	_, err = m.client.DoWithProgress(ctx, req, result, reqID, output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, secret, nil
+}
+
 func createTokenSecret() (*base64.Value, error) {
 	value := make([]byte, tokenSecretSize)
 	_, err := rand.Read(value)
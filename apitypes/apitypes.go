@@ -16,11 +16,12 @@ type ErrorType string
 
 // These are the possible error types.
 const (
-	BadRequestError     = "bad_request"
-	UnauthorizedError   = "unauthorized"
-	NotFoundError       = "not_found"
-	InternalServerError = "internal_server"
-	NotImplementedError = "not_implemented"
+	BadRequestError         = "bad_request"
+	UnauthorizedError       = "unauthorized"
+	NotFoundError           = "not_found"
+	InternalServerError     = "internal_server"
+	NotImplementedError     = "not_implemented"
+	VerificationFailedError = "verification_failed"
 )
 
 // Error represents standard formatted API errors from the daemon or registry.
@@ -100,6 +101,20 @@ func IsUnauthorizedError(err error) bool {
 	return false
 }
 
+// IsVerificationFailedError returns whether or not an error is a
+// VerificationFailedError.
+func IsVerificationFailedError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if apiErr, ok := err.(*Error); ok {
+		return apiErr.Type == VerificationFailedError
+	}
+
+	return false
+}
+
 // SessionType is the enumerated string type of sessions.
 type SessionType string
 
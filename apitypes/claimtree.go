@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/primitive"
 )
 
@@ -48,3 +49,50 @@ outerLoop:
 
 	return nil, ErrClaimCycleFound
 }
+
+// Trust levels for the public keys of a user or machine in an org's claim
+// tree.
+const (
+	// TrustedKeys means every key and claim was verified, and there are
+	// active signing and encryption keys.
+	TrustedKeys = "trusted"
+
+	// IncompleteKeys means every key and claim was verified, but either the
+	// signing or the encryption key is missing or revoked.
+	IncompleteKeys = "incomplete"
+
+	// RevokedKeys means every key and claim was verified, and every key has
+	// been revoked.
+	RevokedKeys = "revoked"
+
+	// UntrustedKeys means a key or claim could not be verified.
+	UntrustedKeys = "untrusted"
+)
+
+// PublicKeyVerification is the result of verifying a public key, and the
+// claims made against it.
+type PublicKeyVerification struct {
+	PublicKeyID *identity.ID      `json:"public_key_id"`
+	KeyType     primitive.KeyType `json:"key_type"`
+	Revoked     bool              `json:"revoked"`
+	Problems    []string          `json:"problems"`
+}
+
+// KeyOwnerVerification summarizes the verification of the public keys owned
+// by a user or machine in an org's claim tree.
+type KeyOwnerVerification struct {
+	OwnerID *identity.ID            `json:"owner_id"`
+	Name    string                  `json:"name"`
+	Type    string                  `json:"type"` // user, machine, or empty if unknown
+	Trust   string                  `json:"trust"`
+	Keys    []PublicKeyVerification `json:"keys"`
+}
+
+// Problems returns the problems found with all of the owner's keys.
+func (v *KeyOwnerVerification) Problems() []string {
+	var problems []string
+	for _, k := range v.Keys {
+		problems = append(problems, k.Problems...)
+	}
+	return problems
+}
@@ -1,13 +1,16 @@
 package apitypes
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
 )
 
 var errMistmatchedType = errors.New("Mismatched type and value in credential")
@@ -17,8 +20,16 @@ const (
 	stringCV
 	intCV
 	floatCV
+	referenceCV
+	objectCV
+	booleanCV
+	binaryCV
 )
 
+// ReferencePrefix is prepended to the string representation of a reference
+// credential value.
+const ReferencePrefix = "ref:"
+
 // CredentialEnvelope is an unencrypted credential object with a
 // deserialized body
 type CredentialEnvelope struct {
@@ -34,7 +45,8 @@ type CredentialResp struct {
 	Body    json.RawMessage `json:"body"`
 }
 
-// Credential interface is either a v1 or v2 credential object
+// Credential interface is either a v1 or v2 credential object. Version 3
+// credentials are represented by CredentialV2.
 type Credential interface {
 	GetName() string
 	GetOrgID() *identity.ID
@@ -84,6 +96,23 @@ func (c *BaseCredential) GetValue() *CredentialValue {
 type CredentialV2 struct {
 	BaseCredential
 	State string `json:"state"`
+
+	// Metadata optionally describes the credential. It is only stored with
+	// version 3 credentials.
+	Metadata *primitive.CredentialMetadata `json:"metadata,omitempty"`
+
+	// ClearMetadata discards the metadata of the previous version of the
+	// credential when writing, instead of merging Metadata into it.
+	ClearMetadata bool `json:"clear_metadata,omitempty"`
+
+	// References holds the paths of the credentials followed, in order, when
+	// resolving a reference value. It is populated by the daemon.
+	References []string `json:"references,omitempty"`
+
+	// ReferenceError explains why a reference value could not be resolved,
+	// in which case the value is still the reference. It is populated by the
+	// daemon.
+	ReferenceError string `json:"reference_error,omitempty"`
 }
 
 // GetValue returns the value object, unless unset then returns nil
@@ -112,13 +141,50 @@ func (c *CredentialValue) IsUnset() bool {
 	return c.cvtype == unsetCV
 }
 
-// String returns the string representation of this credential. It panics
+// IsReference returns if this credential refers to the value of another
+// credential.
+func (c *CredentialValue) IsReference() bool {
+	return c.cvtype == referenceCV
+}
+
+// Reference returns the path of the credential referred to by this
+// credential, or an empty string if it is not a reference.
+func (c *CredentialValue) Reference() string {
+	if c.cvtype != referenceCV {
+		return ""
+	}
+
+	return c.value
+}
+
+// IsBinary returns if this credential holds binary data, such as the contents
+// of a file.
+func (c *CredentialValue) IsBinary() bool {
+	return c.cvtype == binaryCV
+}
+
+// Bytes returns the binary data held by this credential, or nil if it does
+// not hold binary data.
+func (c *CredentialValue) Bytes() []byte {
+	if c.cvtype != binaryCV {
+		return nil
+	}
+
+	return c.raw.([]byte)
+}
+
+// String returns the string representation of this credential. Objects are
+// represented as JSON, and binary data is base64 encoded. It panics
 // if the credential was deleted.
 func (c *CredentialValue) String() string {
 	if c.cvtype == unsetCV {
 		panic("CredentialValue has been unset")
 	}
 
+	if c.cvtype == referenceCV {
+		return ReferencePrefix + c.value
+	}
+
 	return c.value
 }
 
@@ -139,20 +205,31 @@ func (c *CredentialValue) Raw() (interface{}, error) {
 	return c.raw, nil
 }
 
-// MarshalJSON implements the json.Marshaler interface.
-func (c *CredentialValue) MarshalJSON() ([]byte, error) {
-	impl := credentialImpl{Version: 1}
-
+// Type returns the name of the type of value held by this credential, as
+// stored: string, number, reference, object, boolean, binary or undefined.
+func (c *CredentialValue) Type() string {
 	switch c.cvtype {
 	case stringCV:
-		impl.Body.Type = "string"
-	case intCV:
-		impl.Body.Type = "number"
-	case floatCV:
-		impl.Body.Type = "number"
-	case unsetCV:
-		impl.Body.Type = "undefined"
+		return "string"
+	case intCV, floatCV:
+		return "number"
+	case referenceCV:
+		return "reference"
+	case objectCV:
+		return "object"
+	case booleanCV:
+		return "boolean"
+	case binaryCV:
+		return "binary"
+	default:
+		return "undefined"
 	}
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (c *CredentialValue) MarshalJSON() ([]byte, error) {
+	impl := credentialImpl{Version: 1}
+	impl.Body.Type = c.Type()
 
 	if c.cvtype != unsetCV {
 		v, err := json.Marshal(c.raw)
@@ -223,6 +300,51 @@ func (c *CredentialValue) UnmarshalJSON(b []byte) error {
 		}
 
 		c.value = v.String()
+	case "reference":
+		c.cvtype = referenceCV
+		var v string
+		err := json.Unmarshal(impl.Body.Value, &v)
+		if err != nil {
+			return errMistmatchedType
+		}
+
+		c.raw = v
+		c.value = v
+	case "object":
+		c.cvtype = objectCV
+		var v map[string]interface{}
+		err := json.Unmarshal(impl.Body.Value, &v)
+		if err != nil || v == nil {
+			return errMistmatchedType
+		}
+
+		o, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		c.raw = v
+		c.value = string(o)
+	case "boolean":
+		c.cvtype = booleanCV
+		var v bool
+		err := json.Unmarshal(impl.Body.Value, &v)
+		if err != nil {
+			return errMistmatchedType
+		}
+
+		c.raw = v
+		c.value = strconv.FormatBool(v)
+	case "binary":
+		c.cvtype = binaryCV
+		var v []byte
+		err := json.Unmarshal(impl.Body.Value, &v)
+		if err != nil {
+			return errMistmatchedType
+		}
+
+		c.raw = v
+		c.value = base64.StdEncoding.EncodeToString(v)
 	default:
 		return errors.New("Decoding type " + impl.Body.Type + " is not supported")
 	}
@@ -260,3 +382,51 @@ func NewFloatCredentialValue(f float64) *CredentialValue {
 		raw:    f,
 	}
 }
+
+// NewObjectCredentialValue creates a CredentialValue with a JSON object value.
+func NewObjectCredentialValue(o map[string]interface{}) (*CredentialValue, error) {
+	if o == nil {
+		return nil, errMistmatchedType
+	}
+
+	s, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CredentialValue{
+		cvtype: objectCV,
+		value:  string(s),
+		raw:    o,
+	}, nil
+}
+
+// NewBoolCredentialValue creates a CredentialValue with a boolean value.
+func NewBoolCredentialValue(b bool) *CredentialValue {
+	return &CredentialValue{
+		cvtype: booleanCV,
+		value:  strconv.FormatBool(b),
+		raw:    b,
+	}
+}
+
+// NewBinaryCredentialValue creates a CredentialValue with a binary value.
+func NewBinaryCredentialValue(b []byte) *CredentialValue {
+	return &CredentialValue{
+		cvtype: binaryCV,
+		value:  base64.StdEncoding.EncodeToString(b),
+		raw:    b,
+	}
+}
+
+// NewReferenceCredentialValue creates a CredentialValue referring to the
+// credential at the given path. The path may optionally be prefixed with
+// ReferencePrefix.
+func NewReferenceCredentialValue(path string) *CredentialValue {
+	path = strings.TrimPrefix(path, ReferencePrefix)
+	return &CredentialValue{
+		cvtype: referenceCV,
+		value:  path,
+		raw:    path,
+	}
+}
@@ -116,3 +116,162 @@ func TestCredentialValueUnmarshalJSON(t *testing.T) {
 
 	})
 }
+
+func TestCredentialValueReference(t *testing.T) {
+	t.Run("unmarshal", func(t *testing.T) {
+		v := map[string]interface{}{
+			"version": 1,
+			"body": map[string]interface{}{
+				"type":  "reference",
+				"value": "/org/proj/env/svc/*/1/name",
+			},
+		}
+
+		c, err := interfaceToCredentialValue(t, v)
+		if err != nil {
+			t.Error("Unable to decode credential value: " + err.Error())
+		}
+
+		if !c.IsReference() {
+			t.Error("value was not considered a reference")
+		}
+
+		expected := "/org/proj/env/svc/*/1/name"
+		if c.Reference() != expected {
+			t.Errorf("wrong reference! had: '%s' wanted: '%s'", c.Reference(), expected)
+		}
+
+		expectedStr := "ref:" + expected
+		if c.String() != expectedStr {
+			t.Errorf("wrong value! had: '%s' wanted: '%s'", c.String(), expectedStr)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		c := NewReferenceCredentialValue("ref:/org/proj/env/svc/*/1/name")
+
+		b, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal("Unable to encode credential value: " + err.Error())
+		}
+
+		out := CredentialValue{}
+		err = json.Unmarshal(b, &out)
+		if err != nil {
+			t.Fatal("Unable to decode credential value: " + err.Error())
+		}
+
+		if !out.IsReference() || out.Reference() != c.Reference() {
+			t.Errorf("wrong reference! had: '%s' wanted: '%s'", out.Reference(), c.Reference())
+		}
+	})
+
+	t.Run("string is not a reference", func(t *testing.T) {
+		c := NewStringCredentialValue("/org/proj/env/svc/*/1/name")
+		if c.IsReference() || c.Reference() != "" {
+			t.Error("string value was considered a reference")
+		}
+	})
+}
+
+func roundTripCredentialValue(t *testing.T, c *CredentialValue) *CredentialValue {
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal("Unable to encode credential value: " + err.Error())
+	}
+
+	out := CredentialValue{}
+	err = json.Unmarshal(b, &out)
+	if err != nil {
+		t.Fatal("Unable to decode credential value: " + err.Error())
+	}
+
+	return &out
+}
+
+func TestCredentialValueTypes(t *testing.T) {
+	t.Run("object", func(t *testing.T) {
+		v := map[string]interface{}{
+			"version": 1,
+			"body": map[string]interface{}{
+				"type": "object",
+				"value": map[string]interface{}{
+					"type":       "service_account",
+					"project_id": 10,
+				},
+			},
+		}
+
+		c, err := interfaceToCredentialValue(t, v)
+		if err != nil {
+			t.Fatal("Unable to decode credential value: " + err.Error())
+		}
+
+		expected := `{"project_id":10,"type":"service_account"}`
+		if c.String() != expected {
+			t.Errorf("wrong value! had: '%s' wanted: '%s'", c.String(), expected)
+		}
+
+		out := roundTripCredentialValue(t, c)
+		if out.String() != expected {
+			t.Errorf("wrong value! had: '%s' wanted: '%s'", out.String(), expected)
+		}
+
+		raw, err := out.Raw()
+		if err != nil {
+			t.Fatal("Unable to get raw value: " + err.Error())
+		}
+		if _, ok := raw.(map[string]interface{}); !ok {
+			t.Errorf("wrong raw type! had: %T", raw)
+		}
+	})
+
+	t.Run("object mismatched", func(t *testing.T) {
+		v := map[string]interface{}{
+			"version": 1,
+			"body": map[string]interface{}{
+				"type":  "object",
+				"value": "not an object",
+			},
+		}
+
+		_, err := interfaceToCredentialValue(t, v)
+		if err == nil {
+			t.Error("expected error, got none")
+		}
+	})
+
+	t.Run("boolean", func(t *testing.T) {
+		c := roundTripCredentialValue(t, NewBoolCredentialValue(true))
+
+		if c.String() != "true" {
+			t.Errorf("wrong value! had: '%s' wanted: 'true'", c.String())
+		}
+
+		raw, err := c.Raw()
+		if err != nil {
+			t.Fatal("Unable to get raw value: " + err.Error())
+		}
+		if b, ok := raw.(bool); !ok || !b {
+			t.Errorf("wrong raw value! had: %v", raw)
+		}
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		data := []byte{0x00, 0xff, 0x10, 'a'}
+		c := roundTripCredentialValue(t, NewBinaryCredentialValue(data))
+
+		if !c.IsBinary() {
+			t.Error("value was not considered binary")
+		}
+
+		if string(c.Bytes()) != string(data) {
+			t.Errorf("wrong value! had: '%v' wanted: '%v'", c.Bytes(), data)
+		}
+
+		expected := "AP8QYQ=="
+		if c.String() != expected {
+			t.Errorf("wrong value! had: '%s' wanted: '%s'", c.String(), expected)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package apitypes
 
 import (
+	"time"
+
 	"github.com/manifoldco/torus-cli/base64"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
@@ -10,10 +12,16 @@ import (
 type MachineSegment struct {
 	Machine     *envelope.Machine     `json:"machine"`
 	Memberships []envelope.Membership `json:"memberships"`
-	Tokens      []struct {
-		Token    *envelope.MachineToken `json:"token"`
-		Keypairs []PublicKeySegment     `json:"keypairs"`
-	} `json:"tokens"`
+	Tokens      []MachineTokenSegment `json:"tokens"`
+}
+
+// MachineTokenSegment represents a machine token and its connected keypairs.
+// LastLogin is recorded by the registry, and is nil if the token has never
+// been used to log in.
+type MachineTokenSegment struct {
+	Token     *envelope.MachineToken `json:"token"`
+	Keypairs  []PublicKeySegment     `json:"keypairs"`
+	LastLogin *time.Time             `json:"last_login_at,omitempty"`
 }
 
 // MachinesCreateRequest represents a request by a client to create a machine
@@ -24,3 +32,46 @@ type MachinesCreateRequest struct {
 	TeamID *identity.ID  `json:"team_id"`
 	Secret *base64.Value `json:"secret"`
 }
+
+// MachineTokensCreateRequest represents a request by a client to create a
+// new token for an existing machine, using the given secret.
+type MachineTokensCreateRequest struct {
+	Secret *base64.Value `json:"secret"`
+}
+
+// MachineTokenRevocationRequest represents a request by a client for the
+// daemon to revoke a machine token at a later time.
+type MachineTokenRevocationRequest struct {
+	RevokeAt time.Time `json:"revoke_at"`
+}
+
+// MachineEnrollmentCode is a short lived code with which a new host can enroll
+// itself as a machine of a role, without a token secret being handed to it.
+type MachineEnrollmentCode struct {
+	Code      string       `json:"code"`
+	OrgID     *identity.ID `json:"org_id"`
+	TeamID    *identity.ID `json:"team_id"`
+	CreatedBy *identity.ID `json:"created_by"`
+	Created   time.Time    `json:"created_at"`
+	Expires   time.Time    `json:"expires_at"`
+	Uses      int          `json:"uses"`
+}
+
+// MachineEnrollmentCodeCreateRequest represents a request to create an
+// enrollment code for a machine role, valid until Expires for the given
+// number of uses.
+type MachineEnrollmentCodeCreateRequest struct {
+	OrgID   *identity.ID `json:"org_id"`
+	TeamID  *identity.ID `json:"team_id"`
+	Expires time.Time    `json:"expires_at"`
+	Uses    int          `json:"uses"`
+}
+
+// MachineEnrollRequest represents a request by a client to enroll the host
+// as a machine with the given name, using an enrollment code and a locally
+// generated secret.
+type MachineEnrollRequest struct {
+	Code   string        `json:"code"`
+	Name   string        `json:"name"`
+	Secret *base64.Value `json:"secret"`
+}
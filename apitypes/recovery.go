@@ -0,0 +1,66 @@
+package apitypes
+
+import (
+	"time"
+
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/identity"
+)
+
+// Ways a recovery bundle's master key can be protected.
+const (
+	PassphraseRecovery = "passphrase"
+	ShamirRecovery     = "shamir"
+)
+
+// RecoveryBundle holds a user's master key, encrypted with a recovery key, so
+// their keypairs can be recovered if they forget their password.
+//
+// With the passphrase scheme, the recovery key is a passphrase chosen by the
+// user. With the shamir scheme, it is a random key split into Shares shares,
+// Threshold of which are needed to recover it.
+type RecoveryBundle struct {
+	Version      int           `json:"version"`
+	OwnerID      *identity.ID  `json:"owner_id"`
+	Created      time.Time     `json:"created_at"`
+	Scheme       string        `json:"scheme"`
+	Shares       int           `json:"shares,omitempty"`
+	Threshold    int           `json:"threshold,omitempty"`
+	PublicKeyIDs []identity.ID `json:"public_key_ids"`
+	MasterKey    *base64.Value `json:"master_key"`
+}
+
+// RecoveryShare is one share of a RecoveryBundle's recovery key.
+type RecoveryShare struct {
+	OwnerID *identity.ID  `json:"owner_id"`
+	Created time.Time     `json:"created_at"`
+	Share   *base64.Value `json:"share"`
+}
+
+// RecoveryBackup is a newly created recovery bundle, along with the shares of
+// its recovery key, if it has any.
+type RecoveryBackup struct {
+	Bundle *RecoveryBundle `json:"bundle"`
+	Shares []RecoveryShare `json:"shares"`
+}
+
+// RecoveryBackupRequest is a request to create a recovery bundle. Either
+// Passphrase, or Shares and Threshold must be set.
+type RecoveryBackupRequest struct {
+	Passphrase string `json:"passphrase,omitempty"`
+	Shares     int    `json:"shares,omitempty"`
+	Threshold  int    `json:"threshold,omitempty"`
+}
+
+// RecoveryRestoreRequest is a request to restore the master key held in a
+// recovery bundle, encrypting it with a new password.
+//
+// Force restores the bundle even if keypairs created since it would become
+// unusable.
+type RecoveryRestoreRequest struct {
+	Bundle     *RecoveryBundle `json:"bundle"`
+	Passphrase string          `json:"passphrase,omitempty"`
+	Shares     []RecoveryShare `json:"shares,omitempty"`
+	Password   string          `json:"password"`
+	Force      bool            `json:"force"`
+}
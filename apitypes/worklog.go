@@ -1,6 +1,7 @@
 package apitypes
 
 import (
+	"encoding/binary"
 	"errors"
 
 	"github.com/dchest/blake2b"
@@ -9,8 +10,9 @@ import (
 	"github.com/manifoldco/torus-cli/identity"
 )
 
-// WorklogType is the enumerated byte type of WorklogItems
-type WorklogType byte
+// WorklogType is the enumerated type of WorklogItems. Types are bit flags, so
+// they can be combined to match more than one type.
+type WorklogType uint16
 
 // The enumberated byte types of WorklogItems
 const (
@@ -18,8 +20,13 @@ const (
 	MissingKeypairsWorklogType
 	InviteApproveWorklogType
 	KeyringMembersWorklogType
+	SecretExpiryWorklogType
+	SecretAgeWorklogType
+	StaleMachineWorklogType
+	ClaimTreeWorklogType
+	ExpiredInviteWorklogType
 
-	AnyWorklogType WorklogType = 0xff
+	AnyWorklogType WorklogType = 0xffff
 )
 
 // WorklogResultType is the string type of worklog results
@@ -37,7 +44,10 @@ const (
 // wrong length.
 var ErrIncorrectWorklogIDLen = errors.New("Incorrect worklog ID length")
 
-const worklogIDLen = 9
+const (
+	worklogIDLen     = 9
+	worklogTypeBytes = 2
+)
 
 // WorklogID is the unique content-based identifier for worklog entries
 type WorklogID [worklogIDLen]byte
@@ -67,7 +77,7 @@ func (id WorklogID) String() string {
 
 // Type returns this id's type
 func (id WorklogID) Type() WorklogType {
-	return WorklogType(id[0])
+	return WorklogType(binary.BigEndian.Uint16(id[:worklogTypeBytes]))
 }
 
 // WorklogItem is an item that the daemon has identified as needing to be done
@@ -96,6 +106,16 @@ func (t WorklogType) String() string {
 		return "invite"
 	case KeyringMembersWorklogType:
 		return "keyring"
+	case SecretExpiryWorklogType:
+		return "expiry"
+	case SecretAgeWorklogType:
+		return "age"
+	case StaleMachineWorklogType:
+		return "machine"
+	case ClaimTreeWorklogType:
+		return "claims"
+	case ExpiredInviteWorklogType:
+		return "expired-invite"
 	default:
 		return "n/a"
 	}
@@ -104,16 +124,18 @@ func (t WorklogType) String() string {
 // CreateID creates and populates a WorklogID for the WorklogItem based on the
 // given type and its subject.
 func (w *WorklogItem) CreateID(worklogType WorklogType) {
-	h, err := blake2b.New(&blake2b.Config{Size: worklogIDLen - 1})
+	h, err := blake2b.New(&blake2b.Config{Size: worklogIDLen - worklogTypeBytes})
 	if err != nil { // this only happens with a bad config
 		panic(err)
 	}
 
-	h.Write([]byte{byte(worklogType)})
+	id := WorklogID{}
+	binary.BigEndian.PutUint16(id[:worklogTypeBytes], uint16(worklogType))
+
+	h.Write(id[:worklogTypeBytes])
 	h.Write([]byte(w.Subject))
 
-	id := WorklogID{byte(worklogType)}
-	copy(id[1:], h.Sum(nil))
+	copy(id[worklogTypeBytes:], h.Sum(nil))
 	w.ID = &id
 }
 
@@ -0,0 +1,27 @@
+package apitypes
+
+import "testing"
+
+func TestWorklogIDType(t *testing.T) {
+	types := []WorklogType{
+		SecretRotateWorklogType,
+		ClaimTreeWorklogType,
+		ExpiredInviteWorklogType,
+	}
+
+	for _, typ := range types {
+		item := WorklogItem{Subject: "subject"}
+		item.CreateID(typ)
+		if item.Type() != typ {
+			t.Errorf("Type() = %s, want %s", item.Type(), typ)
+		}
+
+		id, err := DecodeWorklogIDFromString(item.ID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != *item.ID {
+			t.Errorf("decoded id = %s, want %s", id, item.ID)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/dirprefs"
+	"github.com/manifoldco/torus-cli/errs"
+)
+
+func init() {
+	check := cli.Command{
+		Name:     "check",
+		Usage:    "Check that the secrets listed in .torus.json are set and valid",
+		Category: "SECRETS",
+		Flags: []cli.Flag{
+			stdOrgFlag,
+			stdProjectFlag,
+			stdEnvFlag,
+			serviceFlag("Use this service.", "default", true),
+			userFlag("Use this user.", false),
+			machineFlag("Use this machine.", false),
+			stdInstanceFlag,
+			cli.BoolFlag{
+				Name:  "all-environments, a",
+				Usage: "Check every environment in the project, instead of only the current one",
+			},
+			stdVerifyFlag,
+		},
+		Action: chain(
+			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+			setUserEnv, checkRequiredFlags, checkCmd,
+		),
+	}
+
+	Cmds = append(Cmds, check)
+}
+
+// secretProblem is a secret from the manifest that is missing or invalid.
+type secretProblem struct {
+	name    string
+	problem string
+}
+
+func checkCmd(ctx *cli.Context) error {
+	manifest, err := loadManifest()
+	if err != nil {
+		return err
+	}
+
+	envs := []string{ctx.String("environment")}
+	if ctx.Bool("all-environments") {
+		envs, err = projectEnvNames(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	for _, env := range envs {
+		err = ctx.Set("environment", env)
+		if err != nil {
+			return err
+		}
+
+		secrets, _, err := getSecrets(ctx)
+		if err != nil {
+			return err
+		}
+
+		problems := checkManifest(manifest, secrets)
+		if len(problems) == 0 {
+			fmt.Fprintf(w, "%s\tok\n", env)
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(w, "%s\t%d of %d secrets missing or invalid\n", env, len(problems), len(manifest))
+		for _, p := range problems {
+			fmt.Fprintf(w, "  %s\t%s\n", p.name, p.problem)
+		}
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return errs.NewExitError(fmt.Sprintf("\nThe secrets manifest is not met in %d of %d environments.",
+			failed, len(envs)))
+	}
+
+	return nil
+}
+
+// loadManifest returns the secrets manifest of the linked directory, or an
+// error if there is none.
+func loadManifest() ([]dirprefs.Secret, error) {
+	dPrefs, err := runDirPrefs()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := dPrefs.Manifest()
+	if len(manifest) == 0 {
+		return nil, errs.NewExitError("No secrets manifest found. List the secrets needed in .torus.json.")
+	}
+
+	return manifest, nil
+}
+
+// projectEnvNames returns the names of the environments in the project.
+func projectEnvNames(ctx *cli.Context) ([]string, error) {
+	c, client, err := NewAPIClient(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := client.Orgs.GetByName(c, ctx.String("org"))
+	if err != nil {
+		return nil, errs.NewErrorExitError("Failed to look up org.", err)
+	}
+	if org == nil {
+		return nil, errs.NewExitError("Org not found.")
+	}
+
+	projectName := ctx.String("project")
+	projects, err := listProjects(&c, client, org.ID, &projectName)
+	if err != nil {
+		return nil, errs.NewErrorExitError("Failed to look up project.", err)
+	}
+	if len(projects) != 1 {
+		return nil, errs.NewExitError("Project not found.")
+	}
+
+	envs, err := listEnvs(&c, client, org.ID, projects[0].ID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(envs))
+	for i, e := range envs {
+		names[i] = e.Body.Name
+	}
+
+	return names, nil
+}
+
+// checkManifest returns the secrets in the manifest that are missing from
+// secrets, or that don't have the type or match the pattern given for them.
+func checkManifest(manifest []dirprefs.Secret, secrets []apitypes.CredentialEnvelope) []secretProblem {
+	values := make(map[string]*apitypes.CredentialValue, len(secrets))
+	for _, secret := range secrets {
+		values[strings.ToLower((*secret.Body).GetName())] = (*secret.Body).GetValue()
+	}
+
+	unresolved := make(map[string]string)
+	for _, p := range unresolvedReferences(secrets) {
+		unresolved[strings.ToLower(p.name)] = p.problem
+	}
+
+	var problems []secretProblem
+	for _, s := range manifest {
+		value, ok := values[strings.ToLower(s.Name)]
+		if !ok || value == nil {
+			problems = append(problems, secretProblem{s.Name, "missing"})
+			continue
+		}
+
+		if problem, ok := unresolved[strings.ToLower(s.Name)]; ok {
+			problems = append(problems, secretProblem{s.Name, problem})
+			continue
+		}
+
+		if s.Type != "" && value.Type() != s.Type {
+			problems = append(problems, secretProblem{s.Name,
+				fmt.Sprintf("has type %s, expected %s", value.Type(), s.Type)})
+			continue
+		}
+
+		// Patterns are checked when the manifest is loaded. They don't apply
+		// to binary values.
+		if s.Pattern != "" && !value.IsBinary() &&
+			!regexp.MustCompile(s.Pattern).MatchString(value.String()) {
+			problems = append(problems, secretProblem{s.Name, "does not match " + s.Pattern})
+		}
+	}
+
+	return problems
+}
+
+// unresolvedReferences returns the secrets whose value is a reference the
+// daemon could not resolve.
+func unresolvedReferences(secrets []apitypes.CredentialEnvelope) []secretProblem {
+	var problems []secretProblem
+	for _, secret := range secrets {
+		v2, ok := (*secret.Body).(*apitypes.CredentialV2)
+		if !ok || v2.ReferenceError == "" {
+			continue
+		}
+
+		problems = append(problems, secretProblem{v2.GetName(),
+			"unresolved reference: " + v2.ReferenceError})
+	}
+
+	return problems
+}
+
+// formatProblems returns the problems as a list for an error message.
+func formatProblems(problems []secretProblem) string {
+	lines := make([]string, len(problems))
+	for i, p := range problems {
+		lines[i] = "  " + p.name + ": " + p.problem
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/dirprefs"
+)
+
+func TestCheckManifest(t *testing.T) {
+	secret := func(name string, value *apitypes.CredentialValue) apitypes.CredentialEnvelope {
+		var body apitypes.Credential = &apitypes.CredentialV2{
+			BaseCredential: apitypes.BaseCredential{Name: name, Value: value},
+		}
+		return apitypes.CredentialEnvelope{Body: &body}
+	}
+
+	secrets := []apitypes.CredentialEnvelope{
+		secret("db_url", apitypes.NewStringCredentialValue("mysql://db")),
+		secret("port", apitypes.NewIntCredentialValue(8080)),
+		secret("debug", apitypes.NewBoolCredentialValue(true)),
+		secret("cert", apitypes.NewBinaryCredentialValue([]byte{1, 2, 3})),
+		secret("removed", apitypes.NewUnsetCredentialValue()),
+	}
+
+	manifest := []dirprefs.Secret{
+		{Name: "db_url", Type: "string", Pattern: "^postgres://"},
+		{Name: "PORT", Type: "number", Pattern: "^[0-9]+$"},
+		{Name: "debug", Type: "string"},
+		{Name: "cert", Type: "binary", Pattern: "^x$"},
+		{Name: "removed"},
+		{Name: "api_key"},
+	}
+
+	want := []secretProblem{
+		{"db_url", "does not match ^postgres://"},
+		{"debug", "has type boolean, expected string"},
+		{"removed", "missing"},
+		{"api_key", "missing"},
+	}
+
+	problems := checkManifest(manifest, secrets)
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("checkManifest() = %v, want %v", problems, want)
+	}
+}
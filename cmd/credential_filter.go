@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// credentialFilterFlags are shared by the commands that list secrets, to
+// narrow the results down using the secrets' metadata.
+var credentialFilterFlags = []cli.Flag{
+	newSlicePlaceholder("tag", "TAG", "Only include secrets with this tag (can be repeated)",
+		"", "", false),
+	newPlaceholder("owner", "TEAM", "Only include secrets owned by this team", "", "", false),
+	newPlaceholder("expires-before", "DATE",
+		"Only include secrets that expire before this date (YYYY-MM-DD)", "", "", false),
+	cli.BoolFlag{
+		Name:  "expired",
+		Usage: "Only include secrets that have expired",
+	},
+}
+
+// credentialFilter matches credentials against the metadata filter flags.
+type credentialFilter struct {
+	tags          []string
+	owner         string
+	expiresBefore *time.Time
+	expiredAt     *time.Time
+}
+
+// newCredentialFilter creates a credentialFilter from the filter flags. It
+// returns nil if no filters were provided.
+func newCredentialFilter(ctx *cli.Context) (*credentialFilter, error) {
+	f := credentialFilter{owner: strings.ToLower(ctx.String("owner"))}
+	for _, tag := range ctx.StringSlice("tag") {
+		f.tags = append(f.tags, strings.ToLower(tag))
+	}
+
+	if raw := ctx.String("expires-before"); raw != "" {
+		if ctx.Bool("expired") {
+			return nil, errs.NewUsageExitError(
+				"Cannot specify --expired and --expires-before at the same time", ctx)
+		}
+
+		t, err := parseExpiry(raw)
+		if err != nil {
+			return nil, errs.NewUsageExitError(err.Error(), ctx)
+		}
+		f.expiresBefore = t
+	}
+
+	if ctx.Bool("expired") {
+		now := time.Now().UTC()
+		f.expiredAt = &now
+	}
+
+	if len(f.tags) == 0 && f.owner == "" && f.expiresBefore == nil && f.expiredAt == nil {
+		return nil, nil
+	}
+
+	return &f, nil
+}
+
+// Match returns whether the given credential's metadata satisfies every
+// filter. Credentials without metadata only match an empty filter.
+func (f *credentialFilter) Match(cred apitypes.Credential) bool {
+	if f == nil {
+		return true
+	}
+
+	var md *primitive.CredentialMetadata
+	if v2, ok := cred.(*apitypes.CredentialV2); ok {
+		md = v2.Metadata
+	}
+	if md == nil {
+		return false
+	}
+
+	if f.owner != "" && md.Owner != f.owner {
+		return false
+	}
+
+	for _, tag := range f.tags {
+		if !containsString(md.Tags, tag) {
+			return false
+		}
+	}
+
+	if f.expiresBefore != nil && (md.Expires == nil || !md.Expires.Before(*f.expiresBefore)) {
+		return false
+	}
+
+	// A credential expires at its expiry time, as in the worklog.
+	if f.expiredAt != nil && (md.Expires == nil || md.Expires.After(*f.expiredAt)) {
+		return false
+	}
+
+	return true
+}
+
+// Filter returns the credentials that match the filter.
+func (f *credentialFilter) Filter(creds []apitypes.CredentialEnvelope) []apitypes.CredentialEnvelope {
+	if f == nil {
+		return creds
+	}
+
+	matched := []apitypes.CredentialEnvelope{}
+	for _, cred := range creds {
+		if f.Match(*cred.Body) {
+			matched = append(matched, cred)
+		}
+	}
+
+	return matched
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestCredentialFilterMatch(t *testing.T) {
+	now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tagged := &apitypes.CredentialV2{Metadata: &primitive.CredentialMetadata{
+		Owner:   "ops",
+		Tags:    []string{"db", "prod"},
+		Expires: &past,
+	}}
+	untagged := &apitypes.CredentialV2{Metadata: &primitive.CredentialMetadata{
+		Expires: &future,
+	}}
+	bare := &apitypes.CredentialV2{}
+
+	tcs := []struct {
+		name   string
+		filter *credentialFilter
+		cred   apitypes.Credential
+		match  bool
+	}{
+		{"no filter", nil, bare, true},
+		{"no metadata", &credentialFilter{owner: "ops"}, bare, false},
+		{"owner", &credentialFilter{owner: "ops"}, tagged, true},
+		{"wrong owner", &credentialFilter{owner: "dev"}, tagged, false},
+		{"all tags", &credentialFilter{tags: []string{"db", "prod"}}, tagged, true},
+		{"missing tag", &credentialFilter{tags: []string{"db", "dev"}}, tagged, false},
+		{"expires before", &credentialFilter{expiresBefore: &now}, tagged, true},
+		{"expires after", &credentialFilter{expiresBefore: &now}, untagged, false},
+		{"expired", &credentialFilter{expiredAt: &now}, tagged, true},
+		{"expired now", &credentialFilter{expiredAt: &past}, tagged, true},
+		{"not expired", &credentialFilter{expiredAt: &now}, untagged, false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.Match(tc.cred); got != tc.match {
+				t.Errorf("Match() = %t, want %t", got, tc.match)
+			}
+		})
+	}
+}
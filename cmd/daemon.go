@@ -53,6 +53,13 @@ func init() {
 						Usage:  "Skip Torus root dir permission checks",
 						Hidden: true, // Just for system daemon use
 					},
+					cli.BoolFlag{
+						Name:  "system",
+						Usage: "Run as a system daemon, with a separate session for each connecting user",
+					},
+					newPlaceholder("tokens-dir", "DIR",
+						"Log users of a system daemon in with the machine token in DIR/<username>.environment",
+						"", "", false),
 				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.Bool("foreground") {
@@ -147,7 +154,14 @@ func spawnDaemon() error {
 }
 
 func startDaemon(ctx *cli.Context) error {
-	noPermissionCheck := ctx.Bool("no-permission-check")
+	system := ctx.Bool("system")
+	if !system && ctx.String("tokens-dir") != "" {
+		return errs.NewUsageExitError("--tokens-dir can only be used with --system", ctx)
+	}
+
+	// The root dir of a system daemon is shared with the users connecting to
+	// it, so it cannot pass the permission check.
+	noPermissionCheck := ctx.Bool("no-permission-check") || system
 	torusRoot, err := config.CreateTorusRoot(!noPermissionCheck)
 	if err != nil {
 		return errs.NewErrorExitError("Failed to initialize Torus root dir.", err)
@@ -170,16 +184,21 @@ func startDaemon(ctx *cli.Context) error {
 		return errs.NewErrorExitError("Failed to load config.", err)
 	}
 
-	daemon, err := daemon.New(cfg, noPermissionCheck)
+	var d *daemon.Daemon
+	if system {
+		d, err = daemon.NewSystem(cfg, ctx.String("tokens-dir"))
+	} else {
+		d, err = daemon.New(cfg, noPermissionCheck)
+	}
 	if err != nil {
 		return errs.NewErrorExitError("Failed to create daemon.", err)
 	}
 
-	go watch(daemon)
-	defer daemon.Shutdown()
+	go watch(d)
+	defer d.Shutdown()
 
-	log.Printf("v%s of the Daemon is now listening on %s", cfg.Version, daemon.Addr())
-	err = daemon.Run()
+	log.Printf("v%s of the Daemon is now listening on %s", cfg.Version, d.Addr())
+	err = d.Run()
 	if err != nil {
 		log.Printf("Error while running daemon.\n%s", err)
 	}
@@ -4,12 +4,14 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
 	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/prefs"
 )
 
 // Standard flags with generic descriptions
@@ -23,8 +25,53 @@ var (
 		Name:  "yes, y",
 		Usage: "Automatically accept confirmation dialogues.",
 	}
+
+	stdVerifyFlag = cli.BoolFlag{
+		Name:  "verify",
+		Usage: "Verify the signature of every secret and keyring before use, failing if any can't be trusted.",
+	}
 )
 
+// ProfileFlag selects a profile from .torusrc. It is accepted by the app and,
+// through WithProfileFlag, by every command, so it can be given before or
+// after the command name.
+var ProfileFlag = newPlaceholder("profile", "PROFILE",
+	"Use this profile from .torusrc, instead of the one set with 'torus profile use'",
+	"", prefs.ProfileEnv, false)
+
+// SetProfileEnv exports the profile given with --profile. The profile is read
+// from the environment when loading preferences, and passed on to a daemon
+// started by the command.
+func SetProfileEnv(ctx *cli.Context) error {
+	if profile := ctx.String("profile"); profile != "" {
+		return os.Setenv(prefs.ProfileEnv, profile)
+	}
+	return nil
+}
+
+// WithProfileFlag adds ProfileFlag to the commands and all of their
+// subcommands, exporting the profile before the command runs.
+func WithProfileFlag(cmds []cli.Command) []cli.Command {
+	out := make([]cli.Command, len(cmds))
+	for i, c := range cmds {
+		c.Flags = append(append([]cli.Flag{}, c.Flags...), ProfileFlag)
+		c.Subcommands = WithProfileFlag(c.Subcommands)
+
+		before := c.Before
+		c.Before = func(ctx *cli.Context) error {
+			err := SetProfileEnv(ctx)
+			if err != nil || before == nil {
+				return err
+			}
+			return before(ctx)
+		}
+
+		out[i] = c
+	}
+
+	return out
+}
+
 func formatFlag(defaultValue, description string) cli.Flag {
 	return newPlaceholder("format, f", "FORMAT", description,
 		defaultValue, "TORUS_FORMAT", false)
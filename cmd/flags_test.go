@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/prefs"
+)
+
+func TestWithProfileFlag(t *testing.T) {
+	var profile string
+	record := func(ctx *cli.Context) error {
+		profile = os.Getenv(prefs.ProfileEnv)
+		return nil
+	}
+
+	cmds := []cli.Command{
+		{Name: "view", Action: record},
+		{Name: "run", Flags: []cli.Flag{stdVerifyFlag}, Action: record},
+		{
+			Name: "machines",
+			Subcommands: []cli.Command{
+				{
+					Name: "tokens",
+					Subcommands: []cli.Command{
+						{Name: "list", Action: record},
+					},
+				},
+			},
+		},
+	}
+
+	tcs := []struct {
+		name string
+		args []string
+	}{
+		{"before the command", []string{"--profile", "staging", "view"}},
+		{"after the command", []string{"view", "--profile", "staging"}},
+		{"with other flags", []string{"run", "--verify", "--profile", "staging", "--", "env"}},
+		{"after a subcommand", []string{"machines", "tokens", "list", "--profile", "staging"}},
+		{"between subcommands", []string{"machines", "--profile", "staging", "tokens", "list"}},
+	}
+
+	defer os.Unsetenv(prefs.ProfileEnv)
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Unsetenv(prefs.ProfileEnv)
+			profile = ""
+
+			app := cli.NewApp()
+			app.Commands = WithProfileFlag(cmds)
+			app.Flags = []cli.Flag{ProfileFlag}
+			app.Before = SetProfileEnv
+
+			err := app.Run(append([]string{"torus"}, tc.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if profile != "staging" {
+				t.Errorf("got profile %q, want staging", profile)
+			}
+		})
+	}
+
+	if len(cmds[0].Flags) != 0 {
+		t.Error("WithProfileFlag modified the commands given to it")
+	}
+}
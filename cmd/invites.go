@@ -5,7 +5,7 @@ import "github.com/urfave/cli"
 func init() {
 	invites := cli.Command{
 		Name:     "invites",
-		Usage:    "View and accept organization invites",
+		Usage:    "Manage organization invites",
 		Category: "ORGANIZATIONS",
 		Subcommands: []cli.Command{
 			{
@@ -15,6 +15,9 @@ func init() {
 				Flags: []cli.Flag{
 					orgFlag("org to invite user to", true),
 					newSlicePlaceholder("team, t", "TEAM", "team to add user to", "member", "", true),
+					newPlaceholder("file, f", "FILE",
+						"CSV file of email addresses, each followed by the teams to add them to",
+						"", "", false),
 				},
 				Action: chain(
 					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
@@ -60,6 +63,31 @@ func init() {
 					loadPrefDefaults, setUserEnv, checkRequiredFlags, invitesAccept,
 				),
 			},
+			{
+				Name:      "revoke",
+				Usage:     "Revoke an invitation to join an organization that has not been approved",
+				ArgsUsage: "<email>",
+				Flags: []cli.Flag{
+					orgFlag("org to revoke invite for", true),
+					stdAutoAcceptFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs,
+					loadPrefDefaults, setUserEnv, checkRequiredFlags, invitesRevoke,
+				),
+			},
+			{
+				Name:      "resend",
+				Usage:     "Send an invitation that has not been accepted again",
+				ArgsUsage: "<email>",
+				Flags: []cli.Flag{
+					orgFlag("org to resend invite for", true),
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs,
+					loadPrefDefaults, setUserEnv, checkRequiredFlags, invitesResend,
+				),
+			},
 		},
 	}
 	Cmds = append(Cmds, invites)
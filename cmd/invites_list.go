@@ -14,6 +14,7 @@ import (
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/hints"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
 )
 
 func invitesList(ctx *cli.Context) error {
@@ -84,6 +85,8 @@ func invitesList(ctx *cli.Context) error {
 	}
 	fmt.Println("")
 
+	cutoff := time.Now().UTC().Add(-cfg.InviteExpireAfter)
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
 	fmt.Fprintln(w, "EMAIL\tUSERNAME\tSTATE\tINVITED BY\tCREATION DATE")
 	fmt.Fprintln(w, " \t \t \t ")
@@ -97,7 +100,12 @@ func invitesList(ctx *cli.Context) error {
 		if invite.Body.InviteeID != nil {
 			invitee = usernameByID[invite.Body.InviteeID.String()]
 		}
-		fmt.Fprintln(w, identity+"\t"+invitee+"\t"+invite.Body.State+"\t"+inviter+"\t"+invite.Body.Created.Format(time.RFC3339))
+		state := invite.Body.State
+		if cfg.InviteExpireAfter > 0 && state != primitive.OrgInviteAcceptedState &&
+			state != primitive.OrgInviteApprovedState && invite.Body.Created.Before(cutoff) {
+			state += " (expired)"
+		}
+		fmt.Fprintln(w, identity+"\t"+invitee+"\t"+state+"\t"+inviter+"\t"+invite.Body.Created.Format(time.RFC3339))
 	}
 	w.Flush()
 	fmt.Println("")
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+const (
+	revokeInviteFailed = "Could not revoke invitation to org, please try again."
+	resendInviteFailed = "Could not resend invitation to org, please try again."
+)
+
+func invitesRevoke(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return errs.NewUsageExitError("Missing email", ctx)
+	}
+	email := args[0]
+
+	client, invite, err := findInvite(ctx, email, []string{
+		primitive.OrgInvitePendingState,
+		primitive.OrgInviteAssociatedState,
+		primitive.OrgInviteAcceptedState,
+	})
+	if err != nil {
+		return err
+	}
+
+	preamble := "You are about to revoke the invite for " + email + " to the " + ctx.String("org") + " org."
+	abortErr := ConfirmDialogue(ctx, nil, &preamble, "", true)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	err = client.OrgInvites.Revoke(context.Background(), invite.ID)
+	if err != nil {
+		return errs.NewErrorExitError(revokeInviteFailed, err)
+	}
+
+	fmt.Println("The invite for " + email + " has been revoked.")
+	return nil
+}
+
+func invitesResend(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return errs.NewUsageExitError("Missing email", ctx)
+	}
+	email := args[0]
+
+	// Once an invite is accepted, it's waiting on an admin rather than the
+	// person invited.
+	client, invite, err := findInvite(ctx, email, []string{
+		primitive.OrgInvitePendingState,
+		primitive.OrgInviteAssociatedState,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.OrgInvites.Resend(context.Background(), invite.ID)
+	if err != nil {
+		return errs.NewErrorExitError(resendInviteFailed, err)
+	}
+
+	fmt.Println("The invite for " + email + " has been sent again, with a new code.")
+	return nil
+}
+
+// findInvite returns the invite for the email address to the org given by
+// the org flag, if it is in one of the states.
+func findInvite(ctx *cli.Context, email string, states []string) (*api.Client, *envelope.OrgInvite, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := api.NewClient(cfg)
+
+	org, err := client.Orgs.GetByName(context.Background(), ctx.String("org"))
+	if err != nil {
+		return nil, nil, errs.NewExitError("Could not retrieve org information.")
+	}
+	if org == nil {
+		return nil, nil, errs.NewExitError("Org not found.")
+	}
+
+	invites, err := client.OrgInvites.List(context.Background(), org.ID, states, email)
+	if err != nil {
+		return nil, nil, errs.NewExitError("Failed to retrieve invites, please try again.")
+	}
+
+	for _, invite := range invites {
+		if invite.Body.Email == email {
+			return client, &invite, nil
+		}
+	}
+
+	return nil, nil, errs.NewExitError("Invite not found.")
+}
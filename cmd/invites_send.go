@@ -17,6 +17,10 @@ import (
 const orgInviteFailed = "Could not send invitation to org, please try again."
 
 func invitesSend(ctx *cli.Context) error {
+	if ctx.String("file") != "" {
+		return invitesSendFile(ctx)
+	}
+
 	args := ctx.Args()
 	if len(args) < 1 || args[0] == "" {
 		return errs.NewUsageExitError("Missing email", ctx)
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// inviteConcurrency is how many invites from a file are sent at once.
+const inviteConcurrency = 4
+
+// inviteRow is an invite to send, read from the file given to
+// `invites send --file`.
+type inviteRow struct {
+	email string
+	teams []string
+}
+
+// inviteResult is the outcome of sending the invite for a row.
+type inviteResult struct {
+	result string
+	failed bool
+}
+
+func invitesSendFile(ctx *cli.Context) error {
+	if len(ctx.Args()) > 0 {
+		return errs.NewUsageExitError("Cannot specify an email and --file at the same time", ctx)
+	}
+
+	f, err := os.Open(ctx.String("file"))
+	if err != nil {
+		return errs.NewErrorExitError("Could not read invites file", err)
+	}
+	defer f.Close()
+
+	rows, err := parseInviteFile(f, ctx.StringSlice("team"))
+	if err != nil {
+		return errs.NewExitError("Invalid invites file: " + err.Error())
+	}
+
+	c, client, err := NewAPIClient(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	org, err := client.Orgs.GetByName(c, ctx.String("org"))
+	if err != nil {
+		return errs.NewExitError(orgInviteFailed)
+	}
+	if org == nil {
+		return errs.NewExitError("Org not found.")
+	}
+
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewExitError(orgInviteFailed)
+	}
+
+	// Every team is checked before any invite is sent, so a typo doesn't
+	// leave the file half sent.
+	teamIDs, err := inviteTeamIDs(c, client, org.ID, rows)
+	if err != nil {
+		return err
+	}
+
+	invited, err := invitedEmails(c, client, org.ID)
+	if err != nil {
+		return err
+	}
+
+	results := make([]inviteResult, len(rows))
+	sem := make(chan struct{}, inviteConcurrency)
+	var wg sync.WaitGroup
+	for i, row := range rows {
+		if state, ok := invited[strings.ToLower(row.email)]; ok {
+			results[i] = inviteResult{result: inviteSkipReason(state)}
+			continue
+		}
+
+		ids := make([]identity.ID, len(row.teams))
+		for j, name := range row.teams {
+			ids[j] = teamIDs[name]
+		}
+
+		wg.Add(1)
+		go func(i int, email string, ids []identity.ID) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := client.OrgInvites.Send(c, email, *org.ID, *session.ID(), ids)
+			switch {
+			case err == nil:
+				results[i] = inviteResult{result: "sent"}
+			case strings.Contains(err.Error(), "resource exists"):
+				results[i] = inviteResult{result: "skipped, already invited"}
+			default:
+				results[i] = inviteResult{result: "failed: " + err.Error(), failed: true}
+			}
+		}(i, row.email, ids)
+	}
+	wg.Wait()
+
+	sent, failed := 0, 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "EMAIL\tTEAMS\tRESULT")
+	for i, row := range rows {
+		r := results[i]
+		switch {
+		case r.failed:
+			failed++
+		case r.result == "sent":
+			sent++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", row.email, strings.Join(row.teams, ", "), r.result)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d invitation(s) sent, %d skipped, %d failed.\n", sent, len(rows)-sent-failed, failed)
+	if failed > 0 {
+		return errs.NewExitError("Some invitations could not be sent. Run the command again to retry them.")
+	}
+
+	return nil
+}
+
+// parseInviteFile parses a CSV file of email addresses, each followed by the
+// names of the teams to invite it to. The team names can be given in separate
+// fields, or together in one quoted, comma separated field. Every invite also
+// gets the default teams, and the member team. A header row starting with
+// "email" is skipped.
+func parseInviteFile(r io.Reader, defaultTeams []string) ([]inviteRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []inviteRow
+	seen := make(map[string]int)
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		email := strings.TrimSpace(record[0])
+		if n == 1 && strings.EqualFold(email, "email") {
+			continue
+		}
+
+		if !govalidator.IsEmail(email) {
+			return nil, fmt.Errorf("row %d: invalid email %q", n, email)
+		}
+		if prev, ok := seen[strings.ToLower(email)]; ok {
+			return nil, fmt.Errorf("row %d: %s is already listed in row %d", n, email, prev)
+		}
+		seen[strings.ToLower(email)] = n
+
+		var teams []string
+		for _, field := range record[1:] {
+			teams = append(teams, strings.Split(field, ",")...)
+		}
+		teams = append(teams, defaultTeams...)
+		teams = append(teams, primitive.MemberTeamName)
+
+		rows = append(rows, inviteRow{email: email, teams: uniqueTeamNames(teams)})
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no invites listed")
+	}
+
+	return rows, nil
+}
+
+// uniqueTeamNames returns the non-empty names, trimmed and without
+// duplicates, in the order they were first given.
+func uniqueTeamNames(names []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		unique = append(unique, name)
+	}
+
+	return unique
+}
+
+// inviteTeamIDs looks up every team the rows are invited to, returning their
+// ids by name, or an error listing the teams that don't exist.
+func inviteTeamIDs(c context.Context, client *api.Client, orgID *identity.ID,
+	rows []inviteRow) (map[string]identity.ID, error) {
+
+	ids := make(map[string]identity.ID)
+	var missing []string
+	for _, row := range rows {
+		for _, name := range row.teams {
+			if _, ok := ids[name]; ok {
+				continue
+			}
+
+			teams, err := client.Teams.GetByName(c, orgID, name)
+			if err != nil {
+				return nil, errs.NewErrorExitError("Unable to lookup team "+name+".", err)
+			}
+			if len(teams) < 1 || isMachineTeam(teams[0].Body) {
+				missing = append(missing, name)
+				ids[name] = identity.ID{}
+				continue
+			}
+
+			ids[name] = *teams[0].ID
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errs.NewExitError("Unknown team(s): " + strings.Join(missing, ", "))
+	}
+
+	return ids, nil
+}
+
+// invitedEmails returns the state of each invite to the org, keyed by the
+// lower cased email address it was sent to.
+func invitedEmails(c context.Context, client *api.Client, orgID *identity.ID) (map[string]string, error) {
+	states := []string{
+		primitive.OrgInvitePendingState,
+		primitive.OrgInviteAssociatedState,
+		primitive.OrgInviteAcceptedState,
+		primitive.OrgInviteApprovedState,
+	}
+
+	invites, err := client.OrgInvites.List(c, orgID, states, "")
+	if err != nil {
+		return nil, errs.NewErrorExitError("Failed to retrieve invites, please try again.", err)
+	}
+
+	invited := make(map[string]string, len(invites))
+	for _, invite := range invites {
+		invited[strings.ToLower(invite.Body.Email)] = invite.Body.State
+	}
+
+	return invited, nil
+}
+
+// inviteSkipReason describes why an invite in the given state isn't sent
+// again.
+func inviteSkipReason(state string) string {
+	if state == primitive.OrgInviteApprovedState {
+		return "skipped, already a member"
+	}
+
+	return "skipped, already invited"
+}
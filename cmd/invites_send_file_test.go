@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseInviteFile(t *testing.T) {
+	tcs := []struct {
+		name  string
+		input string
+		rows  []inviteRow
+		err   bool
+	}{
+		{
+			name:  "quoted teams with header",
+			input: "email,teams\nalice@example.com,\"api, ops\"\nbob@example.com\n",
+			rows: []inviteRow{
+				{"alice@example.com", []string{"api", "ops", "member"}},
+				{"bob@example.com", []string{"member"}},
+			},
+		},
+		{
+			name:  "teams in separate fields",
+			input: "alice@example.com,api,ops,api\n",
+			rows: []inviteRow{
+				{"alice@example.com", []string{"api", "ops", "member"}},
+			},
+		},
+		{name: "invalid email", input: "alice,api\n", err: true},
+		{name: "duplicate email", input: "alice@example.com\nALICE@example.com\n", err: true},
+		{name: "empty", input: "email,teams\n", err: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			rows, err := parseInviteFile(strings.NewReader(tc.input), []string{"member"})
+			if tc.err != (err != nil) {
+				t.Fatalf("error = %v, want error %t", err, tc.err)
+			}
+			if !reflect.DeepEqual(rows, tc.rows) {
+				t.Errorf("rows = %v, want %v", rows, tc.rows)
+			}
+		})
+	}
+}
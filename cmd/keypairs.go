@@ -10,6 +10,7 @@ import (
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/errs"
@@ -49,6 +50,59 @@ func init() {
 					setUserEnv, checkRequiredFlags, generateKeypairs,
 				),
 			},
+			{
+				Name:  "rotate",
+				Usage: "Replace your keypairs for an organization, revoking the old ones",
+				Flags: []cli.Flag{
+					orgFlag("org to rotate keypairs for", true),
+					stdAutoAcceptFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setUserEnv, checkRequiredFlags, rotateKeypairs,
+				),
+			},
+			{
+				Name:  "verify",
+				Usage: "Verify the keys and claims of everyone in an organization",
+				Flags: []cli.Flag{
+					orgFlag("org to verify keys for", true),
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setUserEnv, checkRequiredFlags, verifyKeypairs,
+				),
+			},
+			{
+				Name:      "backup",
+				Usage:     "Export a recovery bundle for your keypairs, in case you forget your password",
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					newPlaceholder("shares", "N",
+						"Split the recovery key into N shares instead of using a passphrase", "", "", false),
+					newPlaceholder("threshold", "K",
+						"Number of shares needed to restore (default: a majority)", "", "", false),
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, backupKeypairs,
+				),
+			},
+			{
+				Name:      "restore",
+				Usage:     "Restore your keypairs from a recovery bundle, setting a new password",
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					newSlicePlaceholder("share", "FILE", "Recovery share to restore with", "", "", false),
+					cli.BoolFlag{
+						Name:  "force",
+						Usage: "Restore even if keypairs created since the backup become unusable",
+					},
+					stdAutoAcceptFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, restoreKeypairs,
+				),
+			},
 			{
 				Name:  "revoke",
 				Usage: "Revoke the keypairs for an organization (used for testing only)",
@@ -236,6 +290,101 @@ func generateKeypairsForOrg(c context.Context, ctx *cli.Context, client *api.Cli
 	return nil
 }
 
+func rotateKeypairs(ctx *cli.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	orgName := ctx.String("org")
+	org, err := client.Orgs.GetByName(c, orgName)
+	if err != nil || org == nil {
+		return errs.NewExitError("Org '" + orgName + "' not found.")
+	}
+
+	preamble := "You are about to rotate your keypairs for " + orgName + ". " +
+		"Your keyring memberships will be cloned for the new keypairs, and " +
+		"the current keypairs will be revoked."
+	abortErr := ConfirmDialogue(ctx, nil, &preamble, "", true)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	err = client.KeyPairs.Rotate(c, org.ID, progress)
+	if err != nil {
+		msg := fmt.Sprintf("Error while rotating keypairs. Run '%s keypairs rotate' again to resume.",
+			ctx.App.Name)
+		return errs.NewErrorExitError(msg, err)
+	}
+
+	fmt.Println("Keypairs rotated.")
+	return nil
+}
+
+func verifyKeypairs(ctx *cli.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	orgName := ctx.String("org")
+	org, err := client.Orgs.GetByName(c, orgName)
+	if err != nil || org == nil {
+		return errs.NewExitError("Org '" + orgName + "' not found.")
+	}
+
+	owners, err := client.KeyPairs.Verify(c, org.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Could not verify keys.", err)
+	}
+
+	fmt.Println("")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OWNER\tTYPE\tKEYS\tTRUST")
+	fmt.Fprintln(w, " \t \t \t ")
+	untrusted := 0
+	for _, o := range owners {
+		ownerType := o.Type
+		if ownerType == "" {
+			ownerType = "-"
+		}
+		if o.Trust == apitypes.UntrustedKeys {
+			untrusted++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", o.Name, ownerType, len(o.Keys), o.Trust)
+	}
+	w.Flush()
+	fmt.Println("")
+
+	for _, o := range owners {
+		problems := o.Problems()
+		if len(problems) == 0 {
+			continue
+		}
+
+		fmt.Printf("Problems with the keys of %s:\n", o.Name)
+		for _, p := range problems {
+			fmt.Printf("  - %s\n", p)
+		}
+		fmt.Println("")
+	}
+
+	if untrusted > 0 {
+		return errs.NewExitError(fmt.Sprintf("Keys for %d of %d owners could not be verified.",
+			untrusted, len(owners)))
+	}
+
+	fmt.Println("All keys verified.")
+	return nil
+}
+
 func revokeKeypairs(ctx *cli.Context) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+)
+
+// recoverySharePath returns the path the nth share of the recovery bundle at
+// path is written to.
+func recoverySharePath(path string, n int) string {
+	return fmt.Sprintf("%s.share-%d", path, n)
+}
+
+// recoveryBackupRequest builds a backup request from the --shares and
+// --threshold flags, or by prompting for a recovery passphrase.
+func recoveryBackupRequest(ctx *cli.Context) (*apitypes.RecoveryBackupRequest, error) {
+	rawShares := ctx.String("shares")
+	rawThreshold := ctx.String("threshold")
+
+	if rawShares == "" {
+		if rawThreshold != "" {
+			return nil, errs.NewUsageExitError("--threshold can only be used with --shares", ctx)
+		}
+
+		label := "Recovery Passphrase"
+		passphrase, err := PasswordPrompt(true, &label)
+		if err != nil {
+			return nil, err
+		}
+		return &apitypes.RecoveryBackupRequest{Passphrase: passphrase}, nil
+	}
+
+	shares, err := strconv.Atoi(rawShares)
+	if err != nil || shares < 2 {
+		return nil, errs.NewUsageExitError("--shares must be a number of at least 2", ctx)
+	}
+
+	threshold := shares/2 + 1
+	if rawThreshold != "" {
+		threshold, err = strconv.Atoi(rawThreshold)
+		if err != nil || threshold < 2 || threshold > shares {
+			return nil, errs.NewUsageExitError(
+				"--threshold must be a number between 2 and the number of shares", ctx)
+		}
+	}
+
+	return &apitypes.RecoveryBackupRequest{Shares: shares, Threshold: threshold}, nil
+}
+
+func backupKeypairs(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return errs.NewUsageExitError("A file to write the recovery bundle to is required", ctx)
+	}
+	path := args[0]
+
+	req, err := recoveryBackupRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Never overwrite an existing bundle or share; it may be the only copy.
+	paths := []string{path}
+	for i := 1; i <= req.Shares; i++ {
+		paths = append(paths, recoverySharePath(path, i))
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return errs.NewExitError(p + " already exists.")
+		}
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	backup, err := client.KeyPairs.Backup(c, req)
+	if err != nil {
+		return errs.NewErrorExitError("Could not create recovery bundle.", err)
+	}
+
+	bundle, err := json.MarshalIndent(backup.Bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeSecretFile(path, bundle)
+	if err != nil {
+		return errs.NewErrorExitError("Could not write recovery bundle.", err)
+	}
+
+	for i, s := range backup.Shares {
+		share, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		err = writeSecretFile(recoverySharePath(path, i+1), share)
+		if err != nil {
+			return errs.NewErrorExitError("Could not write recovery share.", err)
+		}
+	}
+
+	fmt.Printf("Recovery bundle for %d keypairs written to %s.\n",
+		len(backup.Bundle.PublicKeyIDs), path)
+	if len(backup.Shares) > 0 {
+		fmt.Printf("Give each of the %d shares (%s) to a different admin. "+
+			"%d of them are needed to restore.\n", len(backup.Shares),
+			recoverySharePath(path, 1)+", ...", backup.Bundle.Threshold)
+	}
+	fmt.Println("Keep the bundle somewhere safe; it is only useful with the " +
+		"recovery passphrase or shares.")
+
+	return nil
+}
+
+func restoreKeypairs(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return errs.NewUsageExitError("A recovery bundle file is required", ctx)
+	}
+
+	bundle := apitypes.RecoveryBundle{}
+	err := readJSONFile(args[0], &bundle)
+	if err != nil {
+		return errs.NewErrorExitError("Could not read recovery bundle.", err)
+	}
+
+	req := &apitypes.RecoveryRestoreRequest{
+		Bundle: &bundle,
+		Force:  ctx.Bool("force"),
+	}
+
+	switch bundle.Scheme {
+	case apitypes.ShamirRecovery:
+		sharePaths := ctx.StringSlice("share")
+		if len(sharePaths) < bundle.Threshold {
+			return errs.NewUsageExitError(fmt.Sprintf(
+				"%d of the %d recovery shares are required, use --share for each",
+				bundle.Threshold, bundle.Shares), ctx)
+		}
+
+		for _, p := range sharePaths {
+			share := apitypes.RecoveryShare{}
+			err = readJSONFile(p, &share)
+			if err != nil {
+				return errs.NewErrorExitError("Could not read recovery share "+p+".", err)
+			}
+			req.Shares = append(req.Shares, share)
+		}
+	default:
+		label := "Recovery Passphrase"
+		req.Passphrase, err = PasswordPrompt(false, &label)
+		if err != nil {
+			return err
+		}
+	}
+
+	label := "New Password"
+	req.Password, err = PasswordPrompt(true, &label)
+	if err != nil {
+		return err
+	}
+
+	preamble := "You are about to restore your keypairs from a recovery bundle. " +
+		"Your password will be changed to the new password entered above."
+	abortErr := ConfirmDialogue(ctx, nil, &preamble, "", true)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewErrorExitError("Error fetching user details", err)
+	}
+
+	err = client.KeyPairs.Restore(c, req)
+	if err != nil {
+		return errs.NewErrorExitError("Could not restore keypairs.", err)
+	}
+
+	// The password has changed, so log the user in again
+	err = performLogin(c, client, session.Email(), req.Password, false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Keypairs restored.")
+	return nil
+}
+
+func readJSONFile(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
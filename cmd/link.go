@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/urfave/cli"
@@ -16,6 +17,7 @@ import (
 	"github.com/manifoldco/torus-cli/hints"
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/prefs"
+	"github.com/manifoldco/torus-cli/promptui"
 )
 
 func init() {
@@ -28,6 +30,10 @@ func init() {
 				Name:  "force, f",
 				Usage: "Overwrite existing organization and project links.",
 			},
+			envFlag("Use this environment by default in this directory.", false),
+			serviceFlag("Use this service by default in this directory.", "", false),
+			newPlaceholder("instance, i", "INSTANCE", "Use this instance by default in this directory.",
+				"", "TORUS_INSTANCE", false),
 			cli.BoolFlag{
 				Name:   "bare",
 				Usage:  "Skip creation of default service.",
@@ -133,6 +139,33 @@ func linkCmd(ctx *cli.Context) error {
 		}
 	}
 
+	// Ask the user for the environment and service to use by default
+	envs, err := listEnvs(&c, client, org.ID, project.ID, nil)
+	if err != nil {
+		return err
+	}
+	envNames := make([]string, len(envs))
+	for i, e := range envs {
+		envNames[i] = e.Body.Name
+	}
+	eName, err := selectLinkDefault("environment", envNames, ctx.String("environment"))
+	if err != nil {
+		return err
+	}
+
+	services, err := listServices(&c, client, org.ID, project.ID, nil)
+	if err != nil {
+		return err
+	}
+	serviceNames := make([]string, len(services))
+	for i, s := range services {
+		serviceNames[i] = s.Body.Name
+	}
+	sName, err := selectLinkDefault("service", serviceNames, ctx.String("service"))
+	if err != nil {
+		return err
+	}
+
 	// write out the link
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -144,6 +177,9 @@ func linkCmd(ctx *cli.Context) error {
 
 	dPrefs.Organization = oName
 	dPrefs.Project = pName
+	dPrefs.Environment = eName
+	dPrefs.Service = sName
+	dPrefs.Instance = ctx.String("instance")
 	dPrefs.Path = filepath.Join(cwd, ".torus.json")
 
 	err = dPrefs.Save()
@@ -156,6 +192,15 @@ func linkCmd(ctx *cli.Context) error {
 	w := tabwriter.NewWriter(os.Stdout, 2, 0, 1, ' ', 0)
 	fmt.Fprintf(w, "Org:\t%s\n", oName)
 	fmt.Fprintf(w, "Project:\t%s\n", pName)
+	if eName != "" {
+		fmt.Fprintf(w, "Environment:\t%s\n", eName)
+	}
+	if sName != "" {
+		fmt.Fprintf(w, "Service:\t%s\n", sName)
+	}
+	if dPrefs.Instance != "" {
+		fmt.Fprintf(w, "Instance:\t%s\n", dPrefs.Instance)
+	}
 	w.Flush()
 	fmt.Printf("\nUse '%s status' to view your full working context.\n", ctx.App.Name)
 
@@ -166,3 +211,32 @@ func linkCmd(ctx *cli.Context) error {
 	hints.Display([]string{"context", "set", "run", "view"})
 	return nil
 }
+
+// selectLinkDefault prompts the user to pick the default environment or
+// service for a linked directory, unless one was given. There is no default
+// if the project has none to pick from.
+func selectLinkDefault(kind string, names []string, name string) (string, error) {
+	label := "Default " + kind
+	if name != "" {
+		for _, n := range names {
+			if n == name {
+				fmt.Println(promptui.SuccessfulValue(label, name))
+				return name, nil
+			}
+		}
+
+		fmt.Println(promptui.FailedValue(label, name))
+		return "", errs.NewExitError(strings.Title(kind) + " not found.")
+	}
+
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	name, err := SelectDefaultPrompt("Select default "+kind, names)
+	if err != nil {
+		return "", handleSelectError(err, "Selection of default "+kind+" failed.")
+	}
+
+	return name, nil
+}
@@ -2,9 +2,13 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/urfave/cli"
 
@@ -17,7 +21,12 @@ import (
 	"github.com/manifoldco/torus-cli/registry"
 )
 
-var targetMap = []string{"orgs", "projects", "envs", "services"}
+// targetMap maps the number of defined path segments to the type of object
+// being listed. Paths reaching the identity, instance or secret segments list
+// the matching secrets.
+var targetMap = []string{
+	"orgs", "projects", "envs", "services", "secrets", "secrets", "secrets",
+}
 
 func init() {
 	ls := cli.Command{
@@ -25,13 +34,21 @@ func init() {
 		ArgsUsage: "<path>",
 		Usage:     "Explore all objects your account has access to",
 		Category:  "SECRETS",
-		Flags: []cli.Flag{
-			formatFlag("simple", "Format used to display data (simple, verbose)"),
+		Flags: append([]cli.Flag{
+			formatFlag("simple", "Format used to display data (simple, verbose, json)"),
 			cli.BoolFlag{
 				Name:  "verbose, v",
 				Usage: "Lists the types of resources and source path (shortcut for --format verbose)",
 			},
-		},
+			cli.BoolFlag{
+				Name:  "recursive, r",
+				Usage: "List all secrets contained within the path",
+			},
+			cli.BoolFlag{
+				Name:  "tree, t",
+				Usage: "Display the org, project, environment, service and secret hierarchy as a tree",
+			},
+		}, credentialFilterFlags...),
 		Action: chain(
 			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
 			checkRequiredFlags, listObjects,
@@ -66,7 +83,7 @@ func listObjects(ctx *cli.Context) error {
 	if ctx.Bool("verbose") {
 		format = "verbose"
 	}
-	if format != "verbose" && format != "simple" {
+	if format != "verbose" && format != "simple" && format != "json" {
 		return errs.NewUsageExitError("Invalid format", ctx)
 	}
 
@@ -76,16 +93,28 @@ func listObjects(ctx *cli.Context) error {
 			"Note: arguments containing wildcards must be wrapped in quotes.", ctx)
 	}
 
+	filter, err := newCredentialFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool("tree") {
+		return listTree(c, client, args, format, filter)
+	}
+
 	cpathExp, target, err := identifyTarget(args, recursive)
 	if err != nil || cpathExp == nil {
 		return errs.NewUsageExitError("Invalid path supplied", ctx)
 	}
+	if filter != nil && target != "secrets" {
+		return errs.NewUsageExitError("Filters can only be used when listing secrets", ctx)
+	}
 
 	var orgName string
 	var projectTree registry.ProjectTreeSegment
 	var projectMap map[string]envelope.Project
 	if target != "orgs" {
-		tree, err := projectTreeForOrg(c, client, cpathExp)
+		tree, err := projectTreeForOrg(c, client, cpathExp.Org.String())
 		if err != nil {
 			return err
 		}
@@ -95,7 +124,7 @@ func listObjects(ctx *cli.Context) error {
 	}
 
 	// Pull list of paths for the target object
-	var paths []string
+	paths := []string{}
 	var pathsErr error
 	switch target {
 	case "orgs":
@@ -130,22 +159,17 @@ func listObjects(ctx *cli.Context) error {
 			}
 		}
 	case "secrets":
-		segments := strings.Split(args[0], "/")
-		targetName := segments[len(segments)-1:][0]
-		if targetName == "**" {
-			targetName = "*"
-		}
-		pexp, err := pathexp.Parse(cpathExp.String())
+		searchPath, targetName, err := secretSearchPath(args[0])
 		if err != nil {
-			pathsErr = err
+			pathsErr = errs.NewUsageExitError("Invalid path supplied", ctx)
 			break
 		}
-		creds, err := client.Credentials.Search(c, pexp.String())
+		creds, err := client.Credentials.Search(c, searchPath)
 		if err != nil {
 			pathsErr = err
 			break
 		}
-		for _, cred := range creds {
+		for _, cred := range filter.Filter(creds) {
 			body := *cred.Body
 			if body.GetValue() == nil {
 				continue
@@ -163,10 +187,13 @@ func listObjects(ctx *cli.Context) error {
 	}
 
 	// Final output of paths
+	sort.Strings(paths)
+	if format == "json" {
+		return printJSON(paths)
+	}
 	if format == "verbose" {
 		fmt.Println(strings.ToUpper(target) + "\n")
 	}
-	sort.Strings(paths)
 	for _, p := range paths {
 		fmt.Println(p)
 	}
@@ -254,9 +281,276 @@ func identifyTarget(args []string, recursive bool) (*pathexp.PathExp, string, er
 	return pexp, target, nil
 }
 
-// retrieve the projecttree for non-recursive operations
-func projectTreeForOrg(c context.Context, client *api.Client, cpathExp *pathexp.PathExp) (*registry.ProjectTreeSegment, error) {
-	org, err := client.Orgs.GetByName(c, cpathExp.Org.String())
+// secretSearchPath expands the supplied path into a path expression covering
+// every secret contained within it, along with the secret name to match.
+// Unspecified segments are filled with full globs. The secret name is taken
+// from the final segment of a complete path, or from the segment following a
+// double glob.
+func secretSearchPath(path string) (string, string, error) {
+	name := "*"
+	children := strings.HasSuffix(path, "/")
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	last := segments[len(segments)-1]
+
+	hasDoubleGlob := false
+	for _, s := range segments {
+		if s == "**" {
+			hasDoubleGlob = true
+		}
+	}
+
+	if !children && (len(segments) == 8 || (hasDoubleGlob && last != "**")) {
+		name = last
+		segments = segments[:len(segments)-1]
+	}
+
+	if !pathexp.ValidSecret(name) {
+		return "", "", errors.New("invalid secret name")
+	}
+
+	if hasDoubleGlob {
+		pe, err := pathexp.ParsePartial(strings.Join(segments, "/"))
+		if err != nil {
+			return "", "", err
+		}
+		return pe.String(), name, nil
+	}
+
+	if len(segments) > 7 {
+		return "", "", errors.New("too many path segments")
+	}
+	for len(segments) < 7 {
+		segments = append(segments, "*")
+	}
+
+	searchPath := strings.Join(segments, "/")
+	if _, err := pathexp.ParsePartial(searchPath); err != nil {
+		return "", "", err
+	}
+
+	return searchPath, name, nil
+}
+
+// lsNode is a single object within the hierarchy displayed by ls --tree
+type lsNode struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Path     string    `json:"path"`
+	Children []*lsNode `json:"children,omitempty"`
+}
+
+// listTree displays the org, project, environment, service and secret
+// hierarchy contained within the supplied path.
+func listTree(c context.Context, client *api.Client, args []string, format string,
+	filter *credentialFilter) error {
+	path := "/"
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if len(path) == 0 || path[:1] != "/" {
+		return errs.NewExitError("path must start with /")
+	}
+
+	orgSeg := "*"
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(segments) > 1 && segments[1] != "" && segments[1] != "**" {
+		orgSeg = segments[1]
+	}
+
+	var orgNames []string
+	if pathexp.ValidSlug(orgSeg) {
+		orgNames = []string{orgSeg}
+	} else {
+		orgs, _, err := orgsList()
+		if err != nil {
+			return err
+		}
+		for _, o := range orgs {
+			if matchPathSegment(orgSeg, o.Body.Name) {
+				orgNames = append(orgNames, o.Body.Name)
+			}
+		}
+	}
+
+	nodes := []*lsNode{}
+	for _, orgName := range orgNames {
+		// Search within this org, using the rest of the supplied path.
+		orgPath := "/" + orgName
+		if len(segments) > 2 {
+			orgPath += "/" + strings.Join(segments[2:], "/")
+		}
+		if strings.HasSuffix(path, "/") {
+			orgPath += "/"
+		}
+
+		node, err := orgTree(c, client, orgName, orgPath, filter)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if format == "json" {
+		return printJSON(nodes)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	for _, n := range nodes {
+		printTreeNode(w, n, "", "", format == "verbose")
+	}
+	w.Flush()
+
+	hints.Display([]string{"path", "view"})
+	return nil
+}
+
+// orgTree builds the hierarchy of objects contained within the given path for
+// a single org.
+func orgTree(c context.Context, client *api.Client, orgName, path string,
+	filter *credentialFilter) (*lsNode, error) {
+	pe, err := pathexp.ParsePartial(strings.TrimSuffix(path, "/"))
+	if err != nil {
+		return nil, errs.NewExitError("Invalid path supplied")
+	}
+
+	searchPath, secretName, err := secretSearchPath(path)
+	if err != nil {
+		return nil, errs.NewExitError("Invalid path supplied")
+	}
+
+	tree, err := projectTreeForOrg(c, client, orgName)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := client.Credentials.Search(c, searchPath)
+	if err != nil {
+		return nil, errs.NewErrorExitError("Could not retrieve secrets", err)
+	}
+	creds = filter.Filter(creds)
+
+	orgNode := &lsNode{Name: orgName, Type: "org", Path: "/" + orgName}
+	for _, p := range matchingProjects(pe, *tree) {
+		projectPath := orgNode.Path + "/" + p.Body.Name
+		projectNode := &lsNode{Name: p.Body.Name, Type: "project", Path: projectPath}
+
+		for _, e := range tree.Envs {
+			if *e.Body.ProjectID != *p.ID || !segmentContains(pe.Envs, e.Body.Name) {
+				continue
+			}
+
+			envPath := projectPath + "/" + e.Body.Name
+			envNode := &lsNode{Name: e.Body.Name, Type: "env", Path: envPath}
+
+			for _, s := range tree.Services {
+				if *s.Body.ProjectID != *p.ID || !segmentContains(pe.Services, s.Body.Name) {
+					continue
+				}
+
+				servicePath := envPath + "/" + s.Body.Name
+				serviceNode := &lsNode{Name: s.Body.Name, Type: "service", Path: servicePath}
+
+				seen := make(map[string]bool)
+				for _, cred := range creds {
+					body := *cred.Body
+					if body.GetValue() == nil || *body.GetProjectID() != *p.ID {
+						continue
+					}
+
+					cpe := body.GetPathExp()
+					name := body.GetName()
+					if !cpe.Envs.Contains(e.Body.Name) || !cpe.Services.Contains(s.Body.Name) ||
+						!matchPathSegment(secretName, name) {
+						continue
+					}
+
+					secretPath := cpe.String() + "/" + name
+					if seen[secretPath] {
+						continue
+					}
+					seen[secretPath] = true
+
+					serviceNode.Children = append(serviceNode.Children, &lsNode{
+						Name: name, Type: "secret", Path: secretPath,
+					})
+				}
+
+				envNode.Children = append(envNode.Children, serviceNode)
+			}
+
+			projectNode.Children = append(projectNode.Children, envNode)
+		}
+
+		orgNode.Children = append(orgNode.Children, projectNode)
+	}
+
+	sortTree(orgNode)
+	return orgNode, nil
+}
+
+// pathSegment is satisfied by the segments of a pathexp.PathExp
+type pathSegment interface {
+	Contains(subject string) bool
+}
+
+// segmentContains returns whether the path segment contains the subject.
+// A segment missing from a partial path contains everything.
+func segmentContains(seg pathSegment, subject string) bool {
+	if seg == nil {
+		return true
+	}
+	return seg.Contains(subject)
+}
+
+func sortTree(n *lsNode) {
+	sort.Sort(lsNodeSorter(n.Children))
+	for _, c := range n.Children {
+		sortTree(c)
+	}
+}
+
+// lsNodeSorter implements sort.Interface, for sorting nodes by name, and then
+// by path.
+type lsNodeSorter []*lsNode
+
+func (l lsNodeSorter) Len() int      { return len(l) }
+func (l lsNodeSorter) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l lsNodeSorter) Less(i, j int) bool {
+	if l[i].Name == l[j].Name {
+		return l[i].Path < l[j].Path
+	}
+	return l[i].Name < l[j].Name
+}
+
+func printTreeNode(w *tabwriter.Writer, n *lsNode, branch, indent string, verbose bool) {
+	if verbose {
+		fmt.Fprintf(w, "%s%s\t%s\t%s\n", branch, n.Name, n.Type, n.Path)
+	} else {
+		fmt.Fprintf(w, "%s%s\n", branch, n.Name)
+	}
+
+	for i, c := range n.Children {
+		if i == len(n.Children)-1 {
+			printTreeNode(w, c, indent+"└── ", indent+"    ", verbose)
+		} else {
+			printTreeNode(w, c, indent+"├── ", indent+"│   ", verbose)
+		}
+	}
+}
+
+func printJSON(v interface{}) error {
+	str, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errs.NewErrorExitError("Could not marshal to json", err)
+	}
+
+	fmt.Printf("%s\n", str)
+	return nil
+}
+
+// retrieve the projecttree for the named org
+func projectTreeForOrg(c context.Context, client *api.Client, orgName string) (*registry.ProjectTreeSegment, error) {
+	org, err := client.Orgs.GetByName(c, orgName)
 	if err != nil {
 		return nil, err
 	}
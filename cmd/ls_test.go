@@ -0,0 +1,46 @@
+package cmd
+
+import "testing"
+
+func TestSecretSearchPath(t *testing.T) {
+	testCases := []struct {
+		path       string
+		searchPath string
+		name       string
+	}{
+		{"/org/proj", "/org/proj/*/*/*/*", "*"},
+		{"/org/proj/dev/", "/org/proj/dev/*/*/*", "*"},
+		{"/org/proj/dev/api/", "/org/proj/dev/api/*/*", "*"},
+		{"/org/proj/dev/api/jeff", "/org/proj/dev/api/jeff/*", "*"},
+		{"/org/proj/dev/api/jeff/1", "/org/proj/dev/api/jeff/1", "*"},
+		{"/org/proj/dev/api/jeff/1/port", "/org/proj/dev/api/jeff/1", "port"},
+		{"/org/proj/dev/api/*/*/db*", "/org/proj/dev/api/*/*", "db*"},
+		{"/org/proj/**", "/org/proj/*/*/*/*", "*"},
+		{"/org/proj/**/port", "/org/proj/*/*/*/*", "port"},
+		{"/org/proj/dev/**/", "/org/proj/dev/*/*/*", "*"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			searchPath, name, err := secretSearchPath(tc.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if searchPath != tc.searchPath {
+				t.Errorf("wrong search path! had: '%s' wanted: '%s'", searchPath, tc.searchPath)
+			}
+
+			if name != tc.name {
+				t.Errorf("wrong name! had: '%s' wanted: '%s'", name, tc.name)
+			}
+		})
+	}
+
+	t.Run("too many segments", func(t *testing.T) {
+		_, _, err := secretSearchPath("/org/proj/dev/api/jeff/1/port/extra")
+		if err == nil {
+			t.Error("expected error, got none")
+		}
+	})
+}
@@ -85,11 +85,15 @@ func init() {
 					checkRequiredFlags, destroyMachineCmd,
 				),
 			},
+			machineTokensCommand,
+			machineProvisionCommand,
+			machineEnrollCodeCommand,
+			machineEnrollCommand,
 			{
 				Name:      "roles",
-				Usage:     "Lists and create machine roles for an organization",
+				Usage:     "Manage machine roles for an organization",
 				ArgsUsage: "<machine-role>",
-				Subcommands: []cli.Command{
+				Subcommands: append([]cli.Command{
 					{
 						Name:      "create",
 						Usage:     "Create a machine role for an organization",
@@ -113,7 +117,7 @@ func init() {
 							checkRequiredFlags, listMachineRoles,
 						),
 					},
-				},
+				}, machineRoleCommands...),
 			},
 		},
 	}
@@ -381,13 +385,17 @@ func listMachinesCmd(ctx *cli.Context) error {
 	for _, machine := range machines {
 		mID := machine.Machine.ID.String()
 		m := machine.Machine.Body
-		roleName := "-"
+		var roleNames []string
 		for _, m := range machine.Memberships {
 			role, ok := roleMap[*m.Body.TeamID]
 			if ok {
-				roleName = role.Name
+				roleNames = append(roleNames, role.Name)
 			}
 		}
+		roleName := "-"
+		if len(roleNames) > 0 {
+			roleName = strings.Join(roleNames, ", ")
+		}
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", mID, m.Name, m.State, roleName, m.Created.Format(time.RFC3339))
 	}
 	w.Flush()
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+const defaultEnrollmentCodeTTL = 15 * time.Minute
+
+// machineEnrollCodeCommand is the `machines enroll-code` subcommand, for
+// creating codes with which new hosts enroll themselves as machines.
+var machineEnrollCodeCommand = cli.Command{
+	Name:  "enroll-code",
+	Usage: "Create codes with which new hosts enroll themselves as machines",
+	Subcommands: []cli.Command{
+		{
+			Name:  "create",
+			Usage: "Create an enrollment code for a machine role",
+			Flags: []cli.Flag{
+				orgFlag("Org the machines will belong to", true),
+				roleFlag("Role the machines will belong to", true),
+				newPlaceholder("ttl", "DURATION",
+					"How long the code can be used for (default 15m)", "", "", false),
+				newPlaceholder("uses", "N",
+					"How many machines can enroll with the code (default 1)", "", "", false),
+			},
+			Action: chain(
+				ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+				checkRequiredFlags, createEnrollmentCodeCmd,
+			),
+		},
+	},
+}
+
+// machineEnrollCommand is the `machines enroll` subcommand, run on a new host
+// to enroll it as a machine using an enrollment code.
+var machineEnrollCommand = cli.Command{
+	Name:      "enroll",
+	Usage:     "Enroll this host as a machine using an enrollment code",
+	ArgsUsage: "<code>",
+	Flags: []cli.Flag{
+		newPlaceholder("name", "NAME",
+			"Name of the machine (defaults to the host name)", "", "", false),
+		newPlaceholder("output", "FILE",
+			"Write the machine's token to FILE instead of displaying it", "", "", false),
+	},
+	Action: chain(ensureDaemon, enrollMachineCmd),
+}
+
+func createEnrollmentCodeCmd(ctx *cli.Context) error {
+	if len(ctx.Args()) > 0 {
+		return errs.NewUsageExitError("Too many arguments supplied.", ctx)
+	}
+
+	ttl := defaultEnrollmentCodeTTL
+	if raw := ctx.String("ttl"); raw != "" {
+		var err error
+		ttl, err = time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return errs.NewUsageExitError("Invalid ttl, use a value such as 15m", ctx)
+		}
+	}
+
+	uses := 1
+	if raw := ctx.String("uses"); raw != "" {
+		var err error
+		uses, err = strconv.Atoi(raw)
+		if err != nil || uses < 1 {
+			return errs.NewUsageExitError("--uses must be a positive number", ctx)
+		}
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return errs.NewErrorExitError("Failed to retrieve org", err)
+	}
+	if org == nil {
+		return errs.NewExitError("Org not found.")
+	}
+
+	teams, err := client.Teams.List(c, org.ID, ctx.String("role"), primitive.MachineTeamType)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to retrieve machine role", err)
+	}
+	if len(teams) < 1 {
+		return errs.NewExitError("Machine role not found.")
+	}
+
+	code, err := client.Machines.CreateEnrollmentCode(c, &apitypes.MachineEnrollmentCodeCreateRequest{
+		OrgID:   org.ID,
+		TeamID:  teams[0].ID,
+		Expires: time.Now().Add(ttl).UTC(),
+		Uses:    uses,
+	})
+	if err != nil {
+		return errs.NewErrorExitError("Could not create enrollment code", err)
+	}
+
+	fmt.Printf("Enrollment code for role %s, valid for %d machine(s) until %s:\n\n",
+		teams[0].Body.Name, code.Uses, code.Expires.Local().Format(time.RFC3339))
+	fmt.Printf("    %s\n\n", code.Code)
+	fmt.Println("Run 'torus machines enroll <code>' on the new host to enroll it.")
+	return nil
+}
+
+func enrollMachineCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments supplied.", ctx)
+	}
+	if len(args) < 1 {
+		return errs.NewUsageExitError("Enrollment code is required", ctx)
+	}
+
+	name := ctx.String("name")
+	if name == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return errs.NewErrorExitError("Could not determine host name, use --name", err)
+		}
+		name = machineNameFromHost(host)
+	}
+	if err := validateSlug("machine")(name); err != nil {
+		return errs.NewUsageExitError(err.Error(), ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	machine, secret, err := client.Machines.Enroll(c, args[0], name, progress)
+	if err != nil {
+		if strings.Contains(err.Error(), "resource exists") {
+			return errs.NewExitError("Machine " + name + " already exists, use --name")
+		}
+		return errs.NewErrorExitError("Could not enroll machine", err)
+	}
+
+	tokenID := machine.Tokens[0].Token.ID
+	fmt.Printf("\nMachine %s enrolled with ID %s.\n", name, machine.Machine.ID)
+
+	if output := ctx.String("output"); output != "" {
+		contents := fmt.Sprintf("TORUS_TOKEN_ID=%s\nTORUS_TOKEN_SECRET=%s\n", tokenID, secret)
+		err = writeSecretFile(output, []byte(contents))
+		if err != nil {
+			return errs.NewErrorExitError("Could not write machine token", err)
+		}
+		fmt.Printf("Token written to %s.\n", output)
+	} else {
+		fmt.Print("\nYou will only be shown the secret once, please keep it safe.\n\n")
+		fmt.Printf("TORUS_TOKEN_ID=%s\n", tokenID)
+		fmt.Printf("TORUS_TOKEN_SECRET=%s\n", secret)
+	}
+
+	fmt.Println("\nThe machine can read secrets once a member of the org has " +
+		"resolved its keyring\nmemberships with 'torus worklog resolve'.")
+	return nil
+}
+
+var invalidMachineNameChars = regexp.MustCompile("[^a-z0-9_-]+")
+
+// machineNameFromHost derives a machine name from a host name, using its first
+// label with any characters not allowed in names replaced by hyphens.
+func machineNameFromHost(host string) string {
+	name := strings.ToLower(strings.SplitN(host, ".", 2)[0])
+	name = invalidMachineNameChars.ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-_")
+
+	if name != "" && (name[0] < 'a' || name[0] > 'z') {
+		name = "host-" + name
+	}
+	if len(name) > 64 {
+		name = strings.TrimRight(name[:64], "-_")
+	}
+
+	return name
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import "testing"
+
+func TestMachineNameFromHost(t *testing.T) {
+	tcs := []struct {
+		host string
+		name string
+	}{
+		{"build-01", "build-01"},
+		{"Build01.example.com", "build01"},
+		{"ip-10-0-0-1.ec2.internal", "ip-10-0-0-1"},
+		{"10node", "host-10node"},
+		{"web_server!", "web_server"},
+	}
+
+	for _, tc := range tcs {
+		if got := machineNameFromHost(tc.host); got != tc.name {
+			t.Errorf("machineNameFromHost(%q) = %q, want %q", tc.host, got, tc.name)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// machineProvisionCommand is the `machines provision` subcommand, for creating
+// many machines at once from a manifest or a generated list of names.
+var machineProvisionCommand = cli.Command{
+	Name:  "provision",
+	Usage: "Create many machines at once, skipping those that already exist",
+	Flags: []cli.Flag{
+		orgFlag("Org the machines will belong to", true),
+		newPlaceholder("file, f", "FILE", "Manifest of the machines to create", "", "", false),
+		newPlaceholder("prefix", "PREFIX",
+			"Generate machine names from this prefix, used with --count", "", "", false),
+		newPlaceholder("count", "N", "Number of machine names to generate", "", "", false),
+		roleFlag("Role of the generated machines", false),
+		newPlaceholder("output-dir", "DIR",
+			"Write each new machine's token to DIR/<name>.env", "", "", false),
+		newPlaceholder("bundle", "FILE",
+			"Write the new machines' tokens to a single JSON file", "", "", false),
+	},
+	Action: chain(
+		ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+		checkRequiredFlags, provisionMachinesCmd,
+	),
+}
+
+// provisionManifest is the format of the file given to `machines provision`.
+type provisionManifest struct {
+	Machines []provisionEntry `yaml:"machines"`
+}
+
+// provisionEntry describes a machine, or with Count a set of machines named
+// <name>-1 to <name>-<count>, to create.
+type provisionEntry struct {
+	Name  string `yaml:"name"`
+	Role  string `yaml:"role"`
+	Count int    `yaml:"count"`
+}
+
+// provisionTarget is a single machine to create.
+type provisionTarget struct {
+	name string
+	role string
+}
+
+// provisionBundle is the format of the JSON file written with --bundle.
+type provisionBundle struct {
+	Org      string                   `json:"org"`
+	Machines []provisionBundleMachine `json:"machines"`
+}
+
+type provisionBundleMachine struct {
+	Name        string        `json:"name"`
+	Role        string        `json:"role"`
+	MachineID   *identity.ID  `json:"machine_id"`
+	TokenID     *identity.ID  `json:"token_id"`
+	TokenSecret *base64.Value `json:"token_secret"`
+}
+
+func provisionMachinesCmd(ctx *cli.Context) error {
+	if len(ctx.Args()) > 0 {
+		return errs.NewUsageExitError("Too many arguments supplied.", ctx)
+	}
+
+	targets, err := provisionTargetsFromFlags(ctx)
+	if err != nil {
+		return err
+	}
+
+	outputDir := ctx.String("output-dir")
+	bundlePath := ctx.String("bundle")
+	if outputDir != "" && bundlePath != "" {
+		return errs.NewUsageExitError(
+			"Cannot specify --output-dir and --bundle at the same time", ctx)
+	}
+	if outputDir == "" && bundlePath == "" {
+		return errs.NewUsageExitError("One of --output-dir or --bundle is required", ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return errs.NewErrorExitError("Failed to retrieve org", err)
+	}
+	if org == nil {
+		return errs.NewExitError("Org not found.")
+	}
+
+	roles, err := provisionRoles(c, client, org.ID, targets)
+	if err != nil {
+		return err
+	}
+
+	state := primitive.MachineActiveState
+	existing, err := client.Machines.List(c, org.ID, &state, nil, nil)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to retrieve machines", err)
+	}
+	exists := make(map[string]bool)
+	for _, m := range existing {
+		exists[m.Machine.Body.Name] = true
+	}
+
+	var bundle *provisionBundle
+	if bundlePath != "" {
+		bundle, err = readProvisionBundle(bundlePath)
+		if err != nil {
+			return errs.NewErrorExitError("Could not read bundle", err)
+		}
+		bundle.Org = org.Body.Name
+	} else {
+		err = os.MkdirAll(outputDir, 0700)
+		if err != nil {
+			return errs.NewErrorExitError("Could not create output directory", err)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "NAME\tROLE\tRESULT")
+
+	created := 0
+	for _, t := range targets {
+		if exists[t.name] {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", t.name, t.role, "skipped, already exists")
+			continue
+		}
+
+		machine, secret, err := createMachineByName(c, client, org.ID, roles[t.role], t.name)
+		if err != nil {
+			w.Flush()
+			return err
+		}
+
+		bm := provisionBundleMachine{
+			Name:        t.name,
+			Role:        t.role,
+			MachineID:   machine.Machine.ID,
+			TokenID:     machine.Tokens[0].Token.ID,
+			TokenSecret: secret,
+		}
+
+		if bundle != nil {
+			bundle.add(bm)
+			err = writeProvisionBundle(bundlePath, bundle)
+		} else {
+			err = writeProvisionEnvFile(outputDir, &bm)
+		}
+		if err != nil {
+			w.Flush()
+			return errs.NewErrorExitError("Could not write token for machine "+t.name, err)
+		}
+
+		created++
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.name, t.role, "created")
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d machine(s) created, %d skipped.\n", created, len(targets)-created)
+	if created > 0 {
+		dest := bundlePath
+		if dest == "" {
+			dest = outputDir
+		}
+		fmt.Printf("Tokens written to %s. They will not be shown again.\n", dest)
+	}
+
+	return nil
+}
+
+// provisionTargetsFromFlags returns the machines to create, either from the
+// manifest given with --file, or generated with --prefix and --count.
+func provisionTargetsFromFlags(ctx *cli.Context) ([]provisionTarget, error) {
+	file := ctx.String("file")
+	prefix := ctx.String("prefix")
+	rawCount := ctx.String("count")
+
+	if file != "" {
+		if prefix != "" || rawCount != "" {
+			return nil, errs.NewUsageExitError(
+				"Cannot specify --file with --prefix or --count", ctx)
+		}
+
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errs.NewErrorExitError("Could not read manifest", err)
+		}
+
+		targets, err := parseProvisionManifest(raw)
+		if err != nil {
+			return nil, errs.NewExitError("Invalid manifest: " + err.Error())
+		}
+		return targets, nil
+	}
+
+	if prefix == "" || rawCount == "" {
+		return nil, errs.NewUsageExitError(
+			"Either --file, or --prefix and --count, are required", ctx)
+	}
+	if ctx.String("role") == "" {
+		return nil, errs.NewUsageExitError("--role is required with --prefix", ctx)
+	}
+
+	count, err := strconv.Atoi(rawCount)
+	if err != nil || count < 1 {
+		return nil, errs.NewUsageExitError("--count must be a positive number", ctx)
+	}
+
+	targets, err := expandProvisionEntries([]provisionEntry{
+		{Name: prefix, Role: ctx.String("role"), Count: count},
+	})
+	if err != nil {
+		return nil, errs.NewUsageExitError(err.Error(), ctx)
+	}
+	return targets, nil
+}
+
+// parseProvisionManifest parses a YAML (or JSON) manifest into the list of
+// machines to create.
+func parseProvisionManifest(raw []byte) ([]provisionTarget, error) {
+	m := provisionManifest{}
+	err := yaml.Unmarshal(raw, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Machines) == 0 {
+		return nil, fmt.Errorf("no machines listed")
+	}
+
+	return expandProvisionEntries(m.Machines)
+}
+
+// expandProvisionEntries expands entries with a count into individual
+// machines, and checks that every name is valid and unique.
+func expandProvisionEntries(entries []provisionEntry) ([]provisionTarget, error) {
+	var targets []provisionTarget
+	seen := make(map[string]bool)
+
+	for i, e := range entries {
+		if e.Name == "" {
+			return nil, fmt.Errorf("machine %d has no name", i+1)
+		}
+		if e.Role == "" {
+			return nil, fmt.Errorf("machine %s has no role", e.Name)
+		}
+		if e.Count < 0 {
+			return nil, fmt.Errorf("machine %s has a negative count", e.Name)
+		}
+
+		names := []string{e.Name}
+		if e.Count > 0 {
+			names = generateMachineNames(e.Name, e.Count)
+		}
+
+		for _, name := range names {
+			if !govalidator.StringMatches(name, slugPattern) {
+				return nil, fmt.Errorf("invalid machine name %q: names can only use "+
+					"a-z, 0-9, hyphens and underscores", name)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("machine %s is listed more than once", name)
+			}
+			seen[name] = true
+
+			targets = append(targets, provisionTarget{name: name, role: e.Role})
+		}
+	}
+
+	return targets, nil
+}
+
+// generateMachineNames returns the names <prefix>-1 to <prefix>-<count>.
+func generateMachineNames(prefix string, count int) []string {
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%d", prefix, i+1)
+	}
+
+	return names
+}
+
+// provisionRoles looks up the machine roles used by the targets, returning
+// their IDs by name. All roles must exist before any machine is created.
+func provisionRoles(c context.Context, client *api.Client, orgID *identity.ID,
+	targets []provisionTarget) (map[string]*identity.ID, error) {
+
+	teams, err := client.Teams.List(c, orgID, "", primitive.MachineTeamType)
+	if err != nil {
+		return nil, errs.NewErrorExitError("Failed to retrieve machine roles", err)
+	}
+
+	available := make(map[string]*identity.ID)
+	for _, t := range teams {
+		available[t.Body.Name] = t.ID
+	}
+
+	roles := make(map[string]*identity.ID)
+	var missing []string
+	for _, t := range targets {
+		if _, ok := roles[t.role]; ok {
+			continue
+		}
+
+		id, ok := available[t.role]
+		if !ok {
+			if !containsString(missing, t.role) {
+				missing = append(missing, t.role)
+			}
+			continue
+		}
+		roles[t.role] = id
+	}
+
+	if len(missing) > 0 {
+		return nil, errs.NewExitError("Machine role(s) not found: " +
+			strings.Join(missing, ", ") +
+			"\nCreate them with 'torus machines roles create' first.")
+	}
+
+	return roles, nil
+}
+
+// add adds the machine to the bundle, replacing any entry with the same name.
+func (b *provisionBundle) add(m provisionBundleMachine) {
+	for i, existing := range b.Machines {
+		if existing.Name == m.Name {
+			b.Machines[i] = m
+			return
+		}
+	}
+
+	b.Machines = append(b.Machines, m)
+}
+
+// readProvisionBundle reads an existing bundle, so a re-run keeps the tokens
+// of machines created previously. A missing file yields an empty bundle.
+func readProvisionBundle(path string) (*provisionBundle, error) {
+	b := &provisionBundle{Machines: []provisionBundleMachine{}}
+
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(raw, b)
+	return b, err
+}
+
+func writeProvisionBundle(path string, b *provisionBundle) error {
+	raw, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeSecretFile(path, append(raw, '\n'))
+}
+
+func writeProvisionEnvFile(dir string, m *provisionBundleMachine) error {
+	contents := fmt.Sprintf("TORUS_TOKEN_ID=%s\nTORUS_TOKEN_SECRET=%s\n",
+		m.TokenID, m.TokenSecret)
+
+	return writeSecretFile(filepath.Join(dir, m.Name+".env"), []byte(contents))
+}
+
+// writeSecretFile writes the file readable only by the current user, even if
+// it already existed with broader permissions.
+func writeSecretFile(path string, contents []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = f.Chmod(0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(contents)
+	return err
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProvisionManifest(t *testing.T) {
+	tcs := []struct {
+		name     string
+		manifest string
+		targets  []provisionTarget
+		err      bool
+	}{
+		{
+			name: "names and counts",
+			manifest: `
+machines:
+  - name: db
+    role: database
+  - name: web
+    role: web
+    count: 2
+`,
+			targets: []provisionTarget{
+				{name: "db", role: "database"},
+				{name: "web-1", role: "web"},
+				{name: "web-2", role: "web"},
+			},
+		},
+		{
+			name:     "json",
+			manifest: `{"machines": [{"name": "db", "role": "database"}]}`,
+			targets:  []provisionTarget{{name: "db", role: "database"}},
+		},
+		{name: "empty", manifest: "machines: []", err: true},
+		{name: "missing role", manifest: "machines: [{name: db}]", err: true},
+		{name: "missing name", manifest: "machines: [{role: web}]", err: true},
+		{name: "invalid name", manifest: "machines: [{name: Web, role: web}]", err: true},
+		{
+			name: "duplicate",
+			manifest: `
+machines:
+  - {name: web-1, role: web}
+  - {name: web, role: web, count: 2}
+`,
+			err: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			targets, err := parseProvisionManifest([]byte(tc.manifest))
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+
+			if !reflect.DeepEqual(targets, tc.targets) {
+				t.Errorf("got %v, want %v", targets, tc.targets)
+			}
+		})
+	}
+}
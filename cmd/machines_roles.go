@@ -0,0 +1,480 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// machineRoleCommands are the `machines roles` subcommands for managing the
+// machines in each role, and deleting roles.
+var machineRoleCommands = []cli.Command{
+	{
+		Name:      "members",
+		Usage:     "List the machines in a machine role",
+		ArgsUsage: "<role>",
+		Flags: []cli.Flag{
+			orgFlag("Org the machine role belongs to", true),
+		},
+		Action: chain(
+			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+			checkRequiredFlags, machineRoleMembersCmd,
+		),
+	},
+	{
+		Name:      "add",
+		Usage:     "Add a machine to an additional role",
+		ArgsUsage: "<id|name> <role>",
+		Flags: []cli.Flag{
+			orgFlag("Org the machine belongs to", true),
+		},
+		Action: chain(
+			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+			checkRequiredFlags, addMachineRoleCmd,
+		),
+	},
+	{
+		Name:      "remove",
+		Usage:     "Remove a machine from one of its roles",
+		ArgsUsage: "<id|name> <role>",
+		Flags: []cli.Flag{
+			orgFlag("Org the machine belongs to", true),
+		},
+		Action: chain(
+			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+			checkRequiredFlags, removeMachineRoleCmd,
+		),
+	},
+	{
+		Name:      "move",
+		Usage:     "Move a machine to a role, removing it from its other roles",
+		ArgsUsage: "<id|name> <role>",
+		Flags: []cli.Flag{
+			orgFlag("Org the machine belongs to", true),
+		},
+		Action: chain(
+			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+			checkRequiredFlags, moveMachineRoleCmd,
+		),
+	},
+	{
+		Name:      "delete",
+		Usage:     "Delete a machine role that has no machines",
+		ArgsUsage: "<role>",
+		Flags: []cli.Flag{
+			orgFlag("Org the machine role belongs to", true),
+			stdAutoAcceptFlag,
+		},
+		Action: chain(
+			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+			checkRequiredFlags, deleteMachineRoleCmd,
+		),
+	},
+}
+
+func machineRoleMembersCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return errs.NewUsageExitError("A role name is required", ctx)
+	}
+
+	c, client, err := NewAPIClient(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return err
+	}
+
+	role, err := getMachineRole(c, client, org.ID, args[0])
+	if err != nil {
+		return err
+	}
+
+	state := primitive.MachineActiveState
+	machines, err := client.Machines.List(c, org.ID, &state, nil, role.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to retrieve machines", err)
+	}
+
+	if len(machines) == 0 {
+		fmt.Printf("%s has no machines\n", role.Body.Name)
+		return nil
+	}
+
+	title := "machines in the " + role.Body.Name + " role (" + strconv.Itoa(len(machines)) + ")"
+	fmt.Println("")
+	fmt.Println(title)
+	fmt.Println(strings.Repeat("-", utf8.RuneCountInString(title)))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
+	for _, machine := range machines {
+		m := machine.Machine
+		fmt.Fprintf(w, "%s\t%s\t%s\n", m.ID, m.Body.Name, m.Body.Created.Format(time.RFC3339))
+	}
+	w.Flush()
+	fmt.Println("")
+
+	return nil
+}
+
+func addMachineRoleCmd(ctx *cli.Context) error {
+	c, client, machine, role, err := machineAndRole(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = checkAddMachineRole(machine, role)
+	if err != nil {
+		return err
+	}
+
+	name := machine.Machine.Body.Name
+	err = client.Memberships.Create(c, machine.Machine.ID, machine.Machine.Body.OrgID, role.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to add machine to role", err)
+	}
+
+	fmt.Println(name + " has been added to the " + role.Body.Name + " role.")
+	return syncKeyringMembers(c, client, machine)
+}
+
+func removeMachineRoleCmd(ctx *cli.Context) error {
+	c, client, machine, role, err := machineAndRole(ctx)
+	if err != nil {
+		return err
+	}
+
+	roles, err := machineRoleNames(c, client, machine)
+	if err != nil {
+		return err
+	}
+
+	err = checkRemoveMachineRole(ctx.App.Name, machine, role, roles)
+	if err != nil {
+		return err
+	}
+
+	name := machine.Machine.Body.Name
+	err = client.Memberships.Delete(c, machineMembership(machine, role.ID).ID)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to remove machine from role", err)
+	}
+
+	fmt.Println(name + " has been removed from the " + role.Body.Name + " role.")
+	return syncKeyringMembers(c, client, machine)
+}
+
+func moveMachineRoleCmd(ctx *cli.Context) error {
+	c, client, machine, role, err := machineAndRole(ctx)
+	if err != nil {
+		return err
+	}
+
+	roles, err := machineRoleNames(c, client, machine)
+	if err != nil {
+		return err
+	}
+
+	err = checkMoveMachineRole(machine, role, roles)
+	if err != nil {
+		return err
+	}
+
+	orgID := machine.Machine.Body.OrgID
+	if machineMembership(machine, role.ID) == nil {
+		err = client.Memberships.Create(c, machine.Machine.ID, orgID, role.ID)
+		if err != nil {
+			return errs.NewErrorExitError("Failed to add machine to role", err)
+		}
+	}
+
+	// The machine stays in the system machine team, which isn't a role.
+	for _, m := range machine.Memberships {
+		if _, ok := roles[*m.Body.TeamID]; !ok || *m.Body.TeamID == *role.ID {
+			continue
+		}
+
+		err = client.Memberships.Delete(c, m.ID)
+		if err != nil {
+			return errs.NewErrorExitError("Failed to remove machine from role "+roles[*m.Body.TeamID], err)
+		}
+	}
+
+	fmt.Println(machine.Machine.Body.Name + " has been moved to the " + role.Body.Name + " role.")
+	return syncKeyringMembers(c, client, machine)
+}
+
+func deleteMachineRoleCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return errs.NewUsageExitError("A role name is required", ctx)
+	}
+
+	c, client, err := NewAPIClient(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return err
+	}
+
+	role, err := getMachineRole(c, client, org.ID, args[0])
+	if err != nil {
+		return err
+	}
+
+	state := primitive.MachineActiveState
+	machines, err := client.Machines.List(c, org.ID, &state, nil, role.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to retrieve machines", err)
+	}
+	err = checkDeleteMachineRole(ctx.App.Name, role, len(machines))
+	if err != nil {
+		return err
+	}
+
+	preamble := "You are about to delete the " + role.Body.Name + " role, and its policy attachments."
+	abortErr := ConfirmDialogue(ctx, nil, &preamble, "", true)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	err = client.Teams.Delete(c, role.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to delete role", err)
+	}
+
+	fmt.Printf("Role %s deleted.\n", role.Body.Name)
+	return nil
+}
+
+// checkAddMachineRole returns an error if the machine already has the role.
+func checkAddMachineRole(machine *apitypes.MachineSegment, role *envelope.Team) error {
+	if machineMembership(machine, role.ID) != nil {
+		return errs.NewExitError(machine.Machine.Body.Name + " already has the " +
+			role.Body.Name + " role.")
+	}
+
+	return nil
+}
+
+// checkRemoveMachineRole returns an error if the machine doesn't have the
+// role, or if it is the only role in roles, the roles the machine has.
+func checkRemoveMachineRole(appName string, machine *apitypes.MachineSegment,
+	role *envelope.Team, roles map[identity.ID]string) error {
+
+	name := machine.Machine.Body.Name
+	if machineMembership(machine, role.ID) == nil {
+		return errs.NewExitError(name + " does not have the " + role.Body.Name + " role.")
+	}
+	if len(roles) < 2 {
+		return errs.NewExitError(fmt.Sprintf(
+			"%s must keep at least one role. Use '%s machines roles move' to change its role.",
+			name, appName))
+	}
+
+	return nil
+}
+
+// checkMoveMachineRole returns an error if the role is already the only role
+// in roles, the roles the machine has.
+func checkMoveMachineRole(machine *apitypes.MachineSegment, role *envelope.Team,
+	roles map[identity.ID]string) error {
+
+	if _, ok := roles[*role.ID]; ok && len(roles) == 1 {
+		return errs.NewExitError(machine.Machine.Body.Name + " already has only the " +
+			role.Body.Name + " role.")
+	}
+
+	return nil
+}
+
+// checkDeleteMachineRole returns an error if the role still has machines.
+func checkDeleteMachineRole(appName string, role *envelope.Team, machines int) error {
+	if machines > 0 {
+		return errs.NewExitError(fmt.Sprintf(
+			"The %s role has %d machines. Move them to another role with '%s machines roles move' first.",
+			role.Body.Name, machines, appName))
+	}
+
+	return nil
+}
+
+// machineAndRole looks up the machine and machine role given as arguments.
+func machineAndRole(ctx *cli.Context) (context.Context, *api.Client,
+	*apitypes.MachineSegment, *envelope.Team, error) {
+
+	args := ctx.Args()
+	if len(args) != 2 {
+		return nil, nil, nil, nil, errs.NewUsageExitError("A machine and a role are required", ctx)
+	}
+
+	c, client, machine, err := machineForTokens(ctx, args[0])
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	role, err := getMachineRole(c, client, machine.Machine.Body.OrgID, args[1])
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return c, client, machine, role, nil
+}
+
+// getMachineRole returns the machine role with the given name.
+func getMachineRole(c context.Context, client *api.Client, orgID *identity.ID,
+	name string) (*envelope.Team, error) {
+
+	roles, err := client.Teams.List(c, orgID, name, primitive.MachineTeamType)
+	if err != nil {
+		return nil, errs.NewErrorExitError("Failed to retrieve machine role", err)
+	}
+	if len(roles) < 1 {
+		return nil, errs.NewExitError("Machine role not found.")
+	}
+
+	return &roles[0], nil
+}
+
+// machineRoleNames returns the names of the roles the machine has, keyed by
+// team id.
+func machineRoleNames(c context.Context, client *api.Client,
+	machine *apitypes.MachineSegment) (map[identity.ID]string, error) {
+
+	teams, err := client.Teams.List(c, machine.Machine.Body.OrgID, "", primitive.MachineTeamType)
+	if err != nil {
+		return nil, errs.NewErrorExitError("Failed to retrieve machine roles", err)
+	}
+
+	roles := make(map[identity.ID]string)
+	for _, t := range teams {
+		if machineMembership(machine, t.ID) != nil {
+			roles[*t.ID] = t.Body.Name
+		}
+	}
+
+	return roles, nil
+}
+
+// machineMembership returns the machine's membership of the team, or nil if
+// it is not a member.
+func machineMembership(machine *apitypes.MachineSegment, teamID *identity.ID) *envelope.Membership {
+	for _, m := range machine.Memberships {
+		if *m.Body.TeamID == *teamID {
+			return &m
+		}
+	}
+
+	return nil
+}
+
+// syncKeyringMembers resolves the keyring membership items in the org's
+// worklog that are for the machine alone, so a machine whose roles changed has
+// access to the secrets it should right away, rather than once someone
+// resolves the worklog. Items for other members are left for the worklog.
+func syncKeyringMembers(c context.Context, client *api.Client, machine *apitypes.MachineSegment) error {
+	orgID := machine.Machine.Body.OrgID
+	items, err := client.Worklog.List(c, orgID)
+	if err != nil {
+		return errs.NewErrorExitError(keyringSyncFailed, err)
+	}
+
+	mine, others := machineKeyringItems(items, machine)
+	err = resolveKeyringItems(c, client, orgID, mine)
+	if err != nil {
+		return err
+	}
+
+	if others > 0 {
+		fmt.Printf("%d keyring(s) are missing other members too. Run 'torus worklog' to update them.\n",
+			others)
+	}
+
+	return nil
+}
+
+// syncOrgKeyringMembers resolves every keyring membership item in the org's
+// worklog.
+func syncOrgKeyringMembers(c context.Context, client *api.Client, orgID *identity.ID) error {
+	items, err := client.Worklog.List(c, orgID)
+	if err != nil {
+		return errs.NewErrorExitError(keyringSyncFailed, err)
+	}
+
+	var keyringItems []apitypes.WorklogItem
+	for _, item := range items {
+		if item.Type() == apitypes.KeyringMembersWorklogType {
+			keyringItems = append(keyringItems, item)
+		}
+	}
+
+	return resolveKeyringItems(c, client, orgID, keyringItems)
+}
+
+const keyringSyncFailed = "Failed to update keyring memberships. Run 'torus worklog' to retry."
+
+// resolveKeyringItems resolves the keyring membership items, stopping at the
+// first that isn't resolved.
+func resolveKeyringItems(c context.Context, client *api.Client, orgID *identity.ID,
+	items []apitypes.WorklogItem) error {
+
+	for _, item := range items {
+		res, err := client.Worklog.Resolve(c, orgID, item.ID)
+		if err != nil {
+			return errs.NewErrorExitError(keyringSyncFailed, err)
+		}
+		if res.State != apitypes.SuccessWorklogResult {
+			return errs.NewExitError(keyringSyncFailed + "\n" + res.Message)
+		}
+	}
+
+	return nil
+}
+
+// machineKeyringItems returns the keyring membership items whose subject is
+// the machine or one of its tokens, along with how many other keyring
+// membership items there are.
+func machineKeyringItems(items []apitypes.WorklogItem,
+	machine *apitypes.MachineSegment) ([]apitypes.WorklogItem, int) {
+
+	ids := map[identity.ID]bool{*machine.Machine.ID: true}
+	for _, t := range machine.Tokens {
+		ids[*t.Token.ID] = true
+	}
+
+	var mine []apitypes.WorklogItem
+	others := 0
+	for _, item := range items {
+		if item.Type() != apitypes.KeyringMembersWorklogType {
+			continue
+		}
+
+		if item.SubjectID != nil && ids[*item.SubjectID] {
+			mine = append(mine, item)
+		} else {
+			others++
+		}
+	}
+
+	return mine, others
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func mutableID(t *testing.T, body identity.Mutable) *identity.ID {
+	id, err := identity.NewMutable(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &id
+}
+
+func TestMachineRoleArgs(t *testing.T) {
+	tcs := []struct {
+		name string
+		fn   func(*cli.Context) error
+		args []string
+	}{
+		{"members without a role", machineRoleMembersCmd, nil},
+		{"members with two roles", machineRoleMembersCmd, []string{"web", "db"}},
+		{"add without a role", addMachineRoleCmd, []string{"box"}},
+		{"add with extra args", addMachineRoleCmd, []string{"box", "web", "db"}},
+		{"remove without a role", removeMachineRoleCmd, []string{"box"}},
+		{"move without args", moveMachineRoleCmd, nil},
+		{"delete without a role", deleteMachineRoleCmd, nil},
+		{"delete with two roles", deleteMachineRoleCmd, []string{"web", "db"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			flagset := flag.NewFlagSet("", flag.ContinueOnError)
+			flagset.String("org", "", "")
+			err := flagset.Parse(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := cli.NewContext(cli.NewApp(), flagset, nil)
+			ctx.Command = cli.Command{Name: "roles"}
+
+			err = tc.fn(ctx)
+			if err == nil || !strings.Contains(err.Error(), "required") {
+				t.Errorf("got error %v, want a usage error", err)
+			}
+		})
+	}
+}
+
+func TestMachineRoleState(t *testing.T) {
+	orgID := mutableID(t, &primitive.Org{Name: "org"})
+	web := &envelope.Team{
+		ID:   mutableID(t, &primitive.Team{Name: "web", OrgID: orgID}),
+		Body: &primitive.Team{Name: "web", OrgID: orgID},
+	}
+	db := &envelope.Team{
+		ID:   mutableID(t, &primitive.Team{Name: "db", OrgID: orgID}),
+		Body: &primitive.Team{Name: "db", OrgID: orgID},
+	}
+
+	// machine returns a machine with the given roles, and the roles keyed by
+	// team id as machineRoleNames returns them.
+	machine := func(roles ...*envelope.Team) (*apitypes.MachineSegment, map[identity.ID]string) {
+		m := &apitypes.MachineSegment{
+			Machine: &envelope.Machine{
+				ID:   mutableID(t, &primitive.Machine{Name: "box", OrgID: orgID}),
+				Body: &primitive.Machine{Name: "box", OrgID: orgID},
+			},
+		}
+
+		names := make(map[identity.ID]string)
+		for _, r := range roles {
+			m.Memberships = append(m.Memberships, envelope.Membership{
+				ID:   mutableID(t, &primitive.Membership{TeamID: r.ID}),
+				Body: &primitive.Membership{OrgID: orgID, OwnerID: m.Machine.ID, TeamID: r.ID},
+			})
+			names[*r.ID] = r.Body.Name
+		}
+
+		return m, names
+	}
+
+	webOnly, webOnlyRoles := machine(web)
+	both, bothRoles := machine(web, db)
+
+	tcs := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"add a new role", checkAddMachineRole(webOnly, db), ""},
+		{"add a role it has", checkAddMachineRole(webOnly, web), "already has the web role"},
+		{"remove one of two roles", checkRemoveMachineRole("torus", both, db, bothRoles), ""},
+		{"remove a role it lacks", checkRemoveMachineRole("torus", webOnly, db, webOnlyRoles),
+			"does not have the db role"},
+		{"remove its only role", checkRemoveMachineRole("torus", webOnly, web, webOnlyRoles),
+			"must keep at least one role"},
+		{"move to a new role", checkMoveMachineRole(webOnly, db, webOnlyRoles), ""},
+		{"move to one of its roles", checkMoveMachineRole(both, web, bothRoles), ""},
+		{"move to its only role", checkMoveMachineRole(webOnly, web, webOnlyRoles),
+			"already has only the web role"},
+		{"delete an empty role", checkDeleteMachineRole("torus", web, 0), ""},
+		{"delete a role with machines", checkDeleteMachineRole("torus", web, 2), "has 2 machines"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.want == "" {
+				if tc.err != nil {
+					t.Errorf("unexpected error: %s", tc.err)
+				}
+				return
+			}
+
+			if tc.err == nil || !strings.Contains(tc.err.Error(), tc.want) {
+				t.Errorf("got error %v, want %q", tc.err, tc.want)
+			}
+		})
+	}
+}
+
+func TestMachineKeyringItems(t *testing.T) {
+	orgID := mutableID(t, &primitive.Org{Name: "org"})
+	machineID := mutableID(t, &primitive.Machine{Name: "box", OrgID: orgID})
+	tokenID := mutableID(t, &primitive.MachineToken{OrgID: orgID, MachineID: machineID})
+	otherID := mutableID(t, &primitive.MachineToken{OrgID: orgID})
+
+	machine := &apitypes.MachineSegment{
+		Machine: &envelope.Machine{ID: machineID},
+		Tokens: []apitypes.MachineTokenSegment{
+			{Token: &envelope.MachineToken{ID: tokenID}},
+		},
+	}
+
+	item := func(t apitypes.WorklogType, subject string, subjectID *identity.ID) apitypes.WorklogItem {
+		i := apitypes.WorklogItem{Subject: subject, SubjectID: subjectID}
+		i.CreateID(t)
+		return i
+	}
+
+	items := []apitypes.WorklogItem{
+		item(apitypes.KeyringMembersWorklogType, "/org/web/dev/*/*/*", tokenID),
+		item(apitypes.KeyringMembersWorklogType, "/org/web/prod/*/*/*", otherID),
+		item(apitypes.KeyringMembersWorklogType, "/org/db/dev/*/*/*", nil),
+		item(apitypes.StaleMachineWorklogType, "box", machineID),
+	}
+
+	mine, others := machineKeyringItems(items, machine)
+	if len(mine) != 1 || mine[0].Subject != "/org/web/dev/*/*/*" {
+		t.Errorf("got items %v, want only the item for the machine's token", mine)
+	}
+	if others != 2 {
+		t.Errorf("got %d other items, want 2", others)
+	}
+}
@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// machineTokensCommand is the `machines tokens` subcommand, for managing the
+// tokens of an existing machine.
+var machineTokensCommand = cli.Command{
+	Name:  "tokens",
+	Usage: "List, create, revoke and rotate the tokens of a machine",
+	Subcommands: []cli.Command{
+		{
+			Name:      "list",
+			Usage:     "List the tokens of a machine",
+			ArgsUsage: "<id|name>",
+			Flags: []cli.Flag{
+				orgFlag("Org the machine belongs to", true),
+			},
+			Action: chain(
+				ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+				checkRequiredFlags, listMachineTokensCmd,
+			),
+		},
+		{
+			Name:      "create",
+			Usage:     "Create an additional token for a machine",
+			ArgsUsage: "<id|name>",
+			Flags: []cli.Flag{
+				orgFlag("Org the machine belongs to", true),
+			},
+			Action: chain(
+				ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+				checkRequiredFlags, createMachineTokenCmd,
+			),
+		},
+		{
+			Name:      "revoke",
+			Usage:     "Revoke a token of a machine",
+			ArgsUsage: "<id|name> <token-id>",
+			Flags: []cli.Flag{
+				orgFlag("Org the machine belongs to", true),
+				stdAutoAcceptFlag,
+			},
+			Action: chain(
+				ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+				checkRequiredFlags, revokeMachineTokenCmd,
+			),
+		},
+		{
+			Name:      "rotate",
+			Usage:     "Replace the active tokens of a machine with a new token",
+			ArgsUsage: "<id|name>",
+			Flags: []cli.Flag{
+				orgFlag("Org the machine belongs to", true),
+				newPlaceholder("grace", "DURATION",
+					"Leave the previous tokens active for this long (such as 15m), then have the daemon revoke them",
+					"", "", false),
+				stdAutoAcceptFlag,
+			},
+			Action: chain(
+				ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+				checkRequiredFlags, rotateMachineTokensCmd,
+			),
+		},
+	},
+}
+
+func listMachineTokensCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments supplied.", ctx)
+	}
+	if len(args) < 1 {
+		return errs.NewUsageExitError("Name or ID is required", ctx)
+	}
+
+	c, client, machine, err := machineForTokens(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	orgTrees, err := client.Orgs.GetTree(c, *machine.Machine.Body.OrgID)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to retrieve machine tokens", err)
+	}
+
+	profileMap := make(map[identity.ID]apitypes.Profile)
+	if len(orgTrees) > 0 {
+		for _, p := range orgTrees[0].Profiles {
+			profileMap[*p.ID] = *p
+		}
+	}
+
+	fmt.Println("")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
+	fmt.Fprintln(w, "TOKEN ID\tSTATE\tCREATED BY\tCREATED ON\tLAST LOGIN\tDESTROYED ON")
+	fmt.Fprintln(w, " \t \t \t \t \t ")
+	for _, token := range machine.Tokens {
+		body := token.Token.Body
+
+		createdBy := "-"
+		if creator, ok := profileMap[*body.CreatedBy]; ok {
+			createdBy = creator.Body.Username
+		}
+
+		destroyedOn := "-"
+		if body.State == primitive.MachineTokenDestroyedState && body.Destroyed != nil {
+			destroyedOn = body.Destroyed.Format(time.RFC3339)
+		}
+
+		lastLogin := "-"
+		if token.LastLogin != nil {
+			lastLogin = token.LastLogin.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", token.Token.ID, body.State, createdBy,
+			body.Created.Format(time.RFC3339), lastLogin, destroyedOn)
+	}
+	w.Flush()
+	fmt.Println("")
+
+	return nil
+}
+
+func createMachineTokenCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments supplied.", ctx)
+	}
+	if len(args) < 1 {
+		return errs.NewUsageExitError("Name or ID is required", ctx)
+	}
+
+	c, client, machine, err := machineForTokens(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	token, secret, err := client.Machines.CreateToken(c, machine.Machine.ID, progress)
+	if err != nil {
+		return errs.NewErrorExitError("Could not create machine token", err)
+	}
+
+	printMachineToken(token, secret)
+	return nil
+}
+
+func revokeMachineTokenCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) > 2 {
+		return errs.NewUsageExitError("Too many arguments supplied.", ctx)
+	}
+	if len(args) < 2 {
+		return errs.NewUsageExitError("Machine name or ID and token ID are required", ctx)
+	}
+
+	tokenID, err := identity.DecodeFromString(args[1])
+	if err != nil {
+		return errs.NewUsageExitError("Invalid token ID", ctx)
+	}
+
+	c, client, machine, err := machineForTokens(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	active := activeMachineTokens(machine)
+	found := false
+	for _, token := range active {
+		if *token.ID == tokenID {
+			found = true
+		}
+	}
+	if !found {
+		return errs.NewExitError("Active token not found for machine.")
+	}
+	if len(active) == 1 {
+		return errs.NewExitError("Cannot revoke the only active token of a machine. " +
+			"Create or rotate its token first, or destroy the machine.")
+	}
+
+	preamble := "You are about to revoke a machine token. This cannot be undone."
+	abortErr := ConfirmDialogue(ctx, nil, &preamble, "", true)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	err = client.Machines.DestroyToken(c, machine.Machine.ID, &tokenID)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to revoke machine token", err)
+	}
+
+	fmt.Println("Machine token revoked.")
+	return nil
+}
+
+func rotateMachineTokensCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments supplied.", ctx)
+	}
+	if len(args) < 1 {
+		return errs.NewUsageExitError("Name or ID is required", ctx)
+	}
+
+	grace, err := parseGrace(ctx.String("grace"))
+	if err != nil {
+		return errs.NewUsageExitError("Invalid grace duration, use a value such as 15m", ctx)
+	}
+
+	c, client, machine, err := machineForTokens(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	previous := activeMachineTokens(machine)
+
+	preamble := fmt.Sprintf("You are about to rotate the tokens of machine %s. "+
+		"Its %d active token(s) will be revoked.", machine.Machine.Body.Name, len(previous))
+	if grace > 0 {
+		preamble = fmt.Sprintf("You are about to rotate the tokens of machine %s. "+
+			"Its %d active token(s) will be revoked after %s.",
+			machine.Machine.Body.Name, len(previous), grace)
+	}
+	abortErr := ConfirmDialogue(ctx, nil, &preamble, "", true)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	token, secret, err := client.Machines.CreateToken(c, machine.Machine.ID, progress)
+	if err != nil {
+		return errs.NewErrorExitError("Could not create machine token", err)
+	}
+
+	printMachineToken(token, secret)
+
+	if len(previous) == 0 {
+		return nil
+	}
+
+	// The daemon revokes the previous tokens once the grace window ends, so
+	// they don't outlive an interrupted command.
+	if grace > 0 {
+		revokeAt := time.Now().Add(grace)
+		for _, t := range previous {
+			err = client.Machines.ScheduleTokenRevocation(c, machine.Machine.ID, t.ID, revokeAt)
+			if err != nil {
+				return errs.NewErrorExitError("Failed to schedule revocation of previous machine token "+
+					t.ID.String(), err)
+			}
+		}
+
+		fmt.Printf("\n%d previous token(s) will be revoked by the daemon at %s.\n",
+			len(previous), revokeAt.Format(time.RFC3339))
+		return nil
+	}
+
+	for _, t := range previous {
+		err = client.Machines.DestroyToken(c, machine.Machine.ID, t.ID)
+		if err != nil {
+			return errs.NewErrorExitError("Failed to revoke previous machine token "+
+				t.ID.String(), err)
+		}
+	}
+
+	fmt.Printf("\n%d previous token(s) revoked.\n", len(previous))
+	return nil
+}
+
+// machineForTokens looks up the machine with the given ID or name in the org
+// given by the --org flag, returning it along with its tokens.
+func machineForTokens(ctx *cli.Context, idOrName string) (context.Context, *api.Client,
+	*apitypes.MachineSegment, error) {
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return nil, nil, nil, errs.NewErrorExitError("Failed to retrieve org", err)
+	}
+	if org == nil {
+		return nil, nil, nil, errs.NewExitError("Org not found.")
+	}
+
+	machineID, err := identity.DecodeFromString(idOrName)
+	if err != nil {
+		state := primitive.MachineActiveState
+		machines, lErr := client.Machines.List(c, org.ID, &state, &idOrName, nil)
+		if lErr != nil {
+			return nil, nil, nil, errs.NewErrorExitError("Failed to retrieve machine", lErr)
+		}
+		active := activeMachine(machines)
+		if active == nil {
+			return nil, nil, nil, errs.NewExitError("Machine not found")
+		}
+		machineID = *active.Machine.ID
+	}
+
+	machine, err := client.Machines.Get(c, &machineID)
+	if err != nil {
+		return nil, nil, nil, errs.NewErrorExitError("Failed to retrieve machine", err)
+	}
+	if machine == nil {
+		return nil, nil, nil, errs.NewExitError("Machine not found.")
+	}
+
+	return c, client, machine, nil
+}
+
+// parseGrace parses the grace window given to rotate. No grace window is
+// given as an empty string.
+func parseGrace(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+
+	grace, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, err
+	}
+	if grace < 0 {
+		return 0, errors.New("grace duration must not be negative")
+	}
+
+	return grace, nil
+}
+
+// activeMachine returns the first of the machines that is active, or nil if
+// none are. Destroyed machines keep their name, so a lookup by name can
+// return them alongside the live machine.
+func activeMachine(machines []*apitypes.MachineSegment) *apitypes.MachineSegment {
+	for _, m := range machines {
+		if m.Machine.Body.State == primitive.MachineActiveState {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// activeMachineTokens returns the tokens of the machine in the active state.
+func activeMachineTokens(machine *apitypes.MachineSegment) []*envelope.MachineToken {
+	var active []*envelope.MachineToken
+	for _, t := range machine.Tokens {
+		if t.Token.Body.State == primitive.MachineTokenActiveState {
+			active = append(active, t.Token)
+		}
+	}
+
+	return active
+}
+
+func printMachineToken(token *apitypes.MachineTokenSegment, secret *base64.Value) {
+	fmt.Print("\nYou will only be shown the secret once, please keep it safe.\n\n")
+	fmt.Printf("TORUS_TOKEN_ID=%s\n", token.Token.ID)
+	fmt.Printf("TORUS_TOKEN_SECRET=%s\n", secret)
+}
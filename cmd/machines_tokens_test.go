@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestParseGrace(t *testing.T) {
+	tcs := []struct {
+		raw   string
+		grace time.Duration
+		err   bool
+	}{
+		{"", 0, false},
+		{"0s", 0, false},
+		{"15m", 15 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"-5m", 0, true},
+		{"15", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tc := range tcs {
+		grace, err := parseGrace(tc.raw)
+		if (err != nil) != tc.err {
+			t.Errorf("parseGrace(%q) error = %v, want error: %t", tc.raw, err, tc.err)
+			continue
+		}
+		if grace != tc.grace {
+			t.Errorf("parseGrace(%q) = %s, want %s", tc.raw, grace, tc.grace)
+		}
+	}
+}
+
+func TestActiveMachine(t *testing.T) {
+	machine := func(name, state string) *apitypes.MachineSegment {
+		return &apitypes.MachineSegment{
+			Machine: &envelope.Machine{
+				Body: &primitive.Machine{Name: name, State: state},
+			},
+		}
+	}
+
+	destroyed := machine("destroyed", primitive.MachineDestroyedState)
+	active := machine("active", primitive.MachineActiveState)
+	other := machine("other", primitive.MachineActiveState)
+
+	tcs := []struct {
+		name     string
+		machines []*apitypes.MachineSegment
+		want     *apitypes.MachineSegment
+	}{
+		{"none", nil, nil},
+		{"only destroyed", []*apitypes.MachineSegment{destroyed}, nil},
+		{"destroyed first", []*apitypes.MachineSegment{destroyed, active}, active},
+		{"first active", []*apitypes.MachineSegment{active, other}, active},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := activeMachine(tc.machines); got != tc.want {
+				t.Errorf("activeMachine() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
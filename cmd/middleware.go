@@ -50,9 +50,18 @@ func ensureDaemon(ctx *cli.Context) error {
 		return err
 	}
 
+	client := api.NewClient(cfg)
 	spawned := false
+	external := false
 
+	// A system daemon started through socket activation may not be running
+	// yet, or may not be visible to this user; it answers nonetheless.
 	if proc == nil {
+		_, err = client.Version.GetDaemon(context.Background())
+		external = err == nil
+	}
+
+	if proc == nil && !external {
 		err := spawnDaemon()
 		if err != nil {
 			return err
@@ -61,8 +70,6 @@ func ensureDaemon(ctx *cli.Context) error {
 		spawned = true
 	}
 
-	client := api.NewClient(cfg)
-
 	var v *apitypes.Version
 	increment := 5 * time.Millisecond
 	for d := increment; d < 1*time.Second; d += increment {
@@ -85,6 +92,11 @@ func ensureDaemon(ctx *cli.Context) error {
 		return errs.NewExitError("The daemon version is incorrect. Check for stale processes.")
 	}
 
+	if external {
+		return errs.NewExitError("The daemon version is incorrect. The system daemon " +
+			"must be restarted by an administrator.")
+	}
+
 	fmt.Println("The daemon version is out of date and is being restarted.")
 	fmt.Println("You will need to login again.")
 
@@ -201,7 +213,7 @@ func reflectArgs(ctx *cli.Context, p *prefs.Preferences, i interface{},
 	flags := make(map[string]bool)
 	for _, flagName := range ctx.FlagNames() {
 		// This value is already set via arguments or env vars. skip it.
-		if isSet(ctx, flagName) {
+		if isSet(ctx, flagName) && !isDefault(ctx, flagName) {
 			continue
 		}
 
@@ -294,6 +306,28 @@ func isSet(ctx *cli.Context, name string) bool {
 	return false
 }
 
+// isDefault returns whether the named flag holds the default value it was
+// declared with, rather than one given as an argument or env var.
+func isDefault(ctx *cli.Context, name string) bool {
+	for _, f := range ctx.Command.Flags {
+		if strings.SplitN(f.GetName(), ",", 2)[0] != name {
+			continue
+		}
+
+		var value string
+		switch sf := f.(type) {
+		case placeHolderStringFlag:
+			value = sf.Value
+		case cli.StringFlag:
+			value = sf.Value
+		}
+
+		return value != "" && ctx.String(name) == value && !ctx.IsSet(name)
+	}
+
+	return false
+}
+
 // CheckRequiredFlags ensures that any required flags have been set either on
 // the command line, or through envvars/prefs files.
 func checkRequiredFlags(ctx *cli.Context) error {
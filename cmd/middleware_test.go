@@ -6,6 +6,7 @@ import (
 
 	"github.com/urfave/cli"
 
+	"github.com/manifoldco/torus-cli/dirprefs"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/prefs"
 )
@@ -135,6 +136,49 @@ func TestReflectArgs(t *testing.T) {
 			t.Error("loadPrefDefaults did not set argument")
 		}
 	})
+
+	t.Run("Replaces default values", func(t *testing.T) {
+		cmd := cli.Command{
+			Flags: []cli.Flag{cli.StringFlag{Name: "service", Value: "default"}},
+		}
+		d := &dirprefs.DirPreferences{Service: "api"}
+
+		flagset := flag.NewFlagSet("", flag.ContinueOnError)
+		flagset.String("service", "default", "")
+		ctx := cli.NewContext(nil, flagset, nil)
+		ctx.Command = cmd
+
+		err := reflectArgs(ctx, p, d, "json")
+		if err != nil {
+			t.Error("loadDirPrefs errored: " + err.Error())
+		}
+
+		if ctx.String("service") != "api" {
+			t.Error("loadDirPrefs did not replace the default value")
+		}
+	})
+
+	t.Run("Does not overwrite a given default value", func(t *testing.T) {
+		cmd := cli.Command{
+			Flags: []cli.Flag{cli.StringFlag{Name: "service", Value: "default"}},
+		}
+		d := &dirprefs.DirPreferences{Service: "api"}
+
+		flagset := flag.NewFlagSet("", flag.ContinueOnError)
+		flagset.String("service", "default", "")
+		ctx := cli.NewContext(nil, flagset, nil)
+		ctx.Command = cmd
+		ctx.Set("service", "default")
+
+		err := reflectArgs(ctx, p, d, "json")
+		if err != nil {
+			t.Error("loadDirPrefs errored: " + err.Error())
+		}
+
+		if ctx.String("service") != "default" {
+			t.Error("loadDirPrefs overwrote a set argument.")
+		}
+	})
 }
 
 func TestCheckRequiredFlags(t *testing.T) {
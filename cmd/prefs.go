@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/prefs"
 
@@ -42,7 +44,7 @@ func init() {
 
 func listPref(ctx *cli.Context) error {
 	const loadErr = "Failed to load prefs."
-	preferences, err := prefs.NewPreferences()
+	preferences, err := prefs.NewBasePreferences()
 	if err != nil {
 		return errs.NewErrorExitError(loadErr, err)
 	}
@@ -53,6 +55,10 @@ func listPref(ctx *cli.Context) error {
 
 	coreCount := preferences.CountFields("Core")
 	defaultsCount := preferences.CountFields("Defaults")
+	rotationCount := preferences.CountFields("Rotation")
+	machinesCount := preferences.CountFields("Machines")
+	invitesCount := preferences.CountFields("Invites")
+	registryCount := preferences.CountFields("Registry")
 
 	if coreCount > 0 {
 		fmt.Println("[core]")
@@ -74,7 +80,79 @@ func listPref(ctx *cli.Context) error {
 		fd.WriteToIndent(text.NewIndentWriter(os.Stdout, []byte(spacer)), spacer)
 	}
 
-	if defaultsCount < 1 && coreCount < 1 {
+	if rotationCount > 0 {
+		fmt.Println("[rotation]")
+		fr := ini.Empty()
+		err = ini.ReflectFrom(fr, &preferences.Rotation)
+		if err != nil {
+			return errs.NewErrorExitError(loadErr, err)
+		}
+		fr.WriteToIndent(text.NewIndentWriter(os.Stdout, []byte(spacer)), spacer)
+	}
+
+	if machinesCount > 0 {
+		fmt.Println("[machines]")
+		fm := ini.Empty()
+		err = ini.ReflectFrom(fm, &preferences.Machines)
+		if err != nil {
+			return errs.NewErrorExitError(loadErr, err)
+		}
+		fm.WriteToIndent(text.NewIndentWriter(os.Stdout, []byte(spacer)), spacer)
+	}
+
+	if invitesCount > 0 {
+		fmt.Println("[invites]")
+		fi := ini.Empty()
+		err = ini.ReflectFrom(fi, &preferences.Invites)
+		if err != nil {
+			return errs.NewErrorExitError(loadErr, err)
+		}
+		fi.WriteToIndent(text.NewIndentWriter(os.Stdout, []byte(spacer)), spacer)
+	}
+
+	if registryCount > 0 {
+		fmt.Println("[registry]")
+		fg := ini.Empty()
+		err = ini.ReflectFrom(fg, &preferences.Registry)
+		if err != nil {
+			return errs.NewErrorExitError(loadErr, err)
+		}
+		fg.WriteToIndent(text.NewIndentWriter(os.Stdout, []byte(spacer)), spacer)
+	}
+
+	overrides := make([]string, 0, len(preferences.RotationOverrides))
+	for name := range preferences.RotationOverrides {
+		overrides = append(overrides, name)
+	}
+	sort.Strings(overrides)
+	for _, name := range overrides {
+		fmt.Printf("[rotation %s]\n", name)
+		r := preferences.RotationOverrides[name]
+		fo := ini.Empty()
+		err = ini.ReflectFrom(fo, &r)
+		if err != nil {
+			return errs.NewErrorExitError(loadErr, err)
+		}
+		fo.WriteToIndent(text.NewIndentWriter(os.Stdout, []byte(spacer)), spacer)
+	}
+
+	if len(preferences.Profiles) > 0 {
+		rc, err := ini.Load(filepath)
+		if err != nil {
+			return errs.NewErrorExitError(loadErr, err)
+		}
+
+		for _, name := range preferences.Profiles {
+			fmt.Printf("[profile %s]\n", name)
+			for _, key := range rc.Section("profile " + name).Keys() {
+				fmt.Printf("%s%s = %s\n", spacer, key.Name(), key.Value())
+			}
+			fmt.Println("")
+		}
+	}
+
+	if defaultsCount < 1 && coreCount < 1 && rotationCount < 1 && machinesCount < 1 &&
+		invitesCount < 1 && registryCount < 1 && len(overrides) < 1 && len(preferences.Profiles) < 1 {
 		fmt.Println("No preferences set. Use 'torus prefs set' to update.")
 		fmt.Println("")
 	}
@@ -98,11 +176,29 @@ func setPref(ctx *cli.Context) error {
 }
 
 func setPrefByName(key, value string) error {
-	preferences, err := prefs.NewPreferences()
+	preferences, err := prefs.NewBasePreferences()
 	if err != nil {
 		return errs.NewErrorExitError("Failed to load prefs.", err)
 	}
 
+	// A preference in a profile is checked as the [core] or [defaults]
+	// preference it overrides.
+	profile, key, err := profilePref(key)
+	if err != nil {
+		return err
+	}
+
+	if key == "core.profile" {
+		if profile != "" {
+			return errs.NewExitError("Profiles cannot set core.profile")
+		}
+
+		err := checkProfile(preferences, value)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Validate public key file
 	if key == "core.public_key_file" {
 		err := prefs.ValidatePublicKey(value)
@@ -116,21 +212,57 @@ func setPrefByName(key, value string) error {
 		}
 	}
 
+	if key == "core.proxy" {
+		_, err := config.ParseProxy(value)
+		if err != nil {
+			return errs.NewExitError(err.Error())
+		}
+	}
+
+	if key == "core.client_cert_file" || key == "core.client_key_file" {
+		_, err := os.Stat(value)
+		if err != nil {
+			return errs.NewExitError("File must exist")
+		}
+
+		value, err = filepath.Abs(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if strings.HasPrefix(key, "rotation.") || key == "machines.stale_after" ||
+		key == "invites.expire_after" {
+		_, err := prefs.ParseDuration(value)
+		if err != nil {
+			return errs.NewExitError(err.Error())
+		}
+	}
+
 	// Set value inside prefs struct
 	result, err := preferences.SetValue(key, value)
 	if err != nil {
 		return err
 	}
 
-	// Reflect struct to ini format
-	cfg := ini.Empty()
-	err = ini.ReflectFrom(cfg, &result)
+	// Reflect struct to ini format, on top of the existing file so sections
+	// not represented in the struct (such as rotation overrides) are kept.
+	rcPath, _ := prefs.RcPath()
+	cfg, err := ini.Load(rcPath)
+	if err != nil {
+		cfg = ini.Empty()
+	}
+	if profile == "" {
+		err = ini.ReflectFrom(cfg, &result)
+	} else {
+		name := strings.SplitN(key, ".", 2)[1]
+		_, err = cfg.Section("profile "+profile).NewKey(name, value)
+	}
 	if err != nil {
 		return errs.NewErrorExitError("Failed to save preferences.", err)
 	}
 
 	// Save updated ini to filePath
-	rcPath, _ := prefs.RcPath()
 	err = cfg.SaveTo(rcPath)
 	if err != nil {
 		return errs.NewErrorExitError("Failed to save preferences.", err)
@@ -139,3 +271,48 @@ func setPrefByName(key, value string) error {
 	fmt.Println("Preferences updated.")
 	return nil
 }
+
+// profilePref splits a preference key of the form profile.<name>.<key> into
+// the profile name, and the [core] or [defaults] key it overrides. Other
+// keys are returned as they are, with no profile.
+func profilePref(key string) (string, string, error) {
+	if !strings.HasPrefix(key, "profile.") {
+		return "", key, nil
+	}
+
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) < 3 {
+		return "", "", errs.NewExitError("Profile preferences are set with profile.<name>.<key>")
+	}
+
+	err := prefs.ValidateProfileName(parts[1])
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, section := range []string{"core", "defaults"} {
+		_, err = prefs.Preferences{}.SetValue(section+"."+parts[2], "")
+		if err == nil {
+			return parts[1], section + "." + parts[2], nil
+		}
+	}
+
+	return "", "", errs.NewExitError("error: unknown property `" + parts[2] + "`")
+}
+
+// checkProfile returns an error if name is not a profile defined in the
+// preferences, or the default profile.
+func checkProfile(preferences *prefs.Preferences, name string) error {
+	if name == prefs.DefaultProfile {
+		return nil
+	}
+
+	for _, p := range preferences.Profiles {
+		if p == name {
+			return nil
+		}
+	}
+
+	return errs.NewExitError("Unknown profile " + name + ". Create it with 'torus prefs set profile." +
+		name + ".registry_uri <uri>'")
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import "testing"
+
+func TestProfilePref(t *testing.T) {
+	tcs := []struct {
+		key     string
+		profile string
+		pref    string
+		ok      bool
+	}{
+		{"core.registry_uri", "", "core.registry_uri", true},
+		{"profile.staging.registry_uri", "staging", "core.registry_uri", true},
+		{"profile.staging.org", "staging", "defaults.org", true},
+		{"profile.staging.color", "", "", false},
+		{"profile.staging", "", "", false},
+		{"profile.../root.org", "", "", false},
+		{"profile.default.org", "", "", false},
+	}
+
+	for _, tc := range tcs {
+		profile, pref, err := profilePref(tc.key)
+		if tc.ok != (err == nil) {
+			t.Errorf("profilePref(%q) error = %v, want ok %t", tc.key, err, tc.ok)
+			continue
+		}
+		if profile != tc.profile || pref != tc.pref {
+			t.Errorf("profilePref(%q) = %q, %q, want %q, %q", tc.key, profile, pref, tc.profile, tc.pref)
+		}
+	}
+}
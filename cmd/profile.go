@@ -11,14 +11,16 @@ import (
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/prefs"
 
+	"github.com/go-ini/ini"
 	"github.com/urfave/cli"
 )
 
 func init() {
 	profile := cli.Command{
 		Name:     "profile",
-		Usage:    "Manage your Torus account",
+		Usage:    "Manage your Torus account, and switch between profiles",
 		Category: "ACCOUNT",
 		Subcommands: []cli.Command{
 			{
@@ -35,6 +37,17 @@ func init() {
 					ensureDaemon, ensureSession, setUserEnv, profileEdit,
 				),
 			},
+			{
+				Name:      "use",
+				Usage:     "Switch to a profile from .torusrc, each with its own registry, session and defaults",
+				ArgsUsage: "<name>",
+				Action:    profileUse,
+			},
+			{
+				Name:   "list",
+				Usage:  "List the profiles in .torusrc",
+				Action: profileList,
+			},
 		},
 	}
 	Cmds = append(Cmds, profile)
@@ -188,3 +201,69 @@ func changePassword(c *context.Context, client *api.Client, session *api.Session
 	newLabel := "New Password"
 	return PasswordPrompt(true, &newLabel)
 }
+
+// profileUse sets the profile used by commands without --profile.
+func profileUse(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return errs.NewUsageExitError("A profile name is required", ctx)
+	}
+	name := args[0]
+
+	preferences, err := prefs.NewBasePreferences()
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load prefs.", err)
+	}
+
+	err = checkProfile(preferences, name)
+	if err != nil {
+		return err
+	}
+
+	rcPath, _ := prefs.RcPath()
+	rc, err := ini.Load(rcPath)
+	if err != nil {
+		rc = ini.Empty()
+	}
+
+	if name == prefs.DefaultProfile {
+		rc.Section("core").DeleteKey("profile")
+	} else {
+		rc.Section("core").Key("profile").SetValue(name)
+	}
+
+	err = rc.SaveTo(rcPath)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to save preferences.", err)
+	}
+
+	fmt.Printf("Now using profile %s.\n", name)
+	if env := os.Getenv(prefs.ProfileEnv); env != "" && env != name {
+		fmt.Printf("%s is set, so profile %s is used until it is unset.\n", prefs.ProfileEnv, env)
+	}
+
+	return nil
+}
+
+// profileList lists the profiles, marking the active one.
+func profileList(ctx *cli.Context) error {
+	preferences, err := prefs.NewPreferences()
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load prefs.", err)
+	}
+
+	active := preferences.ActiveProfile
+	if active == "" {
+		active = prefs.DefaultProfile
+	}
+
+	for _, name := range append([]string{prefs.DefaultProfile}, preferences.Profiles...) {
+		if name == active {
+			fmt.Printf("  %s [active]\n", name)
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+
+	return nil
+}
@@ -221,6 +221,28 @@ func SelectTeamPrompt(teams []envelope.Team, label, addLabel string) (int, strin
 	return prompt.Run()
 }
 
+// SelectDefaultPrompt prompts the user to select a default from a list, or to
+// have none, in which case an empty string is returned.
+func SelectDefaultPrompt(label string, names []string) (string, error) {
+	preferences, err := prefs.NewPreferences()
+	if err != nil {
+		return "", err
+	}
+
+	prompt := promptui.Select{
+		Label:     label,
+		Items:     append([]string{"No default"}, names...),
+		IsVimMode: preferences.Core.Vim,
+	}
+
+	idx, name, err := prompt.Run()
+	if err != nil || idx == 0 {
+		return "", err
+	}
+
+	return name, nil
+}
+
 func handleSelectError(err error, generic string) error {
 	if err == promptui.ErrEOF || err == promptui.ErrInterrupt {
 		return err
@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 
+	"github.com/manifoldco/torus-cli/dirprefs"
 	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/prefs"
 
 	"github.com/urfave/cli"
 )
@@ -26,6 +30,11 @@ func init() {
 			machineFlag("Use this machine.", false),
 			serviceFlag("Use this service.", "default", true),
 			stdInstanceFlag,
+			stdVerifyFlag,
+			cli.BoolFlag{
+				Name:  "strict",
+				Usage: "Refuse to run unless the secrets listed in .torus.json are set and valid",
+			},
 		},
 		Action: chain(
 			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
@@ -50,6 +59,34 @@ func runCmd(ctx *cli.Context) error {
 		return err
 	}
 
+	dPrefs, err := runDirPrefs()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(secrets))
+	for i, secret := range secrets {
+		names[i] = (*secret.Body).GetName()
+	}
+	if missing := dPrefs.Missing(names); len(missing) > 0 {
+		return errs.NewExitError("Missing required secrets: " + strings.Join(missing, ", "))
+	}
+
+	if problems := unresolvedReferences(secrets); len(problems) > 0 {
+		return errs.NewExitError("Some secrets could not be resolved:\n" + formatProblems(problems))
+	}
+
+	if ctx.Bool("strict") {
+		manifest, err := loadManifest()
+		if err != nil {
+			return err
+		}
+
+		if problems := checkManifest(manifest, secrets); len(problems) > 0 {
+			return errs.NewExitError("The secrets manifest is not met:\n" + formatProblems(problems))
+		}
+	}
+
 	// Create the command. It gets this processes's stdio.
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Stdin = os.Stdin
@@ -57,16 +94,46 @@ func runCmd(ctx *cli.Context) error {
 	cmd.Stderr = os.Stderr
 	cmd.Env = filterEnv()
 
+	// Binary secrets are written to files, which are removed once the
+	// command exits. The path of each file is placed in the env.
+	var secretDir string
+	cleanup := func() {
+		if secretDir != "" {
+			os.RemoveAll(secretDir)
+		}
+	}
+
 	// Add the secrets into the env
 	for _, secret := range secrets {
 		value := (*secret.Body).GetValue()
-		key := strings.ToUpper((*secret.Body).GetName())
+		name := (*secret.Body).GetName()
+		key := dPrefs.EnvName(name)
+
+		if !value.IsBinary() {
+			cmd.Env = append(cmd.Env, key+"="+value.String())
+			continue
+		}
+
+		if secretDir == "" {
+			secretDir, err = ioutil.TempDir("", "torus-run-")
+			if err != nil {
+				return errs.NewErrorExitError("Failed to write secret files", err)
+			}
+		}
+
+		path := filepath.Join(secretDir, name)
+		err = ioutil.WriteFile(path, value.Bytes(), 0600)
+		if err != nil {
+			cleanup()
+			return errs.NewErrorExitError("Failed to write secret files", err)
+		}
 
-		cmd.Env = append(cmd.Env, key+"="+value.String())
+		cmd.Env = append(cmd.Env, key+"="+path)
 	}
 
 	err = cmd.Start()
 	if err != nil {
+		cleanup()
 		return errs.NewErrorExitError("Failed to run command", err)
 	}
 
@@ -86,6 +153,7 @@ func runCmd(ctx *cli.Context) error {
 
 	err = cmd.Wait()
 	close(done)
+	cleanup()
 	if err != nil {
 		if exiterr, ok := err.(*exec.ExitError); ok {
 			if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
@@ -99,6 +167,22 @@ func runCmd(ctx *cli.Context) error {
 	return nil
 }
 
+// runDirPrefs returns the .torus.json preferences holding the secret mappings
+// and required secrets. Like the rest of the context, they are ignored if
+// context is disabled.
+func runDirPrefs() (*dirprefs.DirPreferences, error) {
+	preferences, err := prefs.NewPreferences()
+	if err != nil {
+		return nil, err
+	}
+
+	if !preferences.Core.Context {
+		return &dirprefs.DirPreferences{}, nil
+	}
+
+	return dirprefs.Load(true)
+}
+
 func filterEnv() []string {
 	env := []string{}
 	for _, e := range os.Environ() {
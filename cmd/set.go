@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli"
 
@@ -13,6 +17,7 @@ import (
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/hints"
 	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
 )
 
 var setUnsetFlags = []cli.Flag{
@@ -34,7 +39,26 @@ func init() {
 		Usage:     "Set a secret for a service and environment",
 		ArgsUsage: "<name|path> <value>",
 		Category:  "SECRETS",
-		Flags:     setUnsetFlags,
+		Flags: append(setUnsetFlags,
+			newPlaceholder("type", "TYPE", "Type of the value (string, boolean, object)",
+				"string", "", false),
+			cli.BoolFlag{
+				Name:  "file",
+				Usage: "Store the contents of the file at the path supplied as the value",
+			},
+			cli.BoolFlag{
+				Name:  "ref",
+				Usage: "Refer to the secret at the path supplied as the value, instead of storing a value",
+			},
+			newPlaceholder("description", "DESCRIPTION", "Describe the secret", "", "", false),
+			newPlaceholder("owner", "TEAM", "Team that owns the secret", "", "", false),
+			newSlicePlaceholder("tag", "TAG", "Tag the secret (can be repeated)", "", "", false),
+			newPlaceholder("expires", "DATE", "Date the secret expires (YYYY-MM-DD)", "", "", false),
+			cli.BoolFlag{
+				Name:  "clear-metadata",
+				Usage: "Discard the metadata of the previous value, keeping only the metadata given",
+			},
+		),
 		Action: chain(
 			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
 			setSliceDefaults, setCmd,
@@ -54,8 +78,19 @@ func setCmd(ctx *cli.Context) error {
 		return errs.NewUsageExitError(msg, ctx)
 	}
 
-	cred, err := setCredential(ctx, args[0], func() *apitypes.CredentialValue {
-		return apitypes.NewStringCredentialValue(args[1])
+	value, err := makeCredentialValue(ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	metadata, err := makeCredentialMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	clearMetadata := ctx.Bool("clear-metadata")
+	cred, err := setCredential(ctx, args[0], metadata, clearMetadata, func() *apitypes.CredentialValue {
+		return value
 	})
 
 	if err != nil {
@@ -65,11 +100,132 @@ func setCmd(ctx *cli.Context) error {
 	name := (*cred.Body).GetName()
 	pe := (*cred.Body).GetPathExp()
 	fmt.Printf("\nCredential %s has been set at %s/%s\n", name, pe, name)
+	if value.IsReference() {
+		fmt.Printf("It refers to the secret at %s\n", value.Reference())
+	}
 
 	hints.Display([]string{"view", "run"})
 	return nil
 }
 
+// makeCredentialValue creates the value to store for a secret from the value
+// argument, according to the type of value requested.
+func makeCredentialValue(ctx *cli.Context, raw string) (*apitypes.CredentialValue, error) {
+	valueType := ctx.String("type")
+	if valueType == "" {
+		valueType = "string"
+	}
+
+	if ctx.Bool("ref") && ctx.Bool("file") {
+		return nil, errs.NewUsageExitError("Cannot specify --ref and --file at the same time", ctx)
+	}
+	if (ctx.Bool("ref") || ctx.Bool("file")) && valueType != "string" {
+		return nil, errs.NewUsageExitError("Cannot specify --type with --ref or --file", ctx)
+	}
+
+	switch {
+	case ctx.Bool("ref"):
+		value := apitypes.NewReferenceCredentialValue(strings.ToLower(raw))
+		if !validReference(value.Reference()) {
+			return nil, errs.NewExitError("Reference must be a full secret path, " +
+				"such as /org/project/env/service/identity/instance/name")
+		}
+		return value, nil
+	case ctx.Bool("file"):
+		b, err := ioutil.ReadFile(raw)
+		if err != nil {
+			return nil, errs.NewErrorExitError("Could not read file.", err)
+		}
+		return apitypes.NewBinaryCredentialValue(b), nil
+	}
+
+	switch valueType {
+	case "string":
+		return apitypes.NewStringCredentialValue(raw), nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errs.NewExitError("Value must be true or false for a boolean secret")
+		}
+		return apitypes.NewBoolCredentialValue(b), nil
+	case "object":
+		var o map[string]interface{}
+		err := json.Unmarshal([]byte(raw), &o)
+		if err != nil || o == nil {
+			return nil, errs.NewExitError("Value must be a JSON object for an object secret")
+		}
+		return apitypes.NewObjectCredentialValue(o)
+	default:
+		return nil, errs.NewUsageExitError("Unknown type: "+valueType, ctx)
+	}
+}
+
+// makeCredentialMetadata creates the metadata to store with a secret from the
+// metadata flags. It returns nil if none were provided. Unless cleared, the
+// fields not provided are kept from the metadata of the secret's previous
+// version.
+func makeCredentialMetadata(ctx *cli.Context) (*primitive.CredentialMetadata, error) {
+	description := ctx.String("description")
+	owner := ctx.String("owner")
+	tags := ctx.StringSlice("tag")
+	expires := ctx.String("expires")
+
+	if description == "" && owner == "" && len(tags) == 0 && expires == "" {
+		return nil, nil
+	}
+
+	metadata := &primitive.CredentialMetadata{
+		Description: description,
+		Owner:       strings.ToLower(owner),
+		Tags:        []string{},
+	}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, errs.NewUsageExitError("Tags cannot be empty", ctx)
+		}
+		metadata.Tags = append(metadata.Tags, tag)
+	}
+
+	if expires != "" {
+		t, err := parseExpiry(expires)
+		if err != nil {
+			return nil, errs.NewUsageExitError(err.Error(), ctx)
+		}
+		metadata.Expires = t
+	}
+
+	return metadata, nil
+}
+
+// parseExpiry parses an expiry date given either as a day (YYYY-MM-DD), which
+// is taken to be midnight UTC, or as an RFC3339 timestamp.
+func parseExpiry(raw string) (*time.Time, error) {
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid expiry date %q, use YYYY-MM-DD", raw)
+	}
+
+	t = t.UTC()
+	return &t, nil
+}
+
+// validReference returns whether the reference target is a complete path
+// expression followed by a secret name.
+func validReference(target string) bool {
+	idx := strings.LastIndex(target, "/")
+	if idx == -1 {
+		return false
+	}
+
+	_, err := pathexp.Parse(target[:idx])
+	return err == nil && pathexp.ValidSlug(target[idx+1:])
+}
+
 func determineCredential(ctx *cli.Context, nameOrPath string) (*pathexp.PathExp, *string, error) {
 	// First try and use the cli args as a full path. it should override any
 	// options.
@@ -118,7 +274,8 @@ func determineCredential(ctx *cli.Context, nameOrPath string) (*pathexp.PathExp,
 	return pe, &name, nil
 }
 
-func setCredential(ctx *cli.Context, nameOrPath string, valueMaker func() *apitypes.CredentialValue) (*apitypes.CredentialEnvelope, error) {
+func setCredential(ctx *cli.Context, nameOrPath string, metadata *primitive.CredentialMetadata,
+	clearMetadata bool, valueMaker func() *apitypes.CredentialValue) (*apitypes.CredentialEnvelope, error) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return nil, err
@@ -147,6 +304,14 @@ func setCredential(ctx *cli.Context, nameOrPath string, valueMaker func() *apity
 		return nil, errs.NewExitError("Project not found")
 	}
 	project := projects[0]
+
+	if metadata != nil && metadata.Owner != "" {
+		teams, err := client.Teams.GetByName(c, org.ID, metadata.Owner)
+		if err != nil || len(teams) == 0 {
+			return nil, errs.NewExitError("Owner team not found")
+		}
+	}
+
 	value := valueMaker()
 
 	state := "set"
@@ -164,7 +329,9 @@ func setCredential(ctx *cli.Context, nameOrPath string, valueMaker func() *apity
 			PathExp:   pe,
 			Value:     value,
 		},
-		State: state,
+		State:         state,
+		Metadata:      metadata,
+		ClearMetadata: clearMetadata,
 	}
 	cred = &cBodyV2
 
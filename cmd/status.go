@@ -93,6 +93,9 @@ func statusCmd(ctx *cli.Context) error {
 	instance := ctx.String("instance")
 
 	w := tabwriter.NewWriter(os.Stdout, 2, 0, 1, ' ', 0)
+	if cfg.Profile != "" {
+		fmt.Fprintf(w, "Profile:\t%s\n", cfg.Profile)
+	}
 	fmt.Fprintf(w, "Org:\t%s\n", org)
 	fmt.Fprintf(w, "Project:\t%s\n", project)
 	fmt.Fprintf(w, "Environment:\t%s\n", env)
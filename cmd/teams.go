@@ -87,6 +87,43 @@ func init() {
 					setUserEnv, checkRequiredFlags, teamsRemoveCmd,
 				),
 			},
+			{
+				Name:      "rename",
+				Usage:     "Rename a team in an organization you administer",
+				ArgsUsage: "<team> <new-name>",
+				Flags: []cli.Flag{
+					stdOrgFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setUserEnv, checkRequiredFlags, teamsRenameCmd,
+				),
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a team in an organization you administer",
+				ArgsUsage: "<team>",
+				Flags: []cli.Flag{
+					stdOrgFlag,
+					stdAutoAcceptFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setUserEnv, checkRequiredFlags, teamsDeleteCmd,
+				),
+			},
+			{
+				Name:      "access",
+				Usage:     "List the secrets a team can access, according to its policies",
+				ArgsUsage: "<team>",
+				Flags: []cli.Flag{
+					stdOrgFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setUserEnv, checkRequiredFlags, teamAccessCmd,
+				),
+			},
 		},
 	}
 	Cmds = append(Cmds, teams)
@@ -564,6 +601,121 @@ func teamsAddCmd(ctx *cli.Context) error {
 	return nil
 }
 
+func teamsRenameCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 2 {
+		return errs.NewUsageExitError("A team and its new name are required", ctx)
+	}
+
+	c, client, err := NewAPIClient(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	org, team, err := getUserTeam(c, client, ctx.String("org"), args[0], "renamed")
+	if err != nil {
+		return err
+	}
+
+	label := "New team name"
+	name, err := NamePrompt(&label, args[1], true)
+	if err != nil {
+		return handleSelectError(err, "Could not rename team.")
+	}
+
+	existing, err := client.Teams.GetByName(c, org.ID, name)
+	if err != nil {
+		return errs.NewErrorExitError("Could not rename team.", err)
+	}
+	if len(existing) > 0 {
+		return errs.NewExitError("Team " + name + " already exists.")
+	}
+
+	_, err = client.Teams.Rename(c, team.ID, name)
+	if err != nil {
+		return errs.NewErrorExitError("Could not rename team.", err)
+	}
+
+	fmt.Printf("Team %s renamed to %s.\n", team.Body.Name, name)
+	return nil
+}
+
+func teamsDeleteCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return errs.NewUsageExitError("A team name is required", ctx)
+	}
+
+	c, client, err := NewAPIClient(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	org, team, err := getUserTeam(c, client, ctx.String("org"), args[0], "deleted")
+	if err != nil {
+		return err
+	}
+
+	attachments, err := client.Policies.AttachmentsList(c, org.ID, team.ID, nil)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve policy attachments.", err)
+	}
+
+	preamble := fmt.Sprintf("You are about to delete the %s team. Its %d policies will be "+
+		"detached, and its members will lose the access they give.", team.Body.Name, len(attachments))
+	abortErr := ConfirmDialogue(ctx, nil, &preamble, "", true)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	for _, a := range attachments {
+		err = client.Policies.Detach(c, a.ID)
+		if err != nil {
+			return errs.NewErrorExitError(policyDetachFailed, err)
+		}
+	}
+
+	err = client.Teams.Delete(c, team.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Could not delete team.", err)
+	}
+
+	fmt.Printf("Team %s deleted.\n", team.Body.Name)
+	return syncOrgKeyringMembers(c, client, org.ID)
+}
+
+// getUserTeam looks up a team that users can be members of, to be renamed or
+// deleted. System teams and machine roles are refused.
+func getUserTeam(c context.Context, client *api.Client, orgName, teamName,
+	verb string) (*envelope.Org, *envelope.Team, error) {
+
+	org, err := client.Orgs.GetByName(c, orgName)
+	if err != nil {
+		return nil, nil, errs.NewErrorExitError("Unable to lookup org.", err)
+	}
+	if org == nil {
+		return nil, nil, errs.NewExitError("Org not found.")
+	}
+
+	teams, err := client.Teams.GetByName(c, org.ID, teamName)
+	if err != nil {
+		return nil, nil, errs.NewErrorExitError("Unable to lookup team.", err)
+	}
+	if len(teams) < 1 {
+		return nil, nil, errs.NewExitError("Team not found.")
+	}
+
+	team := &teams[0]
+	switch {
+	case team.Body.TeamType == primitive.SystemTeamType:
+		return nil, nil, errs.NewExitError("System teams cannot be " + verb + ".")
+	case isMachineTeam(team.Body):
+		return nil, nil, errs.NewExitError(team.Body.Name + " is a machine role. Use 'torus machines roles' instead.")
+	}
+
+	return org, team, nil
+}
+
 // isMachineTeam returns whether or not the given team represents a machine
 // role (which uses the Team primitive)
 func isMachineTeam(team *primitive.Team) bool {
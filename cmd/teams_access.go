@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// accessStatement is a parsed policy statement attached to a team.
+type accessStatement struct {
+	effect primitive.PolicyEffect
+	action primitive.PolicyAction
+	path   *pathexp.PathExp
+	secret string
+}
+
+// secretAccess is what a team may do with the secrets matching a name at a
+// location in the project tree.
+type secretAccess struct {
+	secret  string
+	actions primitive.PolicyAction
+}
+
+// policyActions are the actions a statement can cover.
+var policyActions = []primitive.PolicyAction{
+	primitive.PolicyActionCreate,
+	primitive.PolicyActionRead,
+	primitive.PolicyActionUpdate,
+	primitive.PolicyActionDelete,
+	primitive.PolicyActionList,
+}
+
+func teamAccessCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return errs.NewUsageExitError("A team name is required", ctx)
+	}
+
+	c, client, err := NewAPIClient(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	tree, err := projectTreeForOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return err
+	}
+	org := tree.Org
+
+	teams, err := client.Teams.GetByName(c, org.ID, args[0])
+	if err != nil {
+		return errs.NewErrorExitError("Unable to lookup team.", err)
+	}
+	if len(teams) < 1 {
+		return errs.NewExitError("Team not found.")
+	}
+	team := teams[0]
+
+	attachments, err := client.Policies.AttachmentsList(c, org.ID, team.ID, nil)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve policy attachments.", err)
+	}
+
+	policies, err := client.Policies.List(c, org.ID, "")
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve policies.", err)
+	}
+
+	attached := make(map[identity.ID]bool, len(attachments))
+	for _, a := range attachments {
+		attached[*a.Body.PolicyID] = true
+	}
+
+	var teamPolicies []envelope.Policy
+	for _, p := range policies {
+		if attached[*p.ID] {
+			teamPolicies = append(teamPolicies, p)
+		}
+	}
+
+	stmts, narrowed := parseAccessStatements(teamPolicies)
+
+	envs := make(map[identity.ID][]string)
+	for _, e := range tree.Envs {
+		envs[*e.Body.ProjectID] = append(envs[*e.Body.ProjectID], e.Body.Name)
+	}
+	services := make(map[identity.ID][]string)
+	for _, s := range tree.Services {
+		services[*s.Body.ProjectID] = append(services[*s.Body.ProjectID], s.Body.Name)
+	}
+
+	rows := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "PATH\tSECRET\tACTIONS")
+	for _, p := range tree.Projects {
+		pName := p.Body.Name
+		for _, env := range envs[*p.ID] {
+			for _, service := range services[*p.ID] {
+				access := effectiveAccess(stmts, org.Body.Name, pName, env, service)
+				for _, a := range access {
+					path := "/" + strings.Join([]string{org.Body.Name, pName, env, service}, "/")
+					fmt.Fprintf(w, "%s\t%s\t%s\n", path, a.secret, a.actions.ShortString())
+					rows++
+				}
+			}
+		}
+	}
+
+	if rows == 0 {
+		fmt.Printf("The %s team has no access to secrets in %s.\n", team.Body.Name, org.Body.Name)
+	} else {
+		fmt.Println("")
+		w.Flush()
+		fmt.Println("")
+	}
+
+	if narrowed > 0 {
+		fmt.Printf("%d statements apply only to particular identities or instances, and are not shown.\n"+
+			"Use '%s policies view' to see them.\n", narrowed, ctx.App.Name)
+	}
+
+	return nil
+}
+
+// parseAccessStatements returns the statements of the policies that apply to
+// every identity and instance, and the number that apply to fewer, which are
+// left out. Statements with resources that don't parse are skipped.
+func parseAccessStatements(policies []envelope.Policy) ([]accessStatement, int) {
+	var stmts []accessStatement
+	narrowed := 0
+	for _, p := range policies {
+		for _, s := range p.Body.Policy.Statements {
+			idx := strings.LastIndex(s.Resource, "/")
+			if idx == -1 {
+				continue
+			}
+
+			pe, err := pathexp.Parse(s.Resource[:idx])
+			if err != nil {
+				continue
+			}
+
+			if pe.Identities.String() != "*" || pe.Instances.String() != "*" {
+				narrowed++
+				continue
+			}
+
+			stmts = append(stmts, accessStatement{
+				effect: s.Effect,
+				action: s.Action,
+				path:   pe,
+				secret: s.Resource[idx+1:],
+			})
+		}
+	}
+
+	return stmts, narrowed
+}
+
+// effectiveAccess evaluates the statements for the secrets of a service in
+// an environment, returning the actions allowed for each secret name the
+// statements mention, sorted by name. Names with no actions allowed are left
+// out.
+//
+// For each action, the most specific statement that covers it wins: the one
+// with the most specific path, then the most specific secret name. A deny
+// wins over an allow that is as specific.
+func effectiveAccess(stmts []accessStatement, org, project, env, service string) []secretAccess {
+	var matching []accessStatement
+	names := make(map[string]bool)
+	for _, s := range stmts {
+		if !s.path.Org.Contains(org) || !s.path.Project.Contains(project) ||
+			!s.path.Envs.Contains(env) || !s.path.Services.Contains(service) {
+			continue
+		}
+
+		matching = append(matching, s)
+		names[s.secret] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var access []secretAccess
+	for _, name := range sorted {
+		var actions primitive.PolicyAction
+		for _, action := range policyActions {
+			var winner *accessStatement
+			for i, s := range matching {
+				if s.action&action == 0 || !secretNameContains(s.secret, name) {
+					continue
+				}
+				if winner == nil || moreSpecific(&matching[i], winner) {
+					winner = &matching[i]
+				}
+			}
+
+			if winner != nil && winner.effect == primitive.PolicyEffectAllow {
+				actions |= action
+			}
+		}
+
+		if actions != 0 {
+			access = append(access, secretAccess{secret: name, actions: actions})
+		}
+	}
+
+	return access
+}
+
+// moreSpecific returns whether statement a takes precedence over b.
+func moreSpecific(a, b *accessStatement) bool {
+	if cmp := a.path.CompareSpecificity(b.path); cmp != 0 {
+		return cmp > 0
+	}
+
+	if ra, rb := secretNameRank(a.secret), secretNameRank(b.secret); ra != rb {
+		return ra > rb
+	}
+
+	return a.effect == primitive.PolicyEffectDeny && b.effect == primitive.PolicyEffectAllow
+}
+
+// secretNameContains returns whether the secret name, which may be a glob,
+// covers every secret the subject covers.
+func secretNameContains(name, subject string) bool {
+	switch {
+	case name == "*":
+		return true
+	case strings.HasSuffix(name, "*"):
+		return pathexp.GlobContains(strings.TrimSuffix(name, "*"), subject)
+	default:
+		return name == subject
+	}
+}
+
+// secretNameRank ranks the specificity of a secret name in a statement.
+func secretNameRank(name string) int {
+	switch {
+	case name == "*":
+		return 0
+	case strings.HasSuffix(name, "*"):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func accessPolicy(stmts ...primitive.PolicyStatement) []envelope.Policy {
+	p := primitive.Policy{}
+	p.Policy.Statements = stmts
+	return []envelope.Policy{{Body: &p}}
+}
+
+func stmt(effect primitive.PolicyEffect, action primitive.PolicyAction,
+	resource string) primitive.PolicyStatement {
+	return primitive.PolicyStatement{Effect: effect, Action: action, Resource: resource}
+}
+
+func TestEffectiveAccess(t *testing.T) {
+	allow := primitive.PolicyEffect(primitive.PolicyEffectAllow)
+	deny := primitive.PolicyEffect(primitive.PolicyEffectDeny)
+	read := primitive.PolicyAction(primitive.PolicyActionRead)
+	rl := primitive.PolicyAction(primitive.PolicyActionRead | primitive.PolicyActionList)
+
+	tcs := []struct {
+		name     string
+		stmts    []primitive.PolicyStatement
+		narrowed int
+		access   []secretAccess
+	}{
+		{
+			name:  "allowed everywhere",
+			stmts: []primitive.PolicyStatement{stmt(allow, rl, "/org/proj/*/*/*/*/*")},
+			access: []secretAccess{
+				{"*", rl},
+			},
+		},
+		{
+			name:   "other environment",
+			stmts:  []primitive.PolicyStatement{stmt(allow, rl, "/org/proj/dev/*/*/*/*")},
+			access: nil,
+		},
+		{
+			name: "deny wins at the same specificity",
+			stmts: []primitive.PolicyStatement{
+				stmt(allow, rl, "/org/proj/prod/*/*/*/*"),
+				stmt(deny, read, "/org/proj/prod/*/*/*/*"),
+			},
+			access: []secretAccess{
+				{"*", primitive.PolicyActionList},
+			},
+		},
+		{
+			name: "more specific path wins",
+			stmts: []primitive.PolicyStatement{
+				stmt(deny, rl, "/org/proj/*/*/*/*/*"),
+				stmt(allow, read, "/org/proj/prod/api/*/*/*"),
+			},
+			access: []secretAccess{
+				{"*", read},
+			},
+		},
+		{
+			name: "specific secret denied",
+			stmts: []primitive.PolicyStatement{
+				stmt(allow, rl, "/org/proj/*/*/*/*/*"),
+				stmt(deny, read, "/org/proj/*/*/*/*/db_password"),
+			},
+			access: []secretAccess{
+				{"*", rl},
+				{"db_password", primitive.PolicyActionList},
+			},
+		},
+		{
+			name: "identity specific statements left out",
+			stmts: []primitive.PolicyStatement{
+				stmt(allow, rl, "/org/proj/*/*/jeff/*/*"),
+			},
+			narrowed: 1,
+			access:   nil,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			stmts, narrowed := parseAccessStatements(accessPolicy(tc.stmts...))
+			if narrowed != tc.narrowed {
+				t.Errorf("narrowed = %d, want %d", narrowed, tc.narrowed)
+			}
+
+			access := effectiveAccess(stmts, "org", "proj", "prod", "api")
+			if !reflect.DeepEqual(access, tc.access) {
+				t.Errorf("access = %v, want %v", access, tc.access)
+			}
+		})
+	}
+}
@@ -48,7 +48,7 @@ func unsetCmd(ctx *cli.Context) error {
 	}
 
 	var cred *apitypes.CredentialEnvelope
-	cred, err = setCredential(ctx, args[0], func() *apitypes.CredentialValue {
+	cred, err = setCredential(ctx, args[0], nil, false, func() *apitypes.CredentialValue {
 		return apitypes.NewUnsetCredentialValue()
 	})
 
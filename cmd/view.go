@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/urfave/cli"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/hints"
+	"github.com/manifoldco/torus-cli/primitive"
 )
 
 func init() {
@@ -22,7 +24,7 @@ func init() {
 		Name:     "view",
 		Usage:    "View secrets for the current service and environment",
 		Category: "SECRETS",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			stdOrgFlag,
 			stdProjectFlag,
 			stdEnvFlag,
@@ -33,9 +35,10 @@ func init() {
 			formatFlag("env", "Format used to display data (json, env, verbose)"),
 			cli.BoolFlag{
 				Name:  "verbose, v",
-				Usage: "Lists the sources of the secrets (shortcut for --format verbose)",
+				Usage: "Lists the sources and metadata of the secrets (shortcut for --format verbose)",
 			},
-		},
+			stdVerifyFlag,
+		}, credentialFilterFlags...),
 		Action: chain(
 			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
 			setUserEnv, checkRequiredFlags, viewCmd,
@@ -46,10 +49,21 @@ func init() {
 }
 
 func viewCmd(ctx *cli.Context) error {
+	filter, err := newCredentialFilter(ctx)
+	if err != nil {
+		return err
+	}
+
 	secrets, path, err := getSecrets(ctx)
 	if err != nil {
 		return err
 	}
+	secrets = filter.Filter(secrets)
+
+	// Unresolved references are shown as they are stored, with a warning.
+	for _, p := range unresolvedReferences(secrets) {
+		fmt.Fprintf(os.Stderr, "Warning: %s has an %s\n", p.name, p.problem)
+	}
 
 	if ctx.Bool("verbose") && ctx.IsSet("format") {
 		return errs.NewUsageExitError(
@@ -99,7 +113,14 @@ func printVerboseFormat(secrets []apitypes.CredentialEnvelope, path string) erro
 		name := (*secret.Body).GetName()
 		key := strings.ToUpper(name)
 		spath := (*secret.Body).GetPathExp().String() + "/" + name
-		fmt.Fprintf(w, "%s=%s\t%s\n", key, value.String(), spath)
+		var metadata string
+		if v2, ok := (*secret.Body).(*apitypes.CredentialV2); ok {
+			for _, ref := range v2.References {
+				spath += " -> " + ref
+			}
+			metadata = formatCredentialMetadata(v2.Metadata)
+		}
+		fmt.Fprintf(w, "%s=%s\t%s\t%s\n", key, value.String(), spath, metadata)
 	}
 	w.Flush()
 
@@ -107,6 +128,34 @@ func printVerboseFormat(secrets []apitypes.CredentialEnvelope, path string) erro
 
 }
 
+// formatCredentialMetadata returns a single line summary of the given
+// credential metadata, for verbose output.
+func formatCredentialMetadata(md *primitive.CredentialMetadata) string {
+	if md == nil {
+		return ""
+	}
+
+	var parts []string
+	if md.Owner != "" {
+		parts = append(parts, "owner: "+md.Owner)
+	}
+	if len(md.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(md.Tags, ","))
+	}
+	if md.Expires != nil {
+		expires := "expires: " + md.Expires.Format("2006-01-02")
+		if !md.Expires.After(time.Now()) {
+			expires += " (expired)"
+		}
+		parts = append(parts, expires)
+	}
+	if md.Description != "" {
+		parts = append(parts, md.Description)
+	}
+
+	return strings.Join(parts, "; ")
+}
+
 func printJSONFormat(secrets []apitypes.CredentialEnvelope, path string) error {
 	keyMap := make(map[string]interface{})
 
@@ -156,7 +205,16 @@ func getSecrets(ctx *cli.Context) ([]apitypes.CredentialEnvelope, string, error)
 
 	path := strings.Join(parts, "/")
 
-	secrets, err := client.Credentials.Get(c, path)
+	var secrets []apitypes.CredentialEnvelope
+	if ctx.Bool("verify") {
+		secrets, err = client.Credentials.GetVerified(c, path)
+		if apitypes.IsVerificationFailedError(err) {
+			return nil, "", errs.NewErrorExitError(
+				"Secrets could not be verified, and may have been tampered with", err)
+		}
+	} else {
+		secrets, err = client.Credentials.Get(c, path)
+	}
 	if err != nil {
 		return nil, "", errs.NewErrorExitError("Error fetching secrets", err)
 	}
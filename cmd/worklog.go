@@ -181,10 +181,22 @@ func worklogResolve(ctx *cli.Context) error {
 
 	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
 	for _, item := range toResolve {
-		if item.Type() == apitypes.InviteApproveWorklogType {
+		// Items that change who or what is in the org are confirmed one by
+		// one.
+		var confirm string
+		switch item.Type() {
+		case apitypes.InviteApproveWorklogType:
+			confirm = "Approve invite for " + item.Subject
+		case apitypes.ExpiredInviteWorklogType:
+			confirm = "Revoke expired invite for " + item.Subject
+		case apitypes.StaleMachineWorklogType:
+			confirm = "Destroy machine " + item.Subject
+		}
+
+		if confirm != "" {
 			w.Flush()
 
-			err = AskPerform("Approve invite for " + item.Subject)
+			err = AskPerform(confirm)
 			switch err {
 			case nil:
 			case promptui.ErrAbort:
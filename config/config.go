@@ -3,16 +3,19 @@
 package config
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
+	"time"
 
 	"github.com/manifoldco/torus-cli/data"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/prefs"
+	"github.com/manifoldco/torus-cli/registry"
 )
 
 // Version is the compiled version of our binary. It is set via the Makefile.
@@ -27,6 +30,10 @@ type Config struct {
 	APIVersion string
 	Version    string
 
+	// Profile is the name of the active profile, if any. Each profile has
+	// its own root dir, and so its own daemon and session.
+	Profile string
+
 	TorusRoot  string
 	SocketPath string
 	PidPath    string
@@ -35,6 +42,30 @@ type Config struct {
 	RegistryURI *url.URL
 	CABundle    *x509.CertPool
 	PublicKey   *prefs.PublicKey
+
+	// Proxy is the proxy to reach the registry through. If nil, the
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are used.
+	Proxy *url.URL
+
+	// ClientCert is presented to the registry, if set.
+	ClientCert *tls.Certificate
+
+	// RegistryRetry controls the timeouts and retries of registry requests.
+	RegistryRetry *registry.RetryPolicy
+
+	Rotation *RotationPolicy
+
+	// MachineStaleAfter is how long a machine can go without logging in
+	// before it is flagged as stale. Zero disables the check.
+	MachineStaleAfter time.Duration
+
+	// MachineGenerateKeypairs is whether a machine should generate its
+	// keypairs when it logs in and finds it has none for its org.
+	MachineGenerateKeypairs bool
+
+	// InviteExpireAfter is how long an invite can go without being accepted
+	// before it is flagged as expired. Zero disables the check.
+	InviteExpireAfter time.Duration
 }
 
 // NewConfig returns a new Config, with loaded user preferences.
@@ -59,10 +90,48 @@ func NewConfig(torusRoot string) (*Config, error) {
 		return nil, fmt.Errorf("invalid registry_uri")
 	}
 
+	proxy, err := newProxy(preferences)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCert, err := loadClientCert(preferences)
+	if err != nil {
+		return nil, err
+	}
+
+	retry, err := newRetryPolicy(preferences)
+	if err != nil {
+		return nil, err
+	}
+
+	rotation, err := newRotationPolicy(preferences)
+	if err != nil {
+		return nil, err
+	}
+
+	var staleAfter time.Duration
+	if raw := preferences.Machines.StaleAfter; raw != "" {
+		staleAfter, err = prefs.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stale_after in [machines]: %s", err)
+		}
+	}
+
+	var expireAfter time.Duration
+	if raw := preferences.Invites.ExpireAfter; raw != "" {
+		expireAfter, err = prefs.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expire_after in [invites]: %s", err)
+		}
+	}
+
 	cfg := &Config{
 		APIVersion: apiVersion,
 		Version:    Version,
 
+		Profile: preferences.ActiveProfile,
+
 		TorusRoot:  torusRoot,
 		SocketPath: path.Join(torusRoot, "daemon.socket"),
 		PidPath:    path.Join(torusRoot, "daemon.pid"),
@@ -71,23 +140,47 @@ func NewConfig(torusRoot string) (*Config, error) {
 		RegistryURI: registryURI,
 		CABundle:    caBundle,
 		PublicKey:   publicKey,
+		Proxy:       proxy,
+		ClientCert:  clientCert,
+
+		RegistryRetry: retry,
+
+		Rotation:                rotation,
+		MachineStaleAfter:       staleAfter,
+		MachineGenerateKeypairs: preferences.Machines.GenerateKeypairs,
+		InviteExpireAfter:       expireAfter,
 	}
 
 	return cfg, nil
 }
 
-func torusRootPath() string {
+// torusRootPath returns the root dir for the active profile. Profiles each
+// have a dir under profiles/ in the Torus root dir.
+func torusRootPath() (string, error) {
 	torusRoot := os.Getenv("TORUS_ROOT")
 	if len(torusRoot) == 0 {
 		torusRoot = path.Join(os.Getenv("HOME"), ".torus")
 	}
 
-	return torusRoot
+	preferences, err := prefs.NewPreferences()
+	if err != nil {
+		return "", err
+	}
+
+	if preferences.ActiveProfile != "" {
+		torusRoot = path.Join(torusRoot, "profiles", preferences.ActiveProfile)
+	}
+
+	return torusRoot, nil
 }
 
 // CreateTorusRoot creates the root directory for the Torus daemon.
 func CreateTorusRoot(checkPermissions bool) (string, error) {
-	torusRoot := torusRootPath()
+	torusRoot, err := torusRootPath()
+	if err != nil {
+		return "", err
+	}
+
 	src, err := os.Stat(torusRoot)
 	if err != nil && !os.IsNotExist(err) {
 		return "", err
@@ -98,7 +191,7 @@ func CreateTorusRoot(checkPermissions bool) (string, error) {
 	}
 
 	if os.IsNotExist(err) {
-		err = os.Mkdir(torusRoot, requiredPermissions)
+		err = os.MkdirAll(torusRoot, requiredPermissions)
 		if err != nil {
 			return "", err
 		}
@@ -144,7 +237,12 @@ func loadCABundle(cafile string) (*x509.CertPool, error) {
 
 // LoadConfig loads the config, standardizing cli errors on failure.
 func LoadConfig() (*Config, error) {
-	cfg, err := NewConfig(torusRootPath())
+	torusRoot, err := torusRootPath()
+	if err != nil {
+		return nil, errs.NewErrorExitError("Failed to load config.", err)
+	}
+
+	cfg, err := NewConfig(torusRoot)
 	if err != nil {
 		return nil, errs.NewErrorExitError("Failed to load config.", err)
 	}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/manifoldco/torus-cli/prefs"
+	"github.com/manifoldco/torus-cli/registry"
+)
+
+// newRetryPolicy returns the registry retry policy, with the values set in
+// the [registry] section replacing the defaults.
+func newRetryPolicy(preferences *prefs.Preferences) (*registry.RetryPolicy, error) {
+	p := registry.DefaultRetryPolicy
+	r := preferences.Registry
+
+	durations := []struct {
+		name  string
+		raw   string
+		value *time.Duration
+	}{
+		{"timeout", r.Timeout, &p.Timeout},
+		{"backoff", r.Backoff, &p.Backoff},
+		{"max_backoff", r.MaxBackoff, &p.MaxBackoff},
+		{"breaker_cooldown", r.BreakerCooldown, &p.BreakerCooldown},
+	}
+	for _, d := range durations {
+		if d.raw == "" {
+			continue
+		}
+
+		v, err := prefs.ParseDuration(d.raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in [registry]: %s", d.name, err)
+		}
+		*d.value = v
+	}
+
+	counts := []struct {
+		name  string
+		raw   string
+		value *int
+	}{
+		{"retries", r.Retries, &p.MaxRetries},
+		{"breaker_threshold", r.BreakerThreshold, &p.BreakerThreshold},
+	}
+	for _, c := range counts {
+		if c.raw == "" {
+			continue
+		}
+
+		v, err := strconv.Atoi(c.raw)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid %s in [registry]: %s is not a count", c.name, c.raw)
+		}
+		*c.value = v
+	}
+
+	if p.Timeout == 0 {
+		return nil, fmt.Errorf("invalid timeout in [registry]: must be more than 0")
+	}
+	if p.MaxBackoff < p.Backoff {
+		p.MaxBackoff = p.Backoff
+	}
+
+	return &p, nil
+}
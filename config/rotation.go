@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/manifoldco/torus-cli/prefs"
+)
+
+// defaultExpiryWarning is how long before a secret's declared expiry date it is
+// flagged for rotation, when not configured.
+const defaultExpiryWarning = 14 * 24 * time.Hour
+
+// RotationThresholds are the durations after which secrets are flagged for
+// rotation. A zero MaxAge disables age based rotation.
+type RotationThresholds struct {
+	MaxAge        time.Duration
+	ExpiryWarning time.Duration
+}
+
+// rotationOverride holds the thresholds set for an org or project. Unset
+// values are inherited.
+type rotationOverride struct {
+	maxAge        *time.Duration
+	expiryWarning *time.Duration
+}
+
+// RotationPolicy holds the default rotation thresholds, along with those
+// configured for specific orgs and projects.
+type RotationPolicy struct {
+	defaults  RotationThresholds
+	overrides map[string]rotationOverride
+}
+
+// For returns the rotation thresholds for the given project within the given
+// org. Values set for the project take precedence over those set for the org,
+// which take precedence over the defaults.
+func (p *RotationPolicy) For(org, project string) RotationThresholds {
+	if p == nil {
+		return RotationThresholds{ExpiryWarning: defaultExpiryWarning}
+	}
+
+	t := p.defaults
+	for _, key := range []string{org, org + "/" + project} {
+		o, ok := p.overrides[key]
+		if !ok {
+			continue
+		}
+
+		if o.maxAge != nil {
+			t.MaxAge = *o.maxAge
+		}
+		if o.expiryWarning != nil {
+			t.ExpiryWarning = *o.expiryWarning
+		}
+	}
+
+	return t
+}
+
+func newRotationPolicy(preferences *prefs.Preferences) (*RotationPolicy, error) {
+	base, err := parseRotation("rotation", preferences.Rotation)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &RotationPolicy{
+		defaults: RotationThresholds{
+			ExpiryWarning: defaultExpiryWarning,
+		},
+		overrides: make(map[string]rotationOverride),
+	}
+	if base.maxAge != nil {
+		p.defaults.MaxAge = *base.maxAge
+	}
+	if base.expiryWarning != nil {
+		p.defaults.ExpiryWarning = *base.expiryWarning
+	}
+
+	for name, r := range preferences.RotationOverrides {
+		o, err := parseRotation("rotation "+name, r)
+		if err != nil {
+			return nil, err
+		}
+		p.overrides[name] = *o
+	}
+
+	return p, nil
+}
+
+func parseRotation(section string, r prefs.Rotation) (*rotationOverride, error) {
+	o := &rotationOverride{}
+
+	if r.MaxAge != "" {
+		d, err := prefs.ParseDuration(r.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_age in [%s]: %s", section, err)
+		}
+		o.maxAge = &d
+	}
+
+	if r.ExpiryWarning != "" {
+		d, err := prefs.ParseDuration(r.ExpiryWarning)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry_warning in [%s]: %s", section, err)
+		}
+		o.expiryWarning = &d
+	}
+
+	return o, nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/prefs"
+)
+
+func TestRotationPolicyFor(t *testing.T) {
+	day := 24 * time.Hour
+	p, err := newRotationPolicy(&prefs.Preferences{
+		Rotation: prefs.Rotation{MaxAge: "90d"},
+		RotationOverrides: map[string]prefs.Rotation{
+			"org":         {MaxAge: "30d", ExpiryWarning: "7d"},
+			"org/project": {MaxAge: "12h"},
+		},
+	})
+	if err != nil {
+		t.Fatal("error seen:", err)
+	}
+
+	tcs := []struct {
+		org, project string
+		want         RotationThresholds
+	}{
+		{"other", "project", RotationThresholds{90 * day, defaultExpiryWarning}},
+		{"org", "other", RotationThresholds{30 * day, 7 * day}},
+		{"org", "project", RotationThresholds{12 * time.Hour, 7 * day}},
+	}
+
+	for _, tc := range tcs {
+		if got := p.For(tc.org, tc.project); got != tc.want {
+			t.Errorf("For(%s, %s) = %v, want %v", tc.org, tc.project, got, tc.want)
+		}
+	}
+}
+
+func TestRotationPolicyInvalid(t *testing.T) {
+	_, err := newRotationPolicy(&prefs.Preferences{
+		RotationOverrides: map[string]prefs.Rotation{"org": {MaxAge: "soon"}},
+	})
+	if err == nil {
+		t.Error("expected error for invalid max_age")
+	}
+}
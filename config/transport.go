@@ -0,0 +1,63 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+
+	"github.com/manifoldco/torus-cli/prefs"
+)
+
+// ParseProxy parses the url of a proxy to reach the registry through. HTTP
+// proxies are used with CONNECT, and SOCKS5 proxies may require a username
+// and password.
+func ParseProxy(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %s, use a url such as http://proxy:3128", raw)
+	}
+
+	switch u.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %s, use http or socks5", u.Scheme)
+	}
+
+	return u, nil
+}
+
+// newProxy returns the proxy set in core.proxy, or nil if none is set, in
+// which case the proxy environment variables are used.
+func newProxy(preferences *prefs.Preferences) (*url.URL, error) {
+	if preferences.Core.Proxy == "" {
+		return nil, nil
+	}
+
+	proxy, err := ParseProxy(preferences.Core.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy in [core]: %s", err)
+	}
+
+	return proxy, nil
+}
+
+// loadClientCert loads the client certificate presented to the registry, if
+// one is configured.
+func loadClientCert(preferences *prefs.Preferences) (*tls.Certificate, error) {
+	certFile := preferences.Core.ClientCertFile
+	keyFile := preferences.Core.ClientKeyFile
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("client_cert_file and client_key_file must be set together")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load client certificate: %s", err)
+	}
+
+	return &cert, nil
+}
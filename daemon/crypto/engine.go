@@ -113,6 +113,12 @@ func (e *Engine) Unseal(ctx context.Context, ct, nonce []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	return UnsealWithMasterKey(ctx, mk, ct, nonce)
+}
+
+// UnsealWithMasterKey decrypts the ciphertext ct like Unseal, but using the
+// given master key instead of the user's.
+func UnsealWithMasterKey(ctx context.Context, mk, ct, nonce []byte) ([]byte, error) {
 	dk, err := deriveKey(ctx, mk, nonce, blakeSize)
 	if err != nil {
 		return nil, err
@@ -428,6 +434,25 @@ func (e *Engine) Verify(ctx context.Context, s SignatureKeyPair, b, sig []byte)
 	return ed25519.Verify(s.Public, b, sig), nil
 }
 
+// VerifySigned verifies that sig is the correct signature for the given
+// body, made by the private half of the given public signing key. The body is
+// encoded the same way it was when it was signed.
+func (e *Engine) VerifySigned(ctx context.Context, body identity.Immutable,
+	sig *primitive.Signature, public ed25519.PublicKey) (bool, error) {
+
+	if sig == nil || sig.Value == nil || len(public) != ed25519.PublicKeySize {
+		return false, nil
+	}
+
+	b, err := json.Marshal(&body)
+	if err != nil {
+		return false, err
+	}
+
+	s := SignatureKeyPair{Public: public}
+	return e.Verify(ctx, s, append([]byte(strconv.Itoa(body.Version())), b...), *sig.Value)
+}
+
 func (e *Engine) signAndID(ctx context.Context, body identity.Immutable,
 	sigID *identity.ID, sigKP *SignatureKeyPair) (*identity.ID, *primitive.Signature, error) {
 
@@ -507,6 +532,38 @@ func (e *Engine) ChangePassword(ctx context.Context, newPassword string) (*primi
 	return EncryptPasswordObject(ctx, newPassword, &currentMasterKey)
 }
 
+// ExportMasterKey encrypts the user's master key with triplesec-v3 using the
+// given recovery key, so it can be recovered if the user's password is lost.
+func (e *Engine) ExportMasterKey(ctx context.Context, recoveryKey []byte) ([]byte, error) {
+	mk, err := e.unsealMasterKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := newTriplesec(ctx, recoveryKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.Encrypt(mk)
+}
+
+// RecoverMasterKey decrypts a master key exported with ExportMasterKey, using
+// the same recovery key.
+func RecoverMasterKey(ctx context.Context, ct, recoveryKey []byte) ([]byte, error) {
+	ts, err := newTriplesec(ctx, recoveryKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctxutil.ErrIfDone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.Decrypt(ct)
+}
+
 // deriveKey Derives a single use key from the given master key via blake2b
 // and a nonce.
 func deriveKey(ctx context.Context, mk, nonce []byte, size uint8) ([]byte, error) {
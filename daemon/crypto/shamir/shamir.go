@@ -0,0 +1,153 @@
+// Package shamir implements Shamir's secret sharing over GF(2^8).
+//
+// A secret is split into n shares, any k of which can be combined to recover
+// it. Fewer than k shares reveal nothing about the secret. Each byte of the
+// secret is shared independently, using a random polynomial of degree k-1.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// MaxShares is the largest number of shares a secret can be split into.
+const MaxShares = 255
+
+var (
+	errThreshold    = errors.New("threshold must be between 2 and the number of shares")
+	errShareCount   = errors.New("number of shares must be between 2 and 255")
+	errEmptySecret  = errors.New("secret must not be empty")
+	errTooFewShares = errors.New("at least two shares are required")
+	errShareLength  = errors.New("shares must all be the same length")
+	errShareIndex   = errors.New("shares must have distinct, non-zero indices")
+)
+
+// Log and exp tables for GF(2^8), with the generator 0x03 and the AES
+// reduction polynomial x^8 + x^4 + x^3 + x + 1.
+var logTable, expTable [256]byte
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		logTable[x] = byte(i)
+
+		// Multiply x by the generator, 0x03 = x + 1
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	expTable[255] = expTable[0]
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+func div(a, b byte) byte {
+	if b == 0 {
+		panic("shamir: division by zero")
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])-int(logTable[b])+255)%255]
+}
+
+// Split splits secret into n shares, any k of which can recover it. Each
+// share is one byte longer than the secret; the last byte is its index.
+func Split(secret []byte, n, k int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errEmptySecret
+	}
+	if n < 2 || n > MaxShares {
+		return nil, errShareCount
+	}
+	if k < 2 || k > n {
+		return nil, errThreshold
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+
+	coeffs := make([]byte, k)
+	for b, s := range secret {
+		coeffs[0] = s
+		_, err := rand.Read(coeffs[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, share := range shares {
+			x := share[len(secret)]
+
+			// Evaluate the polynomial at x with Horner's method
+			var y byte
+			for c := k - 1; c >= 0; c-- {
+				y = mul(y, x) ^ coeffs[c]
+			}
+			share[b] = y
+		}
+	}
+
+	return shares, nil
+}
+
+// Combine recovers the secret from the given shares. It can't tell whether
+// enough shares were given; with fewer than the threshold used to split the
+// secret, the result is garbage.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errTooFewShares
+	}
+
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errShareLength
+	}
+
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errShareLength
+		}
+
+		x := share[size-1]
+		if x == 0 || seen[x] {
+			return nil, errShareIndex
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	for b := range secret {
+		// Lagrange interpolation at x = 0. Addition and subtraction are
+		// both xor in GF(2^8).
+		var s byte
+		for i, share := range shares {
+			num, den := byte(1), byte(1)
+			for j := range shares {
+				if i == j {
+					continue
+				}
+				num = mul(num, xs[j])
+				den = mul(den, xs[i]^xs[j])
+			}
+			s ^= mul(share[b], div(num, den))
+		}
+		secret[b] = s
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,86 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("got %d shares, want 5", len(shares))
+	}
+
+	t.Run("threshold shares", func(t *testing.T) {
+		got, err := Combine([][]byte{shares[4], shares[0], shares[2]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, secret) {
+			t.Errorf("got %q, want %q", got, secret)
+		}
+	})
+
+	t.Run("all shares", func(t *testing.T) {
+		got, err := Combine(shares)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, secret) {
+			t.Errorf("got %q, want %q", got, secret)
+		}
+	})
+
+	t.Run("too few shares", func(t *testing.T) {
+		got, err := Combine(shares[:2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(got, secret) {
+			t.Error("recovered the secret from fewer shares than the threshold")
+		}
+	})
+
+	t.Run("duplicate shares", func(t *testing.T) {
+		_, err := Combine([][]byte{shares[0], shares[0], shares[1]})
+		if err != errShareIndex {
+			t.Errorf("got error %v, want %v", err, errShareIndex)
+		}
+	})
+
+	t.Run("mismatched lengths", func(t *testing.T) {
+		_, err := Combine([][]byte{shares[0], shares[1][1:]})
+		if err != errShareLength {
+			t.Errorf("got error %v, want %v", err, errShareLength)
+		}
+	})
+}
+
+func TestSplitInvalid(t *testing.T) {
+	tcs := []struct {
+		name   string
+		secret []byte
+		n, k   int
+		err    error
+	}{
+		{"empty secret", nil, 3, 2, errEmptySecret},
+		{"one share", []byte("s"), 1, 1, errShareCount},
+		{"too many shares", []byte("s"), 256, 2, errShareCount},
+		{"threshold of one", []byte("s"), 3, 1, errThreshold},
+		{"threshold above shares", []byte("s"), 3, 4, errThreshold},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Split(tc.secret, tc.n, tc.k)
+			if err != tc.err {
+				t.Errorf("got error %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -3,8 +3,14 @@ package daemon
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/nightlyone/lockfile"
 
@@ -24,7 +30,7 @@ import (
 // Daemon is the torus coprocess that contains session secrets, handles
 // cryptographic operations, and communication with the registry.
 type Daemon struct {
-	proxy       *socket.AuthProxy
+	proxy       server
 	lock        lockfile.Lockfile // actually a string
 	session     session.Session
 	config      *config.Config
@@ -33,17 +39,19 @@ type Daemon struct {
 	hasShutdown bool
 }
 
+// server is the interface shared by the AuthProxy used for a single session,
+// and the UserProxy used by a system daemon.
+type server interface {
+	Listen() error
+	Close() error
+	Addr() string
+}
+
 // New creates a new Daemon.
 func New(cfg *config.Config, groupShared bool) (*Daemon, error) {
-	lock, err := lockfile.New(cfg.PidPath)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to create lockfile object: %s", err)
-	}
-
-	err = lock.TryLock()
+	lock, err := lockDaemon(cfg, groupShared)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"Failed to create lockfile[%s]: %s", cfg.PidPath, err)
+		return nil, err
 	}
 
 	// Recover from the panic and return the error; this way we can
@@ -54,10 +62,9 @@ func New(cfg *config.Config, groupShared bool) (*Daemon, error) {
 		}
 	}()
 
-	if groupShared {
-		if err = os.Chmod(string(lock), 0640); err != nil {
-			return nil, err
-		}
+	transport, err := socket.CreateHTTPTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to configure registry connection: %s", err)
 	}
 
 	db, err := db.NewDB(cfg.DBPath)
@@ -67,9 +74,8 @@ func New(cfg *config.Config, groupShared bool) (*Daemon, error) {
 
 	session := session.NewSession()
 	cryptoEngine := crypto.NewEngine(session)
-	transport := socket.CreateHTTPTransport(cfg)
 	client := registry.NewClient(cfg.RegistryURI.String(), cfg.APIVersion,
-		cfg.Version, session, transport)
+		cfg.Version, session, transport, cfg.RegistryRetry)
 	logic := logic.NewEngine(cfg, session, db, cryptoEngine, client)
 
 	proxy, err := socket.NewAuthProxy(cfg, session, db, transport, client, logic, groupShared)
@@ -90,6 +96,158 @@ func New(cfg *config.Config, groupShared bool) (*Daemon, error) {
 	return daemon, nil
 }
 
+// NewSystem creates a new system Daemon, shared by the users of a host. It
+// keeps a separate session for each user connecting to it, identified by
+// their uid. If tokensDir contains a <username>.environment file for the
+// user, holding TORUS_TOKEN_ID and TORUS_TOKEN_SECRET, their session is
+// logged in as that machine.
+//
+// The daemon listens on the socket passed to it through systemd socket
+// activation, or creates a group accessible socket if there is none.
+func NewSystem(cfg *config.Config, tokensDir string) (*Daemon, error) {
+	lock, err := lockDaemon(cfg, true)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err, _ = r.(error)
+		}
+	}()
+
+	l, err := socket.ActivationListener()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to use activation socket: %s", err)
+	}
+	if l == nil {
+		l, err = socket.MakeSocket(cfg.SocketPath, true)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create socket: %s", err)
+		}
+	}
+
+	transport, err := socket.CreateHTTPTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to configure registry connection: %s", err)
+	}
+
+	db, err := db.NewDB(cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	factory := func(uid uint32, l net.Listener) (*socket.AuthProxy, error) {
+		session := session.NewSession()
+		cryptoEngine := crypto.NewEngine(session)
+		client := registry.NewClient(cfg.RegistryURI.String(), cfg.APIVersion,
+			cfg.Version, session, transport, cfg.RegistryRetry)
+		logic := logic.NewEngine(cfg, session, db, cryptoEngine, client)
+
+		err := loginFromTokenFile(logic, tokensDir, uid)
+		if err != nil {
+			return nil, err
+		}
+
+		return socket.NewAuthProxyForListener(cfg, l, session, db, transport,
+			client, logic), nil
+	}
+
+	daemon := &Daemon{
+		proxy:       socket.NewUserProxy(l, factory),
+		lock:        lock,
+		config:      cfg,
+		db:          db,
+		hasShutdown: false,
+	}
+
+	return daemon, nil
+}
+
+func lockDaemon(cfg *config.Config, groupShared bool) (lockfile.Lockfile, error) {
+	lock, err := lockfile.New(cfg.PidPath)
+	if err != nil {
+		return lock, fmt.Errorf("Failed to create lockfile object: %s", err)
+	}
+
+	err = lock.TryLock()
+	if err != nil {
+		return lock, fmt.Errorf(
+			"Failed to create lockfile[%s]: %s", cfg.PidPath, err)
+	}
+
+	if groupShared {
+		if err = os.Chmod(string(lock), 0640); err != nil {
+			return lock, err
+		}
+	}
+
+	return lock, nil
+}
+
+// loginFromTokenFile logs the session for the given uid in as the machine
+// whose token is in the user's file within tokensDir, if there is one.
+func loginFromTokenFile(engine *logic.Engine, tokensDir string, uid uint32) error {
+	if tokensDir == "" {
+		return nil
+	}
+
+	u, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return err
+	}
+
+	tokenPath := filepath.Join(tokensDir, u.Username+".environment")
+	env, err := readEnvironmentFile(tokenPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ID, err := identity.DecodeFromString(env["TORUS_TOKEN_ID"])
+	if err != nil {
+		return fmt.Errorf("Could not parse TORUS_TOKEN_ID in %s", tokenPath)
+	}
+
+	secret, err := base64.NewValueFromString(env["TORUS_TOKEN_SECRET"])
+	if err != nil {
+		return fmt.Errorf("Could not parse TORUS_TOKEN_SECRET in %s", tokenPath)
+	}
+
+	log.Printf("Attempting to login uid %d as machine token id: %s", uid, ID)
+	return engine.Session.Login(context.Background(), nil, &apitypes.MachineLogin{
+		TokenID: &ID,
+		Secret:  secret,
+	})
+}
+
+// readEnvironmentFile reads a file of KEY=VALUE lines, in the format used
+// by systemd's EnvironmentFile.
+func readEnvironmentFile(path string) (map[string]string, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		env[strings.TrimSpace(parts[0])] = strings.Trim(strings.TrimSpace(parts[1]), "\"'")
+	}
+
+	return env, nil
+}
+
 // Addr returns the domain socket the Daemon is listening on.
 func (d *Daemon) Addr() string {
 	return d.proxy.Addr()
@@ -98,6 +256,11 @@ func (d *Daemon) Addr() string {
 // Run starts the daemon main loop. It returns on failure, or when the daemon
 // has been gracefully shut down.
 func (d *Daemon) Run() error {
+	// A system daemon logs users in as they connect.
+	if d.logic == nil {
+		return d.proxy.Listen()
+	}
+
 	email, hasEmail := os.LookupEnv("TORUS_EMAIL")
 	password, hasPassword := os.LookupEnv("TORUS_PASSWORD")
 	tokenID, hasTokenID := os.LookupEnv("TORUS_TOKEN_ID")
@@ -110,7 +273,7 @@ func (d *Daemon) Run() error {
 			Password: password,
 		}
 
-		err := d.logic.Session.Login(context.Background(), userLogin)
+		err := d.logic.Session.Login(context.Background(), nil, userLogin)
 		if err != nil {
 			return err
 		}
@@ -136,7 +299,7 @@ func (d *Daemon) Run() error {
 			Secret:  secret,
 		}
 
-		err = d.logic.Session.Login(context.Background(), machineLogin)
+		err = d.logic.Session.Login(context.Background(), nil, machineLogin)
 		if err != nil {
 			return err
 		}
@@ -141,3 +141,49 @@ func (db *DB) Get(id *identity.ID, env envelope.Envelope) error {
 		return json.Unmarshal(b, env)
 	})
 }
+
+// Put stores the serialized value of v into the db, under key in the named
+// bucket. It is used for values that are not envelopes, such as work the
+// daemon has been asked to do later.
+func (db *DB) Put(bucket, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return db.db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte(key), b)
+	})
+}
+
+// Delete removes key from the named bucket.
+func (db *DB) Delete(bucket, key string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+
+		return bkt.Delete([]byte(key))
+	})
+}
+
+// ForEach calls fn with the key and serialized value of each entry in the
+// named bucket, stopping at the first error returned.
+func (db *DB) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	return db.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+
+		return bkt.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
@@ -0,0 +1,327 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+	"github.com/manifoldco/torus-cli/registry"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto"
+)
+
+// VerifyClaimTree verifies the signatures and claims of every public key in
+// the given org's claim tree, and summarizes the result for each user and
+// machine owning keys in it.
+func (e *Engine) VerifyClaimTree(ctx context.Context,
+	orgID *identity.ID) ([]apitypes.KeyOwnerVerification, error) {
+
+	claimTrees, err := e.client.ClaimTree.List(ctx, orgID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(claimTrees) != 1 {
+		return nil, &apitypes.Error{
+			Type: apitypes.NotFoundError,
+			Err: []string{
+				fmt.Sprintf("Claim tree not found for org: %s", orgID),
+			},
+		}
+	}
+
+	var systemKey ed25519.PublicKey
+	if e.config.PublicKey != nil {
+		systemKey = ed25519.PublicKey(e.config.PublicKey.PublicKey)
+	}
+
+	owners, err := verifyClaimTree(ctx, e.crypto, &claimTrees[0], systemKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.nameKeyOwners(ctx, orgID, owners)
+	if err != nil {
+		return nil, err
+	}
+
+	return owners, nil
+}
+
+// nameKeyOwners fills in the name and type of each key owner. Keys are owned
+// by users, or by machine tokens.
+func (e *Engine) nameKeyOwners(ctx context.Context, orgID *identity.ID,
+	owners []apitypes.KeyOwnerVerification) error {
+
+	ids := make([]identity.ID, len(owners))
+	for i, o := range owners {
+		ids[i] = *o.OwnerID
+	}
+
+	users := make(map[identity.ID]string)
+	if len(ids) > 0 {
+		profiles, err := e.client.Profiles.ListByID(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, p := range *profiles {
+			users[*p.ID] = p.Body.Username
+		}
+	}
+
+	tokens := make(map[identity.ID]string)
+	machines, err := e.client.Machines.List(ctx, orgID, nil, nil, nil)
+	switch {
+	case apitypes.IsUnauthorizedError(err):
+		// Only admins can list machines; they're reported by id instead.
+	case err != nil:
+		return err
+	}
+	for _, m := range machines {
+		for _, t := range m.Tokens {
+			tokens[*t.Token.ID] = m.Machine.Body.Name
+		}
+	}
+
+	for i := range owners {
+		o := &owners[i]
+		if name, ok := users[*o.OwnerID]; ok {
+			o.Name = name
+			o.Type = "user"
+		} else if name, ok := tokens[*o.OwnerID]; ok {
+			o.Name = name
+			o.Type = "machine"
+		} else {
+			o.Name = o.OwnerID.String()
+		}
+	}
+
+	return nil
+}
+
+// verifyClaimTree verifies every public key in the claim tree, and groups the
+// results by key owner, in the order they appear in the tree.
+//
+// Claims signed by a key that is not in the tree are checked against the
+// system's signing key, if one is given.
+func verifyClaimTree(ctx context.Context, c *crypto.Engine, tree *registry.ClaimTree,
+	systemKey ed25519.PublicKey) ([]apitypes.KeyOwnerVerification, error) {
+
+	keys := make(map[identity.ID]*envelope.PublicKey, len(tree.PublicKeys))
+	for _, segment := range tree.PublicKeys {
+		keys[*segment.PublicKey.ID] = segment.PublicKey
+	}
+
+	var owners []apitypes.KeyOwnerVerification
+	index := make(map[identity.ID]int)
+	for i := range tree.PublicKeys {
+		segment := &tree.PublicKeys[i]
+
+		problems, err := verifyPublicKeySegment(ctx, c, segment, keys, systemKey)
+		if err != nil {
+			return nil, err
+		}
+
+		pk := segment.PublicKey
+		ownerID := *pk.Body.OwnerID
+		if _, ok := index[ownerID]; !ok {
+			index[ownerID] = len(owners)
+			owners = append(owners, apitypes.KeyOwnerVerification{OwnerID: &ownerID})
+		}
+
+		owner := &owners[index[ownerID]]
+		owner.Keys = append(owner.Keys, apitypes.PublicKeyVerification{
+			PublicKeyID: pk.ID,
+			KeyType:     pk.Body.KeyType,
+			Revoked:     segment.Revoked(),
+			Problems:    problems,
+		})
+	}
+
+	for i := range owners {
+		owners[i].Trust = keyOwnerTrust(&owners[i])
+	}
+
+	return owners, nil
+}
+
+// keyOwnerTrust returns the trust level for an owner's verified keys.
+func keyOwnerTrust(owner *apitypes.KeyOwnerVerification) string {
+	if len(owner.Problems()) > 0 {
+		return apitypes.UntrustedKeys
+	}
+
+	active := make(map[primitive.KeyType]bool)
+	for _, k := range owner.Keys {
+		if !k.Revoked {
+			active[k.KeyType] = true
+		}
+	}
+
+	switch {
+	case active[primitive.SigningKeyType] && active[primitive.EncryptionKeyType]:
+		return apitypes.TrustedKeys
+	case len(active) > 0:
+		return apitypes.IncompleteKeys
+	default:
+		return apitypes.RevokedKeys
+	}
+}
+
+// verifyPublicKeySegment checks the public key's signature, and that of each
+// of its claims, and that the claims form a single chain starting at the key.
+// It returns a description of each problem found.
+func verifyPublicKeySegment(ctx context.Context, c *crypto.Engine,
+	segment *apitypes.PublicKeySegment, keys map[identity.ID]*envelope.PublicKey,
+	systemKey ed25519.PublicKey) ([]string, error) {
+
+	var problems []string
+	pk := segment.PublicKey
+
+	id, err := identity.NewImmutable(pk.Body, &pk.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if id != *pk.ID {
+		problems = append(problems, "public key id does not match its contents")
+	}
+
+	// Signing keys sign themselves. Encryption keys are signed by one of
+	// their owner's signing keys.
+	signerID := pk.Signature.PublicKeyID
+	if signerID == nil && pk.Body.KeyType == primitive.SigningKeyType {
+		signerID = pk.ID
+	}
+
+	signer, problem := findSigningKey(keys, signerID)
+	switch {
+	case problem != "":
+		problems = append(problems, "public key "+problem)
+	case *signer.Body.OwnerID != *pk.Body.OwnerID:
+		problems = append(problems, "public key is signed by a key of another owner")
+	default:
+		ok, err := c.VerifySigned(ctx, pk.Body, &pk.Signature,
+			ed25519.PublicKey(*signer.Body.Key.Value))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			problems = append(problems, "public key signature is invalid")
+		}
+	}
+
+	if len(segment.Claims) == 0 {
+		return append(problems, "public key has no claims"), nil
+	}
+
+	claimIDs := make(map[identity.ID]bool, len(segment.Claims))
+	for _, claim := range segment.Claims {
+		claimIDs[*claim.ID] = true
+	}
+
+	next := make(map[identity.ID]int)
+	for _, claim := range segment.Claims {
+		claimProblems, err := verifyClaim(ctx, c, &claim, pk, keys, systemKey)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, claimProblems...)
+
+		prev := claim.Body.Previous
+		switch {
+		case prev == nil:
+			problems = append(problems, fmt.Sprintf("claim %s has no previous claim", claim.ID))
+			continue
+		case *prev != *pk.ID && !claimIDs[*prev]:
+			problems = append(problems,
+				fmt.Sprintf("claim %s follows missing claim %s", claim.ID, prev))
+		}
+
+		next[*prev]++
+		if next[*prev] == 2 {
+			problems = append(problems, fmt.Sprintf("claim chain forks after %s", prev))
+		}
+	}
+
+	if next[*pk.ID] == 0 {
+		problems = append(problems, "no claim follows the public key")
+	}
+
+	for _, claim := range segment.Claims {
+		if claim.Body.ClaimType == primitive.RevocationClaimType && next[*claim.ID] > 0 {
+			problems = append(problems,
+				fmt.Sprintf("claims follow revocation claim %s", claim.ID))
+		}
+	}
+
+	return problems, nil
+}
+
+// verifyClaim checks a claim's id and signature, and that it is made against
+// the given public key.
+func verifyClaim(ctx context.Context, c *crypto.Engine, claim *envelope.Claim,
+	pk *envelope.PublicKey, keys map[identity.ID]*envelope.PublicKey,
+	systemKey ed25519.PublicKey) ([]string, error) {
+
+	var problems []string
+
+	id, err := identity.NewImmutable(claim.Body, &claim.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if id != *claim.ID {
+		problems = append(problems, fmt.Sprintf("claim %s id does not match its contents", claim.ID))
+	}
+
+	if claim.Body.PublicKeyID == nil || *claim.Body.PublicKeyID != *pk.ID {
+		problems = append(problems, fmt.Sprintf("claim %s is made against another key", claim.ID))
+	}
+
+	var public ed25519.PublicKey
+	signer, problem := findSigningKey(keys, claim.Signature.PublicKeyID)
+	switch {
+	case signer != nil:
+		public = ed25519.PublicKey(*signer.Body.Key.Value)
+	case systemKey != nil && claim.Signature.PublicKeyID != nil &&
+		keys[*claim.Signature.PublicKeyID] == nil:
+		public = systemKey
+	default:
+		return append(problems, fmt.Sprintf("claim %s %s", claim.ID, problem)), nil
+	}
+
+	ok, err := c.VerifySigned(ctx, claim.Body, &claim.Signature, public)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		problems = append(problems, fmt.Sprintf("claim %s signature is invalid", claim.ID))
+	}
+
+	return problems, nil
+}
+
+// findSigningKey returns the signing key with the given id from keys. If it
+// can't be used, it returns a description of the problem instead.
+func findSigningKey(keys map[identity.ID]*envelope.PublicKey,
+	id *identity.ID) (*envelope.PublicKey, string) {
+
+	if id == nil {
+		return nil, "is not signed by any key"
+	}
+
+	key, ok := keys[*id]
+	if !ok {
+		return nil, fmt.Sprintf("is signed by unknown key %s", id)
+	}
+
+	if key.Body.KeyType != primitive.SigningKeyType {
+		return nil, fmt.Sprintf("is signed by non-signing key %s", id)
+	}
+
+	return key, ""
+}
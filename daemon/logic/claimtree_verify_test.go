@@ -0,0 +1,213 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+	"github.com/manifoldco/torus-cli/registry"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto"
+)
+
+type testSigner struct {
+	id   *identity.ID
+	priv ed25519.PrivateKey
+}
+
+func testSign(t *testing.T, body identity.Immutable, s testSigner) (*identity.ID, primitive.Signature) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := primitive.Signature{
+		Algorithm:   crypto.EdDSA,
+		PublicKeyID: s.id,
+		Value: base64.NewValue(ed25519.Sign(s.priv,
+			append([]byte(strconv.Itoa(body.Version())), b...))),
+	}
+
+	id, err := identity.NewImmutable(body, &sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &id, sig
+}
+
+func TestVerifyClaimTree(t *testing.T) {
+	ctx := context.Background()
+	c := &crypto.Engine{}
+
+	orgID, err := identity.NewMutable(&primitive.Org{Name: "org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownerID, err := identity.NewMutable(&primitive.User{Username: "jo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	systemPub, systemPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	systemID, err := identity.NewMutable(&primitive.Team{Name: "system"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	system := testSigner{id: &systemID, priv: systemPriv}
+
+	// newKeys returns a valid, claimed signing and encryption key for owner.
+	newKeys := func() (apitypes.PublicKeySegment, apitypes.PublicKeySegment, testSigner) {
+		sigPub, sigPriv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sigBody := &primitive.PublicKey{
+			Algorithm: crypto.EdDSA,
+			Created:   time.Now().UTC(),
+			Key:       primitive.PublicKeyValue{Value: base64.NewValue(sigPub)},
+			OrgID:     &orgID,
+			OwnerID:   &ownerID,
+			KeyType:   primitive.SigningKeyType,
+		}
+		sigID, sigSig := testSign(t, sigBody, testSigner{priv: sigPriv})
+		signer := testSigner{id: sigID, priv: sigPriv}
+
+		encBody := &primitive.PublicKey{
+			Algorithm: crypto.Curve25519,
+			Created:   time.Now().UTC(),
+			Key:       primitive.PublicKeyValue{Value: base64.NewValue(make([]byte, 32))},
+			OrgID:     &orgID,
+			OwnerID:   &ownerID,
+			KeyType:   primitive.EncryptionKeyType,
+		}
+		encID, encSig := testSign(t, encBody, signer)
+
+		segment := func(id *identity.ID, sig primitive.Signature, body *primitive.PublicKey) apitypes.PublicKeySegment {
+			claimBody := primitive.NewClaim(&orgID, &ownerID, id, id, primitive.SignatureClaimType)
+			claimID, claimSig := testSign(t, claimBody, signer)
+			return apitypes.PublicKeySegment{
+				PublicKey: &envelope.PublicKey{ID: id, Version: 1, Signature: sig, Body: body},
+				Claims: []envelope.Claim{
+					{ID: claimID, Version: 1, Signature: claimSig, Body: claimBody},
+				},
+			}
+		}
+
+		return segment(sigID, sigSig, sigBody), segment(encID, encSig, encBody), signer
+	}
+
+	addClaim := func(seg *apitypes.PublicKeySegment, s testSigner, previous *identity.ID,
+		claimType primitive.ClaimType) {
+
+		body := primitive.NewClaim(&orgID, &ownerID, previous, seg.PublicKey.ID, claimType)
+		id, sig := testSign(t, body, s)
+		seg.Claims = append(seg.Claims, envelope.Claim{ID: id, Version: 1, Signature: sig, Body: body})
+	}
+
+	verify := func(t *testing.T, segments ...apitypes.PublicKeySegment) apitypes.KeyOwnerVerification {
+		tree := &registry.ClaimTree{PublicKeys: segments}
+		owners, err := verifyClaimTree(ctx, c, tree, systemPub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(owners) != 1 {
+			t.Fatalf("got %d owners, want 1", len(owners))
+		}
+		return owners[0]
+	}
+
+	expect := func(t *testing.T, owner apitypes.KeyOwnerVerification, trust string, problem string) {
+		if owner.Trust != trust {
+			t.Errorf("trust = %s, want %s (problems: %v)", owner.Trust, trust, owner.Problems())
+		}
+
+		problems := owner.Problems()
+		if problem == "" {
+			if len(problems) > 0 {
+				t.Errorf("unexpected problems: %v", problems)
+			}
+			return
+		}
+		for _, p := range problems {
+			if strings.Contains(p, problem) {
+				return
+			}
+		}
+		t.Errorf("expected a problem containing %q, got %v", problem, problems)
+	}
+
+	t.Run("valid keys", func(t *testing.T) {
+		sig, enc, _ := newKeys()
+		expect(t, verify(t, sig, enc), apitypes.TrustedKeys, "")
+	})
+
+	t.Run("system signed claim", func(t *testing.T) {
+		sig, enc, _ := newKeys()
+		addClaim(&sig, system, sig.Claims[0].ID, primitive.SignatureClaimType)
+		expect(t, verify(t, sig, enc), apitypes.TrustedKeys, "")
+	})
+
+	t.Run("revoked keys", func(t *testing.T) {
+		sig, enc, signer := newKeys()
+		addClaim(&enc, signer, enc.Claims[0].ID, primitive.RevocationClaimType)
+		expect(t, verify(t, sig, enc), apitypes.IncompleteKeys, "")
+
+		addClaim(&sig, signer, sig.Claims[0].ID, primitive.RevocationClaimType)
+		expect(t, verify(t, sig, enc), apitypes.RevokedKeys, "")
+	})
+
+	t.Run("tampered key", func(t *testing.T) {
+		sig, enc, _ := newKeys()
+		enc.PublicKey.Body.Key.Value = base64.NewValue([]byte(strings.Repeat("x", 32)))
+		expect(t, verify(t, sig, enc), apitypes.UntrustedKeys, "signature is invalid")
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		sig, enc, _ := newKeys()
+		other, _, otherSigner := newKeys()
+		enc.Claims = nil
+		addClaim(&enc, otherSigner, enc.PublicKey.ID, primitive.SignatureClaimType)
+		enc.Claims[0].Signature.PublicKeyID = sig.PublicKey.ID
+		expect(t, verify(t, sig, enc, other), apitypes.UntrustedKeys, "signature is invalid")
+	})
+
+	t.Run("missing claims", func(t *testing.T) {
+		sig, enc, _ := newKeys()
+		enc.Claims = nil
+		expect(t, verify(t, sig, enc), apitypes.UntrustedKeys, "has no claims")
+	})
+
+	t.Run("broken chain", func(t *testing.T) {
+		sig, enc, signer := newKeys()
+		addClaim(&enc, signer, sig.PublicKey.ID, primitive.SignatureClaimType)
+		expect(t, verify(t, sig, enc), apitypes.UntrustedKeys, "follows missing claim")
+	})
+
+	t.Run("forked chain", func(t *testing.T) {
+		sig, enc, signer := newKeys()
+		addClaim(&enc, signer, enc.PublicKey.ID, primitive.SignatureClaimType)
+		expect(t, verify(t, sig, enc), apitypes.UntrustedKeys, "forks")
+	})
+
+	t.Run("claims after revocation", func(t *testing.T) {
+		sig, enc, signer := newKeys()
+		addClaim(&enc, signer, enc.Claims[0].ID, primitive.RevocationClaimType)
+		addClaim(&enc, signer, enc.Claims[1].ID, primitive.SignatureClaimType)
+		expect(t, verify(t, sig, enc), apitypes.UntrustedKeys, "follow revocation")
+	})
+}
@@ -3,6 +3,7 @@ package logic
 import (
 	"errors"
 	"sort"
+	"time"
 
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
@@ -186,6 +187,71 @@ func (cgs *credentialGraphSet) NeedRotation() ([]envelope.CredentialInf, error)
 	return needRotation, nil
 }
 
+// ActiveCredentials returns all reachable Credentials, across all versions of
+// the CredentialGraphs.
+func (cgs *credentialGraphSet) ActiveCredentials() ([]envelope.CredentialInf, error) {
+	var active []envelope.CredentialInf
+
+	for _, graphs := range cgs.graphs {
+		var parents []identity.ID
+
+		sort.Sort(graphSorter(graphs))
+		for _, graph := range graphs {
+			var activeCreds []envelope.CredentialInf
+			var err error
+			activeCreds, parents, err = cgs.activeCreds(parents, graph)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, cred := range activeCreds {
+				active = append(active, cred)
+			}
+		}
+	}
+
+	return active, nil
+}
+
+// Expiring returns a slice of the active Credentials whose metadata declares
+// they expire at or before the given time.
+func (cgs *credentialGraphSet) Expiring(t time.Time) ([]envelope.CredentialInf, error) {
+	active, err := cgs.ActiveCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	var expiring []envelope.CredentialInf
+	for _, cred := range active {
+		md := cred.Metadata()
+		if md != nil && md.Expires != nil && !md.Expires.After(t) {
+			expiring = append(expiring, cred)
+		}
+	}
+
+	return expiring, nil
+}
+
+// CreatedBefore returns a slice of the active Credentials created at or before
+// the given time. Credential schema versions that do not record when they were
+// created are left out, as their age is unknown.
+func (cgs *credentialGraphSet) CreatedBefore(t time.Time) ([]envelope.CredentialInf, error) {
+	active, err := cgs.ActiveCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	var old []envelope.CredentialInf
+	for _, cred := range active {
+		created := cred.Created()
+		if created != nil && !created.After(t) {
+			old = append(old, cred)
+		}
+	}
+
+	return old, nil
+}
+
 // Head returns the most recent version of a CredentialGraph that would contain
 // the given PathExp.
 func (cgs *credentialGraphSet) Head(pe *pathexp.PathExp) (registry.CredentialGraph, error) {
@@ -2,6 +2,7 @@ package logic
 
 import (
 	"testing"
+	"time"
 
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
@@ -24,6 +25,9 @@ type cred struct {
 	state *string
 	pe    *string
 	name  *string
+
+	expires *time.Time
+	created *time.Time
 }
 
 func mustID(raw string) *identity.ID {
@@ -72,10 +76,24 @@ func buildGraph(rawPathExp string, version int, secrets ...cred) registry.Creden
 			base.Name = *secret.name
 		}
 
-		cred := envelope.Credential{
+		if secret.expires != nil || secret.created != nil {
+			cg.Credentials = append(cg.Credentials, &envelope.Credential{
+				ID:      secret.id,
+				Version: 3,
+				Body: &primitive.Credential{
+					BaseCredential: base,
+					State:          secret.state,
+					Metadata:       &primitive.CredentialMetadata{Expires: secret.expires},
+					Created:        secret.created,
+				},
+			})
+			continue
+		}
+
+		cred := envelope.CredentialV2{
 			ID:      secret.id,
 			Version: 2,
-			Body: &primitive.Credential{
+			Body: &primitive.CredentialV2{
 				BaseCredential: base,
 				State:          secret.state,
 			},
@@ -672,3 +690,107 @@ func TestCredentialGraphSetNeedRotation(t *testing.T) {
 		}
 	})
 }
+
+func TestCredentialGraphSetExpiring(t *testing.T) {
+	now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	t.Run("expired and unexpired values", func(t *testing.T) {
+		cgs := newCredentialGraphSet()
+
+		pe := "/o/p/e/s/u/i"
+		name := "cred"
+		othername := "othercred"
+
+		cgs.Add(buildGraph("/o/p/e/s/u/*", 1,
+			cred{id: id1, pe: &pe, name: &name, expires: &past},
+			cred{id: id2, pe: &pe, name: &othername, expires: &future},
+			cred{id: id3, pe: &pe, name: &othername}))
+
+		out, err := cgs.Expiring(now)
+		if err != nil {
+			t.Fatal("error seen:", err)
+		}
+
+		if len(out) != 1 {
+			t.Fatal("Wrong number of expiring credentials found")
+		}
+
+		if out[0].GetID() != id1 {
+			t.Error("Wrong expiring credential returned")
+		}
+	})
+
+	t.Run("replaced value is not returned", func(t *testing.T) {
+		cgs := newCredentialGraphSet()
+
+		pe := "/o/p/e/s/u/i"
+		name := "cred"
+
+		cgs.Add(buildGraph("/o/p/e/s/u/*", 1,
+			cred{id: id1, pe: &pe, name: &name, expires: &past},
+			cred{id: id2, prev: id1, pe: &pe, name: &name}))
+
+		out, err := cgs.Expiring(now)
+		if err != nil {
+			t.Fatal("error seen:", err)
+		}
+
+		if len(out) != 0 {
+			t.Error("Wrong number of expiring credentials found")
+		}
+	})
+}
+
+func TestCredentialGraphSetCreatedBefore(t *testing.T) {
+	now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	t.Run("uses credential creation time", func(t *testing.T) {
+		cgs := newCredentialGraphSet()
+
+		pe := "/o/p/e/s/u/i"
+		name := "cred"
+		othername := "othercred"
+
+		cgs.Add(buildGraph("/o/p/e/s/u/*", 1,
+			cred{id: id1, pe: &pe, name: &name, created: &past},
+			cred{id: id2, pe: &pe, name: &othername, created: &future}))
+
+		out, err := cgs.CreatedBefore(now)
+		if err != nil {
+			t.Fatal("error seen:", err)
+		}
+
+		if len(out) != 1 {
+			t.Fatal("Wrong number of old credentials found")
+		}
+
+		if out[0].GetID() != id1 {
+			t.Error("Wrong old credential returned")
+		}
+	})
+
+	t.Run("skips credentials without a creation time", func(t *testing.T) {
+		cgs := newCredentialGraphSet()
+
+		pe := "/o/p/e/s/u/i"
+		name := "cred"
+
+		// The keyring is old, but says nothing about when the secret was set.
+		cg := buildGraph("/o/p/e/s/u/*", 1, cred{id: id1, pe: &pe, name: &name})
+		cg.(*registry.CredentialGraphV2).Keyring.Body.Created = past
+		cgs.Add(cg)
+
+		out, err := cgs.CreatedBefore(now)
+		if err != nil {
+			t.Fatal("error seen:", err)
+		}
+
+		if len(out) != 0 {
+			t.Error("Credential without a creation time found")
+		}
+	})
+}
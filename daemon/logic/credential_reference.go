@@ -0,0 +1,308 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/registry"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto"
+)
+
+// resolvedReference is the result of following a reference to the credential
+// holding its value.
+type resolvedReference struct {
+	value      string
+	references []string
+}
+
+// referenceResolver follows reference credential values to the credentials
+// they refer to. Resolved references are cached for the life of the resolver.
+//
+// If verifier is set, the graph holding each referenced credential is
+// verified before it is decrypted.
+type referenceResolver struct {
+	engine   *Engine
+	verifier *credentialVerifier
+	resolved map[string]*resolvedReference
+}
+
+func newReferenceResolver(e *Engine) *referenceResolver {
+	return &referenceResolver{
+		engine:   e,
+		resolved: make(map[string]*resolvedReference),
+	}
+}
+
+// ResolveAll replaces the value of every set credential that refers to
+// another credential with the value it resolves to, recording the chain of
+// references followed.
+//
+// A reference that can't be resolved, because its target is missing,
+// inaccessible, invalid, or part of a cycle, is reported against its own
+// credential, which keeps its reference value. Any other error, such as a
+// failed verification, fails the whole call.
+func (r *referenceResolver) ResolveAll(ctx context.Context, creds []PlaintextCredentialEnvelope) error {
+	for _, cred := range creds {
+		body := cred.Body
+		if body.State != nil && *body.State == "unset" {
+			continue
+		}
+
+		cv, err := decodeCredentialValue(body.Value)
+		if err != nil || !cv.IsReference() {
+			continue
+		}
+
+		self := body.PathExp.String() + "/" + body.Name
+		res, err := r.resolve(ctx, cv.Reference(), []string{self})
+		if isUnresolvedReference(err) {
+			body.ReferenceError = strings.Join(err.(*apitypes.Error).Err, " ")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		body.Value = res.value
+		body.References = res.references
+	}
+
+	return nil
+}
+
+// isUnresolvedReference returns whether the error is one that leaves a single
+// reference unresolved, rather than one that should fail the whole request.
+func isUnresolvedReference(err error) bool {
+	apiErr, ok := err.(*apitypes.Error)
+	if !ok {
+		return false
+	}
+
+	switch apiErr.Type {
+	case apitypes.NotFoundError, apitypes.UnauthorizedError, apitypes.BadRequestError:
+		return true
+	default:
+		return false
+	}
+}
+
+// resolve follows the reference to the given credential path, and any
+// references it contains in turn. seen holds the paths already visited while
+// resolving this chain, and is used to detect cycles.
+func (r *referenceResolver) resolve(ctx context.Context, target string,
+	seen []string) (*resolvedReference, error) {
+
+	for _, s := range seen {
+		if s == target {
+			chain := strings.Join(append(seen, target), " -> ")
+			return nil, &apitypes.Error{
+				StatusCode: http.StatusBadRequest,
+				Type:       apitypes.BadRequestError,
+				Err:        []string{"Credential reference cycle detected: " + chain},
+			}
+		}
+	}
+
+	if res, ok := r.resolved[target]; ok {
+		return res, nil
+	}
+
+	value, err := r.engine.retrieveReferencedValue(ctx, target, r.verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &resolvedReference{value: value, references: []string{target}}
+
+	cv, err := decodeCredentialValue(value)
+	if err == nil && cv.IsReference() {
+		next, err := r.resolve(ctx, cv.Reference(), append(seen, target))
+		if err != nil {
+			return nil, err
+		}
+
+		res.value = next.value
+		res.references = append(res.references, next.references...)
+	}
+
+	r.resolved[target] = res
+	return res, nil
+}
+
+// retrieveReferencedValue decrypts and returns the current value of the
+// credential at the given path; a path expression followed by the credential
+// name. Access is limited to credentials whose keyring the current session is
+// a member of. If verifier is given, the credential's graph must pass
+// verification.
+func (e *Engine) retrieveReferencedValue(ctx context.Context, target string,
+	verifier *credentialVerifier) (string, error) {
+	pe, name, err := parseReference(target)
+	if err != nil {
+		return "", err
+	}
+
+	// Like `torus view`, the path is looked up rather than the exact path
+	// expression, so credentials set for wider paths apply too.
+	graphs, err := e.client.CredentialGraph.List(ctx, pe.String(), nil, e.session.AuthID())
+	if err != nil {
+		log.Printf("error retrieving referenced credential graphs: %s", err)
+		return "", err
+	}
+
+	cgs := newCredentialGraphSet()
+	err = cgs.Add(graphs...)
+	if err != nil {
+		return "", err
+	}
+
+	activeGraphs, err := cgs.Prune()
+	if err != nil {
+		return "", err
+	}
+
+	graph, cred := mostSpecificCredential(activeGraphs, pe, name)
+	if cred == nil {
+		return "", &apitypes.Error{
+			StatusCode: http.StatusNotFound,
+			Type:       apitypes.NotFoundError,
+			Err:        []string{"Referenced credential not found: " + target},
+		}
+	}
+
+	if verifier != nil {
+		err = verifier.verifyGraph(ctx, graph)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	orgID := graph.GetKeyring().OrgID()
+	_, _, kp, err := fetchKeyPairs(ctx, e.client, orgID)
+	if err != nil {
+		log.Printf("Error fetching keypairs: %s", err)
+		return "", err
+	}
+
+	krm, mekshare, err := graph.FindMember(e.session.AuthID())
+	if err == registry.ErrMemberNotFound {
+		return "", &apitypes.Error{
+			StatusCode: http.StatusUnauthorized,
+			Type:       apitypes.UnauthorizedError,
+			Err:        []string{"Access denied to referenced credential: " + target},
+		}
+	}
+	if err != nil {
+		log.Printf("Error finding keyring membership: %s", err)
+		return "", err
+	}
+
+	encryptingKey, err := findEncryptingKey(ctx, e.client, orgID,
+		krm.EncryptingKeyID)
+	if err != nil {
+		log.Printf("Error finding encrypting key for user: %s", err)
+		return "", err
+	}
+
+	var pt []byte
+	err = e.crypto.WithUnboxer(ctx, *mekshare.Key.Value, *mekshare.Key.Nonce, &kp.Encryption, *encryptingKey.Key.Value, func(u crypto.Unboxer) error {
+		pt, err = u.Unbox(ctx, *cred.Credential().Value, *cred.Nonce(), *cred.Credential().Nonce)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error decrypting referenced credential: %s", err)
+		return "", err
+	}
+
+	return string(pt), nil
+}
+
+// mostSpecificCredential returns the set credential with the given name that
+// applies to the path expression, along with the graph holding it. When more
+// than one applies, the one with the most specific path expression is used,
+// as `torus view` does.
+func mostSpecificCredential(graphs []registry.CredentialGraph, pe *pathexp.PathExp,
+	name string) (registry.CredentialGraph, envelope.CredentialInf) {
+
+	var bestGraph registry.CredentialGraph
+	var best envelope.CredentialInf
+	for _, graph := range graphs {
+		for _, cred := range graph.GetCredentials() {
+			if cred.Name() != name || cred.Unset() || !credentialApplies(cred.PathExp(), pe) {
+				continue
+			}
+
+			if best == nil || cred.PathExp().CompareSpecificity(best.PathExp()) == 1 {
+				bestGraph, best = graph, cred
+			}
+		}
+	}
+
+	return bestGraph, best
+}
+
+// credentialApplies returns whether a credential set for the path expression
+// cpe applies to the path expression of a reference. Each segment of the
+// reference must be contained by the credential's segment, or the two must be
+// the same.
+func credentialApplies(cpe, pe *pathexp.PathExp) bool {
+	if cpe.Equal(pe) {
+		return true
+	}
+
+	parts := strings.Split(strings.TrimPrefix(pe.String(), "/"), "/")
+	if len(parts) != 6 {
+		return false
+	}
+
+	return cpe.Org.Contains(parts[0]) && cpe.Project.Contains(parts[1]) &&
+		cpe.Envs.Contains(parts[2]) && cpe.Services.Contains(parts[3]) &&
+		cpe.Identities.Contains(parts[4]) && cpe.Instances.Contains(parts[5])
+}
+
+// parseReference splits a reference target into its path expression and
+// credential name.
+func parseReference(target string) (*pathexp.PathExp, string, error) {
+	idx := strings.LastIndex(target, "/")
+	if idx == -1 {
+		return nil, "", invalidReferenceError(target)
+	}
+
+	pe, err := pathexp.Parse(target[:idx])
+	if err != nil {
+		return nil, "", invalidReferenceError(target)
+	}
+
+	name := target[idx+1:]
+	if !pathexp.ValidSlug(name) {
+		return nil, "", invalidReferenceError(target)
+	}
+
+	return pe, name, nil
+}
+
+func invalidReferenceError(target string) error {
+	return &apitypes.Error{
+		StatusCode: http.StatusBadRequest,
+		Type:       apitypes.BadRequestError,
+		Err:        []string{"Invalid credential reference: " + target},
+	}
+}
+
+// decodeCredentialValue decodes the plaintext value of a credential.
+func decodeCredentialValue(value string) (*apitypes.CredentialValue, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	cv := &apitypes.CredentialValue{}
+	err = json.Unmarshal(b, cv)
+	return cv, err
+}
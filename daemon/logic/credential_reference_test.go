@@ -0,0 +1,181 @@
+package logic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/registry"
+)
+
+func TestParseReference(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		pe, name, err := parseReference("/org/proj/env/svc/*/1/sentry_dsn")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if pe.String() != "/org/proj/env/svc/*/1" {
+			t.Errorf("wrong pathexp! had: '%s'", pe.String())
+		}
+		if name != "sentry_dsn" {
+			t.Errorf("wrong name! had: '%s'", name)
+		}
+	})
+
+	invalid := []string{
+		"sentry_dsn",
+		"/org/proj/env/svc/sentry_dsn",
+		"/org/proj/env/svc/*/1/*",
+	}
+	for _, target := range invalid {
+		t.Run(target, func(t *testing.T) {
+			_, _, err := parseReference(target)
+			if err == nil {
+				t.Error("expected error, got none")
+			}
+		})
+	}
+}
+
+func TestReferenceResolverCycle(t *testing.T) {
+	r := newReferenceResolver(nil)
+	target := "/org/proj/env/svc/*/1/name"
+
+	_, err := r.resolve(context.Background(), target, []string{target})
+	if err == nil {
+		t.Fatal("expected cycle error, got none")
+	}
+
+	apiErr, ok := err.(*apitypes.Error)
+	if !ok || apiErr.Type != apitypes.BadRequestError {
+		t.Errorf("wrong error returned: %s", err)
+	}
+}
+
+func TestReferenceResolverCached(t *testing.T) {
+	r := newReferenceResolver(nil)
+	target := "/org/proj/env/svc/*/1/name"
+	r.resolved[target] = &resolvedReference{value: "v", references: []string{target}}
+
+	state := "set"
+	creds := []PlaintextCredentialEnvelope{{
+		Body: &PlaintextCredential{
+			Name:    "alias",
+			PathExp: mustPathExp("/org/proj/env/svc/*/*"),
+			Value:   `{"version":1,"body":{"type":"reference","value":"` + target + `"}}`,
+			State:   &state,
+		},
+	}}
+
+	err := r.ResolveAll(context.Background(), creds)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if creds[0].Body.Value != "v" {
+		t.Errorf("wrong value! had: '%s' wanted: 'v'", creds[0].Body.Value)
+	}
+	if len(creds[0].Body.References) != 1 || creds[0].Body.References[0] != target {
+		t.Errorf("wrong references! had: %v", creds[0].Body.References)
+	}
+}
+
+func TestReferenceResolverUnresolved(t *testing.T) {
+	r := newReferenceResolver(nil)
+	target := "/org/proj/env/svc/*/1/name"
+	value := `{"version":1,"body":{"type":"reference","value":"` + target + `"}}`
+
+	// The alias refers to itself, so can't be resolved. The other credential
+	// is still returned untouched.
+	state := "set"
+	creds := []PlaintextCredentialEnvelope{
+		{Body: &PlaintextCredential{
+			Name:    "name",
+			PathExp: mustPathExp("/org/proj/env/svc/*/1"),
+			Value:   value,
+			State:   &state,
+		}},
+		{Body: &PlaintextCredential{
+			Name:    "other",
+			PathExp: mustPathExp("/org/proj/env/svc/*/1"),
+			Value:   "v",
+			State:   &state,
+		}},
+	}
+
+	err := r.ResolveAll(context.Background(), creds)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if creds[0].Body.ReferenceError == "" {
+		t.Error("expected a reference error on the credential")
+	}
+	if creds[0].Body.Value != value {
+		t.Errorf("wrong value! had: '%s'", creds[0].Body.Value)
+	}
+	if creds[1].Body.ReferenceError != "" || creds[1].Body.Value != "v" {
+		t.Errorf("other credential changed: %+v", creds[1].Body)
+	}
+}
+
+func TestMostSpecificCredential(t *testing.T) {
+	name := "name"
+	other := "other"
+	wide := "/o/p/e/s/*/*"
+	narrow := "/o/p/e/s/*/1"
+	user := "/o/p/e/s/user-jo/1"
+
+	ref := mustPathExp("/o/p/e/s/*/1")
+
+	t.Run("matches through a wildcard", func(t *testing.T) {
+		graphs := []registry.CredentialGraph{
+			buildGraph("/o/p/e/s/*/*", 1,
+				cred{id: id1, pe: &wide, name: &name},
+				cred{id: id2, pe: &wide, name: &other}),
+		}
+
+		_, c := mostSpecificCredential(graphs, ref, name)
+		if c == nil || *c.GetID() != *id1 {
+			t.Errorf("wrong credential found: %v", c)
+		}
+	})
+
+	t.Run("most specific wins", func(t *testing.T) {
+		graphs := []registry.CredentialGraph{
+			buildGraph("/o/p/e/s/*/*", 1,
+				cred{id: id1, pe: &wide, name: &name},
+				cred{id: id2, pe: &narrow, name: &name}),
+		}
+
+		_, c := mostSpecificCredential(graphs, ref, name)
+		if c == nil || *c.GetID() != *id2 {
+			t.Errorf("wrong credential found: %v", c)
+		}
+	})
+
+	t.Run("unset and other identities are ignored", func(t *testing.T) {
+		graphs := []registry.CredentialGraph{
+			buildGraph("/o/p/e/s/*/*", 1,
+				cred{id: id1, pe: &wide, name: &name},
+				cred{id: id2, pe: &narrow, name: &name, state: &unset},
+				cred{id: id3, pe: &user, name: &name}),
+		}
+
+		_, c := mostSpecificCredential(graphs, ref, name)
+		if c == nil || *c.GetID() != *id1 {
+			t.Errorf("wrong credential found: %v", c)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		graphs := []registry.CredentialGraph{
+			buildGraph("/o/p/e/s/*/*", 1, cred{id: id1, pe: &wide, name: &other}),
+		}
+
+		if _, c := mostSpecificCredential(graphs, ref, name); c != nil {
+			t.Errorf("unexpected credential found: %v", c)
+		}
+	})
+}
@@ -0,0 +1,363 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+	"github.com/manifoldco/torus-cli/registry"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto"
+)
+
+// credentialVerifier checks every object in the credential graphs read from
+// the registry before they are trusted: each object's id must match its
+// contents, and it must be signed by a verified signing key belonging to a
+// member of its org, that had not been revoked when the object was created.
+//
+// The claim tree of each org is fetched and verified once, and reused for
+// every graph in that org.
+type credentialVerifier struct {
+	client    *registry.Client
+	crypto    *crypto.Engine
+	systemKey ed25519.PublicKey
+	trees     map[identity.ID]*verifiedClaimTree
+}
+
+// verifiedClaimTree holds the public keys of an org's claim tree, along with
+// the problems found with each.
+type verifiedClaimTree struct {
+	keys     map[identity.ID]*apitypes.PublicKeySegment
+	problems map[identity.ID][]string
+}
+
+func newCredentialVerifier(e *Engine) *credentialVerifier {
+	var systemKey ed25519.PublicKey
+	if e.config.PublicKey != nil {
+		systemKey = ed25519.PublicKey(e.config.PublicKey.PublicKey)
+	}
+
+	return &credentialVerifier{
+		client:    e.client,
+		crypto:    e.crypto,
+		systemKey: systemKey,
+		trees:     make(map[identity.ID]*verifiedClaimTree),
+	}
+}
+
+// verificationError returns the error reported when an object fails
+// verification.
+func verificationError(what string, id *identity.ID, problem string) error {
+	msg := fmt.Sprintf("%s %s could not be verified: %s", what, id, problem)
+	log.Printf("Verification failed: %s", msg)
+
+	return &apitypes.Error{
+		StatusCode: http.StatusUnprocessableEntity,
+		Type:       apitypes.VerificationFailedError,
+		Err:        []string{msg},
+	}
+}
+
+// claimTree returns the verified claim tree for the given org.
+func (v *credentialVerifier) claimTree(ctx context.Context,
+	orgID *identity.ID) (*verifiedClaimTree, error) {
+
+	if tree, ok := v.trees[*orgID]; ok {
+		return tree, nil
+	}
+
+	claimTrees, err := v.client.ClaimTree.List(ctx, orgID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(claimTrees) != 1 {
+		return nil, verificationError("org", orgID, "claim tree not found")
+	}
+
+	segments := claimTrees[0].PublicKeys
+	tree := &verifiedClaimTree{
+		keys:     make(map[identity.ID]*apitypes.PublicKeySegment, len(segments)),
+		problems: make(map[identity.ID][]string, len(segments)),
+	}
+
+	keys := make(map[identity.ID]*envelope.PublicKey, len(segments))
+	for i := range segments {
+		tree.keys[*segments[i].PublicKey.ID] = &segments[i]
+		keys[*segments[i].PublicKey.ID] = segments[i].PublicKey
+	}
+
+	for i := range segments {
+		problems, err := verifyPublicKeySegment(ctx, v.crypto, &segments[i], keys, v.systemKey)
+		if err != nil {
+			return nil, err
+		}
+		tree.problems[*segments[i].PublicKey.ID] = problems
+	}
+
+	v.trees[*orgID] = tree
+	return tree, nil
+}
+
+// verifyObject checks that the object's id matches its contents, and that it
+// was signed by a member of the org. If allowSystem is set, the object may
+// instead be signed by the system's signing key.
+func (v *credentialVerifier) verifyObject(ctx context.Context, orgID *identity.ID,
+	what string, id *identity.ID, body identity.Immutable, sig *primitive.Signature,
+	created *time.Time, allowSystem bool) error {
+
+	derived, err := identity.NewImmutable(body, sig)
+	if err != nil {
+		return err
+	}
+	if id == nil || derived != *id {
+		return verificationError(what, id, "its id does not match its contents")
+	}
+
+	tree, err := v.claimTree(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	var public ed25519.PublicKey
+	var signer *apitypes.PublicKeySegment
+	if sig.PublicKeyID != nil {
+		signer = tree.keys[*sig.PublicKeyID]
+	}
+
+	switch {
+	case signer != nil:
+		if signer.PublicKey.Body.KeyType != primitive.SigningKeyType {
+			return verificationError(what, id, "it is signed by a non-signing key")
+		}
+		if problems := tree.problems[*signer.PublicKey.ID]; len(problems) > 0 {
+			return verificationError(what, id, fmt.Sprintf(
+				"its signing key %s could not be verified: %s",
+				signer.PublicKey.ID, strings.Join(problems, "; ")))
+		}
+		if revokedBefore(signer, created) {
+			return verificationError(what, id, "it is signed by a revoked key")
+		}
+		public = ed25519.PublicKey(*signer.PublicKey.Body.Key.Value)
+	case allowSystem && v.systemKey != nil:
+		public = v.systemKey
+	default:
+		return verificationError(what, id, "it is not signed by a member of the org")
+	}
+
+	ok, err := v.crypto.VerifySigned(ctx, body, sig, public)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return verificationError(what, id, "its signature is invalid")
+	}
+
+	return nil
+}
+
+// revokedBefore returns whether the key was revoked before the given time.
+// Objects without a creation time can't be shown to postdate a revocation, so
+// are not rejected for one.
+func revokedBefore(key *apitypes.PublicKeySegment, created *time.Time) bool {
+	if created == nil {
+		return false
+	}
+
+	for _, claim := range key.Claims {
+		if claim.Body.ClaimType != primitive.RevocationClaimType {
+			continue
+		}
+		if !claim.Body.Created.After(*created) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verifyMemberKeys checks that a keyring membership is encrypted for, and by,
+// encryption keys in the org's claim tree, and that it is encrypted for a key
+// belonging to its owner.
+func (v *credentialVerifier) verifyMemberKeys(ctx context.Context, orgID *identity.ID,
+	id *identity.ID, member *primitive.KeyringMember) error {
+
+	tree, err := v.claimTree(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	for _, keyID := range []*identity.ID{member.PublicKeyID, member.EncryptingKeyID} {
+		if keyID == nil {
+			return verificationError("keyring member", id, "it is missing a key")
+		}
+
+		key, ok := tree.keys[*keyID]
+		if !ok || key.PublicKey.Body.KeyType != primitive.EncryptionKeyType {
+			return verificationError("keyring member", id,
+				fmt.Sprintf("%s is not an encryption key in the org", keyID))
+		}
+	}
+
+	owner := tree.keys[*member.PublicKeyID].PublicKey.Body.OwnerID
+	if *owner != *member.OwnerID {
+		return verificationError("keyring member", id,
+			"it is encrypted for a key belonging to someone else")
+	}
+
+	return nil
+}
+
+// verifyGraph checks the keyring, memberships, and credentials of the graph,
+// and that they all belong together.
+func (v *credentialVerifier) verifyGraph(ctx context.Context, graph registry.CredentialGraph) error {
+	keyring := graph.GetKeyring()
+	keyringID := keyring.GetID()
+	orgID := keyring.OrgID()
+
+	switch k := keyring.(type) {
+	case *envelope.KeyringV1:
+		err := v.verifyObject(ctx, orgID, "keyring", k.ID, k.Body, &k.Signature,
+			&k.Body.Created, false)
+		if err != nil {
+			return err
+		}
+	case *envelope.Keyring:
+		err := v.verifyObject(ctx, orgID, "keyring", k.ID, k.Body, &k.Signature,
+			&k.Body.Created, false)
+		if err != nil {
+			return err
+		}
+	default:
+		return verificationError("keyring", keyringID, "unknown keyring schema version")
+	}
+
+	switch g := graph.(type) {
+	case *registry.CredentialGraphV1:
+		for _, m := range g.Members {
+			err := v.verifyObject(ctx, orgID, "keyring member", m.ID, m.Body,
+				&m.Signature, &m.Body.Created, false)
+			if err != nil {
+				return err
+			}
+			if *m.Body.KeyringID != *keyringID {
+				return verificationError("keyring member", m.ID, "it belongs to another keyring")
+			}
+
+			err = v.verifyMemberKeys(ctx, orgID, m.ID, &primitive.KeyringMember{
+				OwnerID:         m.Body.OwnerID,
+				PublicKeyID:     m.Body.PublicKeyID,
+				EncryptingKeyID: m.Body.EncryptingKeyID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	case *registry.CredentialGraphV2:
+		members := make(map[identity.ID]bool, len(g.Members))
+		for _, m := range g.Members {
+			member := m.Member
+			err := v.verifyObject(ctx, orgID, "keyring member", member.ID, member.Body,
+				&member.Signature, &member.Body.Created, false)
+			if err != nil {
+				return err
+			}
+			if *member.Body.KeyringID != *keyringID {
+				return verificationError("keyring member", member.ID, "it belongs to another keyring")
+			}
+
+			err = v.verifyMemberKeys(ctx, orgID, member.ID, member.Body)
+			if err != nil {
+				return err
+			}
+			members[*member.ID] = true
+
+			share := m.MEKShare
+			if share == nil {
+				continue
+			}
+
+			err = v.verifyObject(ctx, orgID, "keyring share", share.ID, share.Body,
+				&share.Signature, &share.Body.Created, false)
+			if err != nil {
+				return err
+			}
+			if *share.Body.KeyringMemberID != *member.ID {
+				return verificationError("keyring share", share.ID, "it belongs to another member")
+			}
+		}
+
+		// Membership claims, such as revocations, may be made by the system.
+		for _, c := range g.Claims {
+			err := v.verifyObject(ctx, orgID, "keyring member claim", c.ID, c.Body,
+				&c.Signature, &c.Body.Created, true)
+			if err != nil {
+				return err
+			}
+			if !members[*c.Body.KeyringMemberID] {
+				return verificationError("keyring member claim", c.ID,
+					"it is made against a member of another keyring")
+			}
+		}
+	}
+
+	for _, cred := range graph.GetCredentials() {
+		if err := v.verifyCredential(ctx, keyring, cred); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyCredential checks a credential, and that it belongs to the keyring.
+//
+// Credentials before schema v3 have no creation time. They were written after
+// their keyring was created, so they're checked against a key revocation as
+// though they were created along with the keyring.
+func (v *credentialVerifier) verifyCredential(ctx context.Context, keyring envelope.KeyringInf,
+	cred envelope.CredentialInf) error {
+
+	orgID := keyring.OrgID()
+
+	var body identity.Immutable
+	var sig *primitive.Signature
+	var credKeyringID *identity.ID
+	switch c := cred.(type) {
+	case *envelope.CredentialV1:
+		body, sig, credKeyringID = c.Body, &c.Signature, c.Body.KeyringID
+	case *envelope.CredentialV2:
+		body, sig, credKeyringID = c.Body, &c.Signature, c.Body.KeyringID
+	case *envelope.Credential:
+		body, sig, credKeyringID = c.Body, &c.Signature, c.Body.KeyringID
+	default:
+		return verificationError("credential", cred.GetID(), "unknown credential schema version")
+	}
+
+	created := cred.Created()
+	if created == nil {
+		keyringCreated := keyring.Created()
+		created = &keyringCreated
+	}
+
+	err := v.verifyObject(ctx, orgID, "credential", cred.GetID(), body, sig, created, false)
+	if err != nil {
+		return err
+	}
+	if credKeyringID == nil || *credKeyringID != *keyring.GetID() {
+		return verificationError("credential", cred.GetID(), "it belongs to another keyring")
+	}
+	if *cred.OrgID() != *orgID {
+		return verificationError("credential", cred.GetID(), "it belongs to another org")
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto"
+)
+
+func TestCredentialVerifierVerifyObject(t *testing.T) {
+	ctx := context.Background()
+
+	orgID, err := identity.NewMutable(&primitive.Org{Name: "org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyBody := &primitive.PublicKey{
+		Algorithm: crypto.EdDSA,
+		Created:   time.Now().UTC().Add(-time.Hour),
+		Key:       primitive.PublicKeyValue{Value: base64.NewValue(pub)},
+		OrgID:     &orgID,
+		KeyType:   primitive.SigningKeyType,
+	}
+	keyID, keySig := testSign(t, keyBody, testSigner{priv: priv})
+	signer := testSigner{id: keyID, priv: priv}
+
+	segment := &apitypes.PublicKeySegment{
+		PublicKey: &envelope.PublicKey{ID: keyID, Version: 1, Signature: keySig, Body: keyBody},
+	}
+
+	newVerifier := func(problems ...string) *credentialVerifier {
+		return &credentialVerifier{
+			crypto: &crypto.Engine{},
+			trees: map[identity.ID]*verifiedClaimTree{
+				orgID: {
+					keys:     map[identity.ID]*apitypes.PublicKeySegment{*keyID: segment},
+					problems: map[identity.ID][]string{*keyID: problems},
+				},
+			},
+		}
+	}
+
+	newKeyring := func(s testSigner) (*identity.ID, *primitive.Keyring, primitive.Signature) {
+		body := primitive.NewKeyring(&orgID, nil, nil)
+		id, sig := testSign(t, body, s)
+		return id, body, sig
+	}
+
+	expectFailure := func(t *testing.T, err error) {
+		if !apitypes.IsVerificationFailedError(err) {
+			t.Errorf("expected a verification failure, got %v", err)
+		}
+	}
+
+	verify := func(v *credentialVerifier, id *identity.ID, body *primitive.Keyring,
+		sig primitive.Signature) error {
+		return v.verifyObject(ctx, &orgID, "keyring", id, body, &sig, &body.Created, false)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		id, body, sig := newKeyring(signer)
+		if err := verify(newVerifier(), id, body, sig); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+
+	t.Run("tampered contents", func(t *testing.T) {
+		id, body, sig := newKeyring(signer)
+		body.KeyringVersion = 2
+		expectFailure(t, verify(newVerifier(), id, body, sig))
+	})
+
+	t.Run("tampered id and contents", func(t *testing.T) {
+		_, body, sig := newKeyring(signer)
+		body.KeyringVersion = 2
+		id, err := identity.NewImmutable(body, &sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectFailure(t, verify(newVerifier(), &id, body, sig))
+	})
+
+	t.Run("unknown signer", func(t *testing.T) {
+		_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherID, err := identity.NewMutable(&primitive.Team{Name: "other"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id, body, sig := newKeyring(testSigner{id: &otherID, priv: otherPriv})
+		expectFailure(t, verify(newVerifier(), id, body, sig))
+	})
+
+	t.Run("untrusted signer", func(t *testing.T) {
+		id, body, sig := newKeyring(signer)
+		expectFailure(t, verify(newVerifier("public key signature is invalid"), id, body, sig))
+	})
+
+	t.Run("revoked signer", func(t *testing.T) {
+		id, body, sig := newKeyring(signer)
+
+		revoked := *segment
+		revoked.Claims = []envelope.Claim{{Body: &primitive.Claim{
+			ClaimType: primitive.RevocationClaimType,
+			Created:   body.Created.Add(-time.Minute),
+		}}}
+		v := newVerifier()
+		v.trees[orgID].keys[*keyID] = &revoked
+		expectFailure(t, verify(v, id, body, sig))
+
+		// Objects signed before the revocation are still trusted
+		revoked.Claims[0].Body.Created = body.Created.Add(time.Minute)
+		if err := verify(v, id, body, sig); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+
+	t.Run("v2 credential after key rotation", func(t *testing.T) {
+		keyringID, keyringBody, keyringSig := newKeyring(signer)
+		keyring := &envelope.Keyring{ID: keyringID, Version: 2, Body: keyringBody,
+			Signature: keyringSig}
+
+		credBody := &primitive.CredentialV2{BaseCredential: primitive.BaseCredential{
+			KeyringID: keyringID,
+			OrgID:     &orgID,
+			Name:      "secret",
+		}}
+		credID, credSig := testSign(t, credBody, signer)
+		cred := &envelope.CredentialV2{ID: credID, Version: 2, Body: credBody,
+			Signature: credSig}
+
+		rotated := *segment
+		rotated.Claims = []envelope.Claim{{Body: &primitive.Claim{
+			ClaimType: primitive.RevocationClaimType,
+			Created:   keyringBody.Created.Add(time.Minute),
+		}}}
+		v := newVerifier()
+		v.trees[orgID].keys[*keyID] = &rotated
+
+		// The credential has no creation time, but its keyring predates the
+		// rotation, so it may have been signed before it.
+		if err := v.verifyCredential(ctx, keyring, cred); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+
+		rotated.Claims[0].Body.Created = keyringBody.Created.Add(-time.Minute)
+		expectFailure(t, v.verifyCredential(ctx, keyring, cred))
+	})
+}
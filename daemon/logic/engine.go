@@ -6,6 +6,8 @@ package logic
 import (
 	"context"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/base64"
@@ -36,6 +38,10 @@ type Engine struct {
 	Worklog Worklog
 	Machine Machine
 	Session Session
+
+	// revoking is set while scheduled token revocations are being made.
+	revokerMutex sync.Mutex
+	revoking     bool
 }
 
 // NewEngine returns a new Engine
@@ -113,20 +119,18 @@ func (e *Engine) AppendCredential(ctx context.Context, notifier *observer.Notifi
 	}
 
 	// Construct an encrypted and signed version of the credential
-	credBody := primitive.Credential{
-		State: cred.Body.State,
-		BaseCredential: primitive.BaseCredential{
-			Name:      cred.Body.Name,
-			PathExp:   cred.Body.PathExp,
-			KeyringID: graph.GetKeyring().GetID(),
-			ProjectID: cred.Body.ProjectID,
-			OrgID:     cred.Body.OrgID,
-			Credential: &primitive.CredentialValue{
-				Algorithm: crypto.SecretBox,
-			},
+	credBody := primitive.BaseCredential{
+		Name:      cred.Body.Name,
+		PathExp:   cred.Body.PathExp,
+		KeyringID: graph.GetKeyring().GetID(),
+		ProjectID: cred.Body.ProjectID,
+		OrgID:     cred.Body.OrgID,
+		Credential: &primitive.CredentialValue{
+			Algorithm: crypto.SecretBox,
 		},
 	}
 
+	metadata := cred.Body.Metadata
 	if previousCred == nil {
 		log.Printf("no previous")
 		credBody.Previous = nil
@@ -134,7 +138,12 @@ func (e *Engine) AppendCredential(ctx context.Context, notifier *observer.Notifi
 	} else {
 		credBody.Previous = previousCred.GetID()
 		credBody.CredentialVersion = previousCred.CredentialVersion() + 1
+		if !cred.Body.ClearMetadata {
+			metadata = mergeCredentialMetadata(previousCred.Metadata(), metadata)
+		}
 	}
+	cred.Body.Metadata = metadata
+	cred.Body.ClearMetadata = false
 
 	krm, mekshare, err := graph.FindMember(e.session.AuthID())
 	if err != nil {
@@ -166,7 +175,23 @@ func (e *Engine) AppendCredential(ctx context.Context, notifier *observer.Notifi
 	credBody.Credential.Nonce = base64.NewValue(ctNonce)
 	credBody.Credential.Value = base64.NewValue(ct)
 
-	signed, err := e.crypto.SignedCredential(ctx, &credBody, sigID, &kp.Signature)
+	// Credentials without metadata are written with the v2 schema, so they
+	// remain readable by older clients.
+	var signed envelope.CredentialInf
+	if metadata != nil {
+		created := time.Now().UTC()
+		signed, err = e.crypto.SignedCredential(ctx, &primitive.Credential{
+			BaseCredential: credBody,
+			State:          cred.Body.State,
+			Metadata:       metadata,
+			Created:        &created,
+		}, sigID, &kp.Signature)
+	} else {
+		signed, err = e.crypto.SignedCredentialV2(ctx, &primitive.CredentialV2{
+			BaseCredential: credBody,
+			State:          cred.Body.State,
+		}, sigID, &kp.Signature)
+	}
 	if err != nil {
 		log.Printf("Error signing credential body: %s", err)
 		return nil, err
@@ -190,8 +215,8 @@ func (e *Engine) AppendCredential(ctx context.Context, notifier *observer.Notifi
 }
 
 // RetrieveCredentials returns all credentials for the given CPath string
-func (e *Engine) RetrieveCredentials(ctx context.Context,
-	notifier *observer.Notifier, cpath, cpathexp *string) ([]PlaintextCredentialEnvelope, error) {
+func (e *Engine) RetrieveCredentials(ctx context.Context, notifier *observer.Notifier,
+	cpath, cpathexp *string, verify bool) ([]PlaintextCredentialEnvelope, error) {
 	if cpath != nil && cpathexp != nil {
 		panic("cannot use both cpath and cpathexp")
 	}
@@ -230,6 +255,19 @@ func (e *Engine) RetrieveCredentials(ctx context.Context,
 	n := notifier.Notifier(steps)
 	n.Notify(observer.Progress, "Credentials retrieved", true)
 
+	// When verifying, every graph is checked before anything in it is
+	// decrypted, and references are only followed to verified graphs.
+	var verifier *credentialVerifier
+	if verify {
+		verifier = newCredentialVerifier(e)
+		for _, graph := range activeGraphs {
+			err = verifier.verifyGraph(ctx, graph)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	keypairs := make(map[identity.ID]*crypto.KeyPairs)
 	encryptingKeys := make(map[identity.ID]*primitive.PublicKey)
 
@@ -289,6 +327,7 @@ func (e *Engine) RetrieveCredentials(ctx context.Context,
 						OrgID:     cred.OrgID(),
 						Value:     string(pt),
 						State:     &state,
+						Metadata:  cred.Metadata(),
 					},
 				}
 				creds = append(creds, plainCred)
@@ -302,6 +341,15 @@ func (e *Engine) RetrieveCredentials(ctx context.Context,
 		}
 	}
 
+	// Replace any references to other credentials with the values they
+	// resolve to.
+	resolver := newReferenceResolver(e)
+	resolver.verifier = verifier
+	err = resolver.ResolveAll(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+
 	return creds, nil
 }
 
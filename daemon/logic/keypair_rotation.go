@@ -0,0 +1,358 @@
+package logic
+
+import (
+	"context"
+	"log"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+	"github.com/manifoldco/torus-cli/registry"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto"
+	"github.com/manifoldco/torus-cli/daemon/observer"
+)
+
+// claimedKeyPairs is a signing keypair, and the encryption keypair it signed.
+type claimedKeyPairs struct {
+	sig *registry.ClaimedKeyPair
+	enc *registry.ClaimedKeyPair
+}
+
+// keypairRotation is the state of a keypair rotation for an org, as seen from
+// the keypairs on the registry.
+type keypairRotation struct {
+	// current holds the keypairs being rotated out. It is nil once their
+	// encryption keypair has been revoked.
+	current *claimedKeyPairs
+
+	// next holds the replacement keypairs. It is nil until they have been
+	// uploaded.
+	next *claimedKeyPairs
+
+	// revoke holds every active keypair that is not part of next.
+	revoke []registry.ClaimedKeyPair
+}
+
+// findKeypairRotation works out how far along a rotation of the given
+// keypairs is, so a rotation that was interrupted can be resumed.
+//
+// Active keypairs are paired by the signing key that signed each encryption
+// key. The newest pair is the replacement if an older signing key is still
+// active; otherwise the newest pair is the one being rotated out.
+func findKeypairRotation(keypairs []registry.ClaimedKeyPair) (*keypairRotation, error) {
+	var sigs, encs []registry.ClaimedKeyPair
+	for _, kp := range keypairs {
+		if kp.Revoked() {
+			continue
+		}
+
+		switch kp.PublicKey.Body.KeyType {
+		case primitive.SigningKeyType:
+			sigs = append(sigs, kp)
+		case primitive.EncryptionKeyType:
+			encs = append(encs, kp)
+		}
+	}
+
+	var pairs []claimedKeyPairs
+	var newestSig *registry.ClaimedKeyPair
+	for i := range sigs {
+		sig := &sigs[i]
+		if newestSig == nil || sig.PublicKey.Body.Created.After(newestSig.PublicKey.Body.Created) {
+			newestSig = sig
+		}
+
+		for j := range encs {
+			enc := &encs[j]
+			if *enc.PublicKey.Signature.PublicKeyID == *sig.PublicKey.ID {
+				pairs = append(pairs, claimedKeyPairs{sig: sig, enc: enc})
+			}
+		}
+	}
+
+	if len(pairs) == 0 {
+		return nil, &apitypes.Error{
+			Type: apitypes.NotFoundError,
+			Err:  []string{"No active keypairs to rotate."},
+		}
+	}
+
+	// Order the pairs from oldest to newest
+	for i := 1; i < len(pairs); i++ {
+		for j := i; j > 0 && pairs[j].sig.PublicKey.Body.Created.Before(pairs[j-1].sig.PublicKey.Body.Created); j-- {
+			pairs[j], pairs[j-1] = pairs[j-1], pairs[j]
+		}
+	}
+
+	newest := pairs[len(pairs)-1]
+	r := &keypairRotation{}
+	switch {
+	case len(pairs) > 1:
+		r.current = &pairs[len(pairs)-2]
+		r.next = &newest
+	case newestSig == newest.sig && len(sigs) > 1:
+		// The previous encryption keypair has already been revoked
+		r.next = &newest
+	default:
+		r.current = &newest
+	}
+
+	for _, kp := range append(encs, sigs...) {
+		if r.next != nil && (*kp.PublicKey.ID == *r.next.sig.PublicKey.ID ||
+			*kp.PublicKey.ID == *r.next.enc.PublicKey.ID) {
+			continue
+		}
+		r.revoke = append(r.revoke, kp)
+	}
+
+	return r, nil
+}
+
+// RotateKeypairs replaces the current user's signing and encryption keypairs
+// for the given org. New keypairs are generated and claimed, each of the
+// user's keyring memberships is cloned for the new encryption key, and then
+// the old keypairs are revoked.
+//
+// Each step checks the registry for work already done, so an interrupted
+// rotation is resumed by running it again.
+func (e *Engine) RotateKeypairs(ctx context.Context, notifier *observer.Notifier,
+	orgID *identity.ID) error {
+
+	n := notifier.Notifier(3)
+
+	keypairs, err := e.client.KeyPairs.List(ctx, orgID)
+	if err != nil {
+		log.Printf("Error retrieving keypairs: %s", err)
+		return err
+	}
+
+	rotation, err := findKeypairRotation(keypairs)
+	if err != nil {
+		return err
+	}
+
+	if rotation.next == nil {
+		err = e.GenerateKeypairs(ctx, n, orgID)
+		if err != nil {
+			return err
+		}
+
+		keypairs, err = e.client.KeyPairs.List(ctx, orgID)
+		if err != nil {
+			log.Printf("Error retrieving keypairs: %s", err)
+			return err
+		}
+
+		rotation, err = findKeypairRotation(keypairs)
+		if err != nil {
+			return err
+		}
+		if rotation.next == nil {
+			return &apitypes.Error{
+				Type: apitypes.InternalServerError,
+				Err:  []string{"Generated keypairs not found."},
+			}
+		}
+	}
+
+	n.Notify(observer.Progress, "New keypairs claimed", true)
+
+	if rotation.current != nil {
+		err = e.cloneRotatedMemberships(ctx, n, orgID, rotation.current, rotation.next)
+		if err != nil {
+			return err
+		}
+	}
+
+	n.Notify(observer.Progress, "Keyring memberships cloned", true)
+
+	for _, kp := range rotation.revoke {
+		err = e.revokeRotatedKeypair(ctx, orgID, &kp, rotation)
+		if err != nil {
+			return err
+		}
+	}
+
+	n.Notify(observer.Progress, "Old keypairs revoked", true)
+
+	return nil
+}
+
+// cloneRotatedMemberships gives the current user a keyring membership for the
+// next encryption key in every keyring they can access with the current one.
+func (e *Engine) cloneRotatedMemberships(ctx context.Context, notifier *observer.Notifier,
+	orgID *identity.ID, current, next *claimedKeyPairs) error {
+
+	authID := e.session.AuthID()
+
+	org, err := e.client.Orgs.Get(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	projects, err := e.client.Projects.List(ctx, org.ID)
+	if err != nil {
+		return err
+	}
+
+	var graphs []registry.CredentialGraph
+	for _, project := range projects {
+		projGraphs, err := e.client.CredentialGraph.Search(ctx,
+			"/"+org.Body.Name+"/"+project.Body.Name+"/*/*/*/*", authID)
+		if err != nil {
+			log.Printf("Error retrieving credential graphs: %s", err)
+			return err
+		}
+
+		graphs = append(graphs, projGraphs...)
+	}
+
+	cgs := newCredentialGraphSet()
+	err = cgs.Add(graphs...)
+	if err != nil {
+		return err
+	}
+
+	activeGraphs, err := cgs.Active()
+	if err != nil {
+		return err
+	}
+
+	n := notifier.Notifier(uint(len(activeGraphs)))
+
+	currentKP := bundleKeypairs(current.sig, current.enc)
+	nextKP := bundleKeypairs(next.sig, next.enc)
+	nextEncID := next.enc.PublicKey.ID
+	nextSigID := next.sig.PublicKey.ID
+
+	for _, graph := range activeGraphs {
+		_, _, err := graph.FindMemberByKey(authID, nextEncID)
+		if err == nil { // cloned by an earlier, interrupted rotation
+			n.Notify(observer.Progress, "Keyring membership already cloned", true)
+			continue
+		}
+
+		krm, mekshare, err := graph.FindMemberByKey(authID, current.enc.PublicKey.ID)
+		if err != nil {
+			log.Printf("No membership for current keypair in keyring %s: %s",
+				graph.GetKeyring().GetID(), err)
+			n.Notify(observer.Progress, "Keyring membership skipped", true)
+			continue
+		}
+
+		encryptingKey, err := findEncryptingKey(ctx, e.client, orgID, krm.EncryptingKeyID)
+		if err != nil {
+			return err
+		}
+
+		encMek, nonce, err := e.crypto.CloneMembership(ctx, *mekshare.Key.Value,
+			*mekshare.Key.Nonce, &currentKP.Encryption, *encryptingKey.Key.Value,
+			nextKP.Encryption.Public[:])
+		if err != nil {
+			log.Printf("Could not clone keyring membership: %s", err)
+			return err
+		}
+
+		// Clone the share again, from the next keypair to itself, so the
+		// membership does not depend on the current keypair once it is
+		// revoked.
+		encMek, nonce, err = e.crypto.CloneMembership(ctx, encMek, nonce,
+			&nextKP.Encryption, currentKP.Encryption.Public[:],
+			nextKP.Encryption.Public[:])
+		if err != nil {
+			log.Printf("Could not clone keyring membership: %s", err)
+			return err
+		}
+
+		key := &primitive.KeyringMemberKey{
+			Algorithm: crypto.EasyBox,
+			Nonce:     base64.NewValue(nonce),
+			Value:     base64.NewValue(encMek),
+		}
+
+		switch k := graph.GetKeyring().(type) {
+		case *envelope.KeyringV1:
+			member, err := newV1KeyringMember(ctx, e.crypto, krm.OrgID,
+				k.Body.ProjectID, krm.KeyringID, authID, nextEncID, nextEncID,
+				nextSigID, key, nextKP)
+			if err != nil {
+				return err
+			}
+
+			_, err = e.client.KeyringMember.Post(ctx, []envelope.KeyringMemberV1{*member})
+			if err != nil {
+				log.Printf("Error uploading keyring membership: %s", err)
+				return err
+			}
+		case *envelope.Keyring:
+			member, err := newV2KeyringMember(ctx, e.crypto, krm.OrgID,
+				krm.KeyringID, authID, nextEncID, nextEncID, nextSigID, key, nextKP)
+			if err != nil {
+				return err
+			}
+
+			err = e.client.Keyring.Members.Post(ctx, *member)
+			if err != nil {
+				log.Printf("Error uploading keyring membership: %s", err)
+				return err
+			}
+		default:
+			return &apitypes.Error{
+				Type: apitypes.InternalServerError,
+				Err:  []string{"Unknown keyring schema version"},
+			}
+		}
+
+		n.Notify(observer.Progress, "Keyring membership cloned", true)
+	}
+
+	return nil
+}
+
+// revokeRotatedKeypair creates a revocation claim for a keypair replaced by
+// the rotation. Signing keys sign their own revocation, as in RevokeKeypairs.
+// Encryption keys are revoked by the signing key that signed them, or by the
+// next signing key if that one is already revoked.
+func (e *Engine) revokeRotatedKeypair(ctx context.Context, orgID *identity.ID,
+	target *registry.ClaimedKeyPair, rotation *keypairRotation) error {
+
+	signer := rotation.next.sig
+	switch target.PublicKey.Body.KeyType {
+	case primitive.SigningKeyType:
+		signer = target
+	case primitive.EncryptionKeyType:
+		for i, kp := range rotation.revoke {
+			if *kp.PublicKey.ID == *target.PublicKey.Signature.PublicKeyID {
+				signer = &rotation.revoke[i]
+			}
+		}
+	}
+
+	kp := bundleKeypairs(signer, nil)
+
+	prevClaim, err := target.HeadClaim()
+	if err != nil {
+		return err
+	}
+
+	body := primitive.NewClaim(orgID, e.session.AuthID(), prevClaim.ID,
+		target.PublicKey.ID, primitive.RevocationClaimType)
+	claim, err := e.crypto.SignedClaim(ctx, body, signer.PublicKey.ID, &kp.Signature)
+	if err != nil {
+		log.Printf("Error creating revocation claim for %s key: %s",
+			target.PublicKey.Body.KeyType, err)
+		return err
+	}
+
+	_, err = e.client.Claims.Create(ctx, claim)
+	if err != nil {
+		log.Printf("Error uploading %s keypair revocation: %s",
+			target.PublicKey.Body.KeyType, err)
+		return err
+	}
+
+	return nil
+}
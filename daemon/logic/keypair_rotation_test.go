@@ -0,0 +1,150 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+	"github.com/manifoldco/torus-cli/registry"
+)
+
+func TestFindKeypairRotation(t *testing.T) {
+	now := time.Now().UTC()
+
+	newID := func(name string) *identity.ID {
+		id, err := identity.NewMutable(&primitive.Team{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &id
+	}
+
+	keypair := func(name string, keyType primitive.KeyType, created time.Time,
+		signer *registry.ClaimedKeyPair, revoked bool) registry.ClaimedKeyPair {
+
+		kp := registry.ClaimedKeyPair{
+			PublicKeySegment: apitypes.PublicKeySegment{
+				PublicKey: &envelope.PublicKey{
+					ID: newID(name),
+					Body: &primitive.PublicKey{
+						KeyType: keyType,
+						Created: created,
+					},
+				},
+			},
+		}
+
+		kp.PublicKey.Signature.PublicKeyID = kp.PublicKey.ID
+		if signer != nil {
+			kp.PublicKey.Signature.PublicKeyID = signer.PublicKey.ID
+		}
+		if revoked {
+			kp.Claims = []envelope.Claim{{Body: &primitive.Claim{
+				ClaimType: primitive.RevocationClaimType,
+			}}}
+		}
+		return kp
+	}
+
+	oldSig := keypair("old-sig", primitive.SigningKeyType, now.Add(-time.Hour), nil, false)
+	oldEnc := keypair("old-enc", primitive.EncryptionKeyType, now.Add(-time.Hour), &oldSig, false)
+	oldEncRevoked := oldEnc
+	oldEncRevoked.Claims = []envelope.Claim{{Body: &primitive.Claim{
+		ClaimType: primitive.RevocationClaimType,
+	}}}
+	oldSigRevoked := oldSig
+	oldSigRevoked.Claims = oldEncRevoked.Claims
+
+	newSig := keypair("new-sig", primitive.SigningKeyType, now, nil, false)
+	newEnc := keypair("new-enc", primitive.EncryptionKeyType, now, &newSig, false)
+	danglingSig := keypair("dangling-sig", primitive.SigningKeyType, now.Add(time.Minute), nil, false)
+
+	id := func(kp *registry.ClaimedKeyPair) identity.ID { return *kp.PublicKey.ID }
+	pair := func(p *claimedKeyPairs) []identity.ID {
+		if p == nil {
+			return nil
+		}
+		return []identity.ID{id(p.sig), id(p.enc)}
+	}
+
+	tcs := []struct {
+		name     string
+		keypairs []registry.ClaimedKeyPair
+		current  []identity.ID
+		next     []identity.ID
+		revoke   int
+	}{
+		{
+			name:     "not started",
+			keypairs: []registry.ClaimedKeyPair{oldSig, oldEnc},
+			current:  []identity.ID{id(&oldSig), id(&oldEnc)},
+			revoke:   2,
+		},
+		{
+			name:     "new keypairs uploaded",
+			keypairs: []registry.ClaimedKeyPair{newEnc, oldSig, newSig, oldEnc},
+			current:  []identity.ID{id(&oldSig), id(&oldEnc)},
+			next:     []identity.ID{id(&newSig), id(&newEnc)},
+			revoke:   2,
+		},
+		{
+			name:     "only signing keypair uploaded",
+			keypairs: []registry.ClaimedKeyPair{oldSig, oldEnc, danglingSig},
+			current:  []identity.ID{id(&oldSig), id(&oldEnc)},
+			revoke:   3,
+		},
+		{
+			name:     "encryption keypair revoked",
+			keypairs: []registry.ClaimedKeyPair{oldSig, oldEncRevoked, newSig, newEnc},
+			next:     []identity.ID{id(&newSig), id(&newEnc)},
+			revoke:   1,
+		},
+		{
+			name:     "finished",
+			keypairs: []registry.ClaimedKeyPair{oldSigRevoked, oldEncRevoked, newSig, newEnc},
+			current:  []identity.ID{id(&newSig), id(&newEnc)},
+			revoke:   2,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := findKeypairRotation(tc.keypairs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := pair(r.current); !equalIDs(got, tc.current) {
+				t.Errorf("current = %v, want %v", got, tc.current)
+			}
+			if got := pair(r.next); !equalIDs(got, tc.next) {
+				t.Errorf("next = %v, want %v", got, tc.next)
+			}
+			if len(r.revoke) != tc.revoke {
+				t.Errorf("revoking %d keypairs, want %d", len(r.revoke), tc.revoke)
+			}
+		})
+	}
+
+	t.Run("no keypairs", func(t *testing.T) {
+		_, err := findKeypairRotation([]registry.ClaimedKeyPair{oldSig, oldEncRevoked})
+		if err == nil {
+			t.Error("expected an error, got none")
+		}
+	})
+}
+
+func equalIDs(a, b []identity.ID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
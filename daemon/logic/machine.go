@@ -31,6 +31,21 @@ type MachineTokenSegment struct {
 // CreateToken generates a new machine token given a machine and a secret value.
 func (m *Machine) CreateToken(ctx context.Context, notifier *observer.Notifier,
 	machine *envelope.Machine, secret *base64.Value) (*registry.MachineTokenCreationSegment, error) {
+	return m.createToken(ctx, notifier, machine, secret, m.engine.session.ID())
+}
+
+// CreateEnrollmentToken generates the first token of a machine enrolling
+// itself with an enrollment code. There is no session; the token is recorded
+// as created by the creator of the enrollment code.
+func (m *Machine) CreateEnrollmentToken(ctx context.Context, notifier *observer.Notifier,
+	machine *envelope.Machine, secret *base64.Value, createdBy *identity.ID) (
+	*registry.MachineTokenCreationSegment, error) {
+	return m.createToken(ctx, notifier, machine, secret, createdBy)
+}
+
+func (m *Machine) createToken(ctx context.Context, notifier *observer.Notifier,
+	machine *envelope.Machine, secret *base64.Value, createdBy *identity.ID) (
+	*registry.MachineTokenCreationSegment, error) {
 	n := notifier.Notifier(2)
 
 	n.Notify(observer.Progress, "Generating machine token", true)
@@ -60,7 +75,7 @@ func (m *Machine) CreateToken(ctx context.Context, notifier *observer.Notifier,
 			Alg:   crypto.EdDSA,
 		},
 		Master:      masterKey,
-		CreatedBy:   m.engine.session.ID(),
+		CreatedBy:   createdBy,
 		Created:     time.Now().UTC(),
 		DestroyedBy: nil,
 		Destroyed:   nil,
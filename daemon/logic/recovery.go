@@ -0,0 +1,270 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+	"github.com/manifoldco/torus-cli/registry"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto"
+	"github.com/manifoldco/torus-cli/daemon/crypto/shamir"
+)
+
+const (
+	recoveryBundleVersion = 1
+	recoveryKeyBytes      = 32
+)
+
+// passwordUpdate is the user update that changes their password, and the
+// master key encrypted with it.
+type passwordUpdate struct {
+	Password *primitive.UserPassword `json:"password"`
+	Master   *primitive.MasterKey    `json:"master"`
+}
+
+func recoveryError(format string, a ...interface{}) error {
+	return &apitypes.Error{
+		StatusCode: http.StatusBadRequest,
+		Type:       apitypes.BadRequestError,
+		Err:        []string{fmt.Sprintf(format, a...)},
+	}
+}
+
+// BackupMasterKey creates a recovery bundle holding the current user's master
+// key, encrypted with either the given passphrase, or a random key split into
+// shares, threshold of which are needed to recover it.
+//
+// The bundle records the user's active keypairs at the time, so a restore can
+// check it matches them.
+func (e *Engine) BackupMasterKey(ctx context.Context,
+	req *apitypes.RecoveryBackupRequest) (*apitypes.RecoveryBackup, error) {
+
+	if e.session.Type() != apitypes.UserSession {
+		return nil, recoveryError("Only users can back up their keys.")
+	}
+
+	bundle := &apitypes.RecoveryBundle{
+		Version: recoveryBundleVersion,
+		OwnerID: e.session.AuthID(),
+		Created: time.Now().UTC(),
+	}
+
+	var key []byte
+	switch {
+	case req.Passphrase != "":
+		bundle.Scheme = apitypes.PassphraseRecovery
+		key = []byte(req.Passphrase)
+	case req.Shares > 0:
+		bundle.Scheme = apitypes.ShamirRecovery
+		bundle.Shares = req.Shares
+		bundle.Threshold = req.Threshold
+
+		key = make([]byte, recoveryKeyBytes)
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, recoveryError("A recovery passphrase or number of shares is required.")
+	}
+
+	backup := &apitypes.RecoveryBackup{Bundle: bundle}
+	if bundle.Scheme == apitypes.ShamirRecovery {
+		shares, err := shamir.Split(key, req.Shares, req.Threshold)
+		if err != nil {
+			return nil, recoveryError("Could not split recovery key: %s", err)
+		}
+
+		for _, s := range shares {
+			backup.Shares = append(backup.Shares, apitypes.RecoveryShare{
+				OwnerID: bundle.OwnerID,
+				Created: bundle.Created,
+				Share:   base64.NewValue(s),
+			})
+		}
+	}
+
+	keypairs, err := e.listOwnKeypairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, kp := range keypairs {
+		if !kp.Revoked() {
+			bundle.PublicKeyIDs = append(bundle.PublicKeyIDs, *kp.PublicKey.ID)
+		}
+	}
+	if len(bundle.PublicKeyIDs) == 0 {
+		return nil, recoveryError("You have no active keypairs to back up.")
+	}
+
+	mk, err := e.crypto.ExportMasterKey(ctx, key)
+	if err != nil {
+		log.Printf("Error encrypting master key for recovery: %s", err)
+		return nil, err
+	}
+	bundle.MasterKey = base64.NewValue(mk)
+
+	return backup, nil
+}
+
+// RestoreMasterKey recovers the master key held in a recovery bundle, and
+// makes it the current user's master key, encrypted with their new password.
+// The keypairs sealed with it become usable again.
+//
+// Active keypairs that can't be unsealed with the recovered master key, such
+// as those generated after a password reset, become unusable. The restore is
+// refused if there are any, unless it is forced.
+//
+// The session must be logged in again with the new password afterwards.
+func (e *Engine) RestoreMasterKey(ctx context.Context, req *apitypes.RecoveryRestoreRequest) error {
+	if e.session.Type() != apitypes.UserSession {
+		return recoveryError("Only users can restore their keys.")
+	}
+
+	bundle := req.Bundle
+	switch {
+	case bundle == nil || bundle.MasterKey == nil:
+		return recoveryError("A recovery bundle is required.")
+	case bundle.Version != recoveryBundleVersion:
+		return recoveryError("Unsupported recovery bundle version: %d", bundle.Version)
+	case bundle.OwnerID == nil || *bundle.OwnerID != *e.session.AuthID():
+		return recoveryError("The recovery bundle belongs to another user.")
+	case req.Password == "":
+		return recoveryError("A new password is required.")
+	}
+
+	key, err := recoveryKey(bundle, req)
+	if err != nil {
+		return err
+	}
+
+	mk, err := crypto.RecoverMasterKey(ctx, *bundle.MasterKey, key)
+	if err != nil {
+		log.Printf("Error decrypting recovery bundle: %s", err)
+		return recoveryError("Could not decrypt the recovery bundle; check the passphrase or shares.")
+	}
+
+	keypairs, err := e.listOwnKeypairs(ctx)
+	if err != nil {
+		return err
+	}
+
+	matched, lost := checkRecoveredKeypairs(ctx, mk, bundle.PublicKeyIDs, keypairs)
+	if matched == 0 {
+		return recoveryError("The recovery bundle does not hold the key to any of your keypairs.")
+	}
+	if len(lost) > 0 && !req.Force {
+		return recoveryError("%d active keypairs were not sealed with the recovered key, "+
+			"and would become unusable. Revoke them, or restore with --force.", len(lost))
+	}
+
+	pw, master, err := crypto.EncryptPasswordObject(ctx, req.Password, &mk)
+	if err != nil {
+		log.Printf("Error generating password object: %s", err)
+		return err
+	}
+
+	user, err := e.client.Users.Update(ctx, passwordUpdate{Password: pw, Master: master})
+	if err != nil {
+		log.Printf("Error updating password: %s", err)
+		return err
+	}
+
+	return e.session.SetIdentity(apitypes.UserSession, user, user)
+}
+
+// recoveryKey returns the key a recovery bundle's master key is encrypted
+// with, from the passphrase or shares in the request.
+func recoveryKey(bundle *apitypes.RecoveryBundle, req *apitypes.RecoveryRestoreRequest) ([]byte, error) {
+	switch bundle.Scheme {
+	case apitypes.PassphraseRecovery:
+		if req.Passphrase == "" {
+			return nil, recoveryError("The recovery passphrase is required.")
+		}
+		return []byte(req.Passphrase), nil
+	case apitypes.ShamirRecovery:
+		if len(req.Shares) < bundle.Threshold {
+			return nil, recoveryError("%d of the %d recovery shares are required, got %d.",
+				bundle.Threshold, bundle.Shares, len(req.Shares))
+		}
+
+		shares := make([][]byte, len(req.Shares))
+		for i, s := range req.Shares {
+			if s.Share == nil || s.OwnerID == nil || *s.OwnerID != *bundle.OwnerID ||
+				!s.Created.Equal(bundle.Created) {
+				return nil, recoveryError("Recovery share %d does not belong to the bundle.", i+1)
+			}
+			shares[i] = *s.Share
+		}
+
+		key, err := shamir.Combine(shares)
+		if err != nil {
+			return nil, recoveryError("Could not combine recovery shares: %s", err)
+		}
+		return key, nil
+	default:
+		return nil, recoveryError("Unknown recovery scheme: %s", bundle.Scheme)
+	}
+}
+
+// checkRecoveredKeypairs tries to unseal the private key of each keypair with
+// the recovered master key. It returns how many of the backed up keypairs were
+// unsealed, and the ids of the active keypairs that were not.
+func checkRecoveredKeypairs(ctx context.Context, mk []byte, backedUp []identity.ID,
+	keypairs []registry.ClaimedKeyPair) (int, []identity.ID) {
+
+	inBundle := make(map[identity.ID]bool, len(backedUp))
+	for _, id := range backedUp {
+		inBundle[id] = true
+	}
+
+	var matched int
+	var lost []identity.ID
+	for _, kp := range keypairs {
+		if kp.PrivateKey == nil {
+			continue
+		}
+
+		pk := kp.PrivateKey.Body
+		_, err := crypto.UnsealWithMasterKey(ctx, mk, *pk.Key.Value, *pk.PNonce)
+		switch {
+		case err == nil && inBundle[*kp.PublicKey.ID]:
+			matched++
+		case err != nil && !kp.Revoked():
+			lost = append(lost, *kp.PublicKey.ID)
+		}
+	}
+
+	return matched, lost
+}
+
+// listOwnKeypairs returns the current user's keypairs in every org they
+// belong to.
+func (e *Engine) listOwnKeypairs(ctx context.Context) ([]registry.ClaimedKeyPair, error) {
+	orgs, err := e.client.Orgs.List(ctx)
+	if err != nil {
+		log.Printf("Error retrieving orgs: %s", err)
+		return nil, err
+	}
+
+	var keypairs []registry.ClaimedKeyPair
+	for _, org := range orgs {
+		orgKeypairs, err := e.client.KeyPairs.List(ctx, org.ID)
+		if err != nil {
+			log.Printf("Error retrieving keypairs: %s", err)
+			return nil, err
+		}
+
+		keypairs = append(keypairs, orgKeypairs...)
+	}
+
+	return keypairs, nil
+}
@@ -0,0 +1,97 @@
+package logic
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto/shamir"
+)
+
+func TestRecoveryKey(t *testing.T) {
+	ownerID, err := identity.NewMutable(&primitive.User{Username: "jo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherID, err := identity.NewMutable(&primitive.User{Username: "al"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Now().UTC()
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	split, err := shamir.Split(key, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares := make([]apitypes.RecoveryShare, len(split))
+	for i, s := range split {
+		shares[i] = apitypes.RecoveryShare{OwnerID: &ownerID, Created: created, Share: base64.NewValue(s)}
+	}
+
+	bundle := &apitypes.RecoveryBundle{
+		OwnerID:   &ownerID,
+		Created:   created,
+		Scheme:    apitypes.ShamirRecovery,
+		Shares:    3,
+		Threshold: 2,
+	}
+
+	t.Run("passphrase", func(t *testing.T) {
+		b := &apitypes.RecoveryBundle{Scheme: apitypes.PassphraseRecovery}
+		got, err := recoveryKey(b, &apitypes.RecoveryRestoreRequest{Passphrase: "hunter22"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "hunter22" {
+			t.Errorf("got key %q, want the passphrase", got)
+		}
+
+		_, err = recoveryKey(b, &apitypes.RecoveryRestoreRequest{})
+		if err == nil {
+			t.Error("expected an error without a passphrase, got none")
+		}
+	})
+
+	t.Run("threshold shares", func(t *testing.T) {
+		req := &apitypes.RecoveryRestoreRequest{Shares: []apitypes.RecoveryShare{shares[2], shares[0]}}
+		got, err := recoveryKey(bundle, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, key) {
+			t.Errorf("got key %x, want %x", got, key)
+		}
+	})
+
+	t.Run("too few shares", func(t *testing.T) {
+		req := &apitypes.RecoveryRestoreRequest{Shares: shares[:1]}
+		if _, err := recoveryKey(bundle, req); err == nil {
+			t.Error("expected an error, got none")
+		}
+	})
+
+	t.Run("share from another backup", func(t *testing.T) {
+		other := shares[1]
+		other.Created = created.Add(time.Second)
+		req := &apitypes.RecoveryRestoreRequest{Shares: []apitypes.RecoveryShare{shares[0], other}}
+		if _, err := recoveryKey(bundle, req); err == nil {
+			t.Error("expected an error, got none")
+		}
+	})
+
+	t.Run("share of another user", func(t *testing.T) {
+		other := shares[1]
+		other.OwnerID = &otherID
+		req := &apitypes.RecoveryRestoreRequest{Shares: []apitypes.RecoveryShare{shares[0], other}}
+		if _, err := recoveryKey(bundle, req); err == nil {
+			t.Error("expected an error, got none")
+		}
+	})
+}
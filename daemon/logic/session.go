@@ -6,9 +6,11 @@ import (
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/registry"
 
 	"github.com/manifoldco/torus-cli/daemon/crypto"
+	"github.com/manifoldco/torus-cli/daemon/observer"
 	"github.com/manifoldco/torus-cli/daemon/session"
 )
 
@@ -20,7 +22,12 @@ type Session struct {
 
 // Login attempts to create a valid auth token to authorize http requests made
 // against the registry.
-func (s *Session) Login(ctx context.Context, creds apitypes.LoginCredential) error {
+//
+// If enabled in the daemon's config, a machine logging in to an org for
+// which it has no keypairs generates them.
+func (s *Session) Login(ctx context.Context, notifier *observer.Notifier,
+	creds apitypes.LoginCredential) error {
+
 	if !creds.Valid() {
 		return &apitypes.Error{
 			Type: apitypes.BadRequestError,
@@ -50,7 +57,54 @@ func (s *Session) Login(ctx context.Context, creds apitypes.LoginCredential) err
 		s.engine.db.Set(self.Auth)
 	}
 
-	return s.engine.session.Set(self.Type, self.Identity, self.Auth, creds.Passphrase(), authToken)
+	err = s.engine.session.Set(self.Type, self.Identity, self.Auth, creds.Passphrase(), authToken)
+	if err != nil {
+		return err
+	}
+
+	if self.Type == apitypes.MachineSession && s.engine.config.MachineGenerateKeypairs {
+		machine := self.Identity.(*envelope.Machine)
+		err = s.generateMissingKeypairs(ctx, notifier, machine)
+		if err != nil {
+			// The login itself succeeded; the missing keypairs are still
+			// reported in the worklog.
+			log.Printf("Could not generate keypairs for machine %s: %s",
+				machine.Body.Name, err)
+		}
+	}
+
+	// Token revocations scheduled by this identity in an earlier session,
+	// or before the daemon restarted, are picked up again.
+	s.engine.Machine.startRevoker()
+
+	return nil
+}
+
+// generateMissingKeypairs generates keypairs for the logged in machine if it
+// is missing either of them for its org.
+func (s *Session) generateMissingKeypairs(ctx context.Context,
+	notifier *observer.Notifier, machine *envelope.Machine) error {
+
+	orgID := machine.Body.OrgID
+	encKP, sigKP, err := fetchRegistryKeyPairs(ctx, s.engine.client, orgID)
+	if err != nil {
+		return err
+	}
+	if encKP != nil && sigKP != nil {
+		return nil
+	}
+
+	n := notifier.Notifier(1)
+
+	log.Printf("Generating missing keypairs for machine %s in org %s",
+		machine.Body.Name, orgID)
+	err = s.engine.GenerateKeypairs(ctx, n, orgID)
+	if err != nil {
+		return err
+	}
+
+	n.Notify(observer.Progress, "Machine keypairs generated", true)
+	return nil
 }
 
 // Logout destroys the current session if it exists, otherwise, it returns an
@@ -0,0 +1,137 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/identity"
+)
+
+// tokenRevocationsBucket is the db bucket holding scheduled token revocations,
+// keyed by token id.
+const tokenRevocationsBucket = "token_revocations"
+
+// revocationCheckInterval is how often the daemon looks for scheduled token
+// revocations that are due.
+const revocationCheckInterval = time.Minute
+
+// scheduledRevocation is a machine token to revoke once its time has come, on
+// behalf of the identity that scheduled it.
+type scheduledRevocation struct {
+	MachineID *identity.ID `json:"machine_id"`
+	TokenID   *identity.ID `json:"token_id"`
+	OwnerID   *identity.ID `json:"owner_id"`
+	RevokeAt  time.Time    `json:"revoke_at"`
+}
+
+// ScheduleTokenRevocation records that the machine token is to be revoked at
+// the given time. Scheduled revocations are kept in the db, so they survive a
+// restart of the daemon, and are made while the identity that scheduled them
+// is logged in.
+func (m *Machine) ScheduleTokenRevocation(machineID, tokenID *identity.ID, at time.Time) error {
+	rev := scheduledRevocation{
+		MachineID: machineID,
+		TokenID:   tokenID,
+		OwnerID:   m.engine.session.AuthID(),
+		RevokeAt:  at.UTC(),
+	}
+
+	err := m.engine.db.Put(tokenRevocationsBucket, tokenID.String(), &rev)
+	if err != nil {
+		return err
+	}
+
+	m.startRevoker()
+	return nil
+}
+
+// startRevoker starts making scheduled revocations in the background, unless
+// it is already running.
+func (m *Machine) startRevoker() {
+	e := m.engine
+
+	e.revokerMutex.Lock()
+	defer e.revokerMutex.Unlock()
+
+	if e.revoking {
+		return
+	}
+
+	e.revoking = true
+	go m.revokeScheduled()
+}
+
+// revokeScheduled makes the scheduled revocations as they fall due. It stops
+// once none are left.
+func (m *Machine) revokeScheduled() {
+	e := m.engine
+	for {
+		pending, err := m.revokeDue(context.Background(), time.Now())
+		if err != nil {
+			log.Printf("Error making scheduled token revocations: %s", err)
+		}
+
+		if pending == 0 {
+			e.revokerMutex.Lock()
+			e.revoking = false
+			e.revokerMutex.Unlock()
+			return
+		}
+
+		time.Sleep(revocationCheckInterval)
+	}
+}
+
+// revokeDue revokes the tokens that were scheduled by the logged in identity
+// to be revoked at or before now. It returns how many revocations, by anyone,
+// are left pending.
+//
+// A token that has already been destroyed is considered revoked. Revocations
+// that fail are retried on the next check.
+func (m *Machine) revokeDue(ctx context.Context, now time.Time) (int, error) {
+	e := m.engine
+
+	var all []scheduledRevocation
+	err := e.db.ForEach(tokenRevocationsBucket, func(key string, value []byte) error {
+		var rev scheduledRevocation
+		if err := json.Unmarshal(value, &rev); err != nil {
+			return err
+		}
+
+		all = append(all, rev)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	authID := e.session.AuthID()
+	if authID == nil || !e.session.HasToken() {
+		return len(all), nil
+	}
+
+	pending := len(all)
+	for _, rev := range all {
+		if rev.OwnerID == nil || *rev.OwnerID != *authID || rev.RevokeAt.After(now) {
+			continue
+		}
+
+		err = e.client.Machines.DestroyToken(ctx, rev.MachineID, rev.TokenID)
+		if err != nil && !apitypes.IsNotFoundError(err) {
+			log.Printf("Could not revoke machine token %s: %s", rev.TokenID, err)
+			continue
+		}
+
+		log.Printf("Revoked machine token %s, as scheduled", rev.TokenID)
+		err = e.db.Delete(tokenRevocationsBucket, rev.TokenID.String())
+		if err != nil {
+			return pending, err
+		}
+		pending--
+	}
+
+	return pending, nil
+}
@@ -3,6 +3,7 @@ package logic
 import (
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
 )
 
 // PlaintextCredentialEnvelope is an unencrypted credential object
@@ -20,4 +21,20 @@ type PlaintextCredential struct {
 	ProjectID *identity.ID     `json:"project_id"`
 	Value     string           `json:"value"`
 	State     *string          `json:"state"`
+
+	// Metadata is optional. On write, the fields it doesn't provide are
+	// carried forward from the metadata of the previous version of the
+	// credential.
+	Metadata *primitive.CredentialMetadata `json:"metadata,omitempty"`
+
+	// ClearMetadata is set on write to discard the metadata of the previous
+	// version of the credential, rather than carrying it forward.
+	ClearMetadata bool `json:"clear_metadata,omitempty"`
+
+	// References holds the paths followed, in order, when resolving a
+	// reference value.
+	References []string `json:"references,omitempty"`
+
+	// ReferenceError explains why a reference value could not be resolved.
+	ReferenceError string `json:"reference_error,omitempty"`
 }
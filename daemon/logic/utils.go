@@ -659,3 +659,31 @@ func getKeyringMembers(ctx context.Context, client *registry.Client,
 
 	return members, nil
 }
+
+// mergeCredentialMetadata returns the metadata to store with a new version of
+// a credential. Each field not given in next is taken from previous. Tags are
+// replaced as a whole when any are given.
+func mergeCredentialMetadata(previous, next *primitive.CredentialMetadata) *primitive.CredentialMetadata {
+	if previous == nil {
+		return next
+	}
+	if next == nil {
+		return previous
+	}
+
+	merged := *next
+	if merged.Description == "" {
+		merged.Description = previous.Description
+	}
+	if merged.Owner == "" {
+		merged.Owner = previous.Owner
+	}
+	if len(merged.Tags) == 0 {
+		merged.Tags = previous.Tags
+	}
+	if merged.Expires == nil {
+		merged.Expires = previous.Expires
+	}
+
+	return &merged
+}
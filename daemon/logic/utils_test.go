@@ -0,0 +1,56 @@
+package logic
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestMergeCredentialMetadata(t *testing.T) {
+	expires := time.Date(2017, 6, 30, 0, 0, 0, 0, time.UTC)
+	later := expires.AddDate(1, 0, 0)
+
+	previous := &primitive.CredentialMetadata{
+		Description: "primary database",
+		Owner:       "ops",
+		Tags:        []string{"db", "prod"},
+		Expires:     &expires,
+	}
+
+	tcs := []struct {
+		name     string
+		previous *primitive.CredentialMetadata
+		next     *primitive.CredentialMetadata
+		want     *primitive.CredentialMetadata
+	}{
+		{"no metadata", nil, nil, nil},
+		{"first metadata", nil, &primitive.CredentialMetadata{Owner: "ops"},
+			&primitive.CredentialMetadata{Owner: "ops"}},
+		{"none given", previous, nil, previous},
+		{"tags given", previous, &primitive.CredentialMetadata{Tags: []string{"foo"}},
+			&primitive.CredentialMetadata{
+				Description: "primary database",
+				Owner:       "ops",
+				Tags:        []string{"foo"},
+				Expires:     &expires,
+			}},
+		{"expiry and owner given", previous, &primitive.CredentialMetadata{Owner: "dev", Expires: &later},
+			&primitive.CredentialMetadata{
+				Description: "primary database",
+				Owner:       "dev",
+				Tags:        []string{"db", "prod"},
+				Expires:     &later,
+			}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := mergeCredentialMetadata(tc.previous, tc.next)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/base64"
@@ -38,6 +39,11 @@ func newWorklog(e *Engine) Worklog {
 			apitypes.MissingKeypairsWorklogType: &missingKeypairsHandler{engine: e},
 			apitypes.InviteApproveWorklogType:   &inviteApproveHandler{engine: e},
 			apitypes.KeyringMembersWorklogType:  &keyringMembersHandler{engine: e},
+			apitypes.SecretExpiryWorklogType:    &secretExpiryHandler{engine: e},
+			apitypes.SecretAgeWorklogType:       &secretAgeHandler{engine: e},
+			apitypes.StaleMachineWorklogType:    &staleMachineHandler{engine: e},
+			apitypes.ClaimTreeWorklogType:       &claimTreeHandler{engine: e},
+			apitypes.ExpiredInviteWorklogType:   &expiredInviteHandler{engine: e},
 		},
 	}
 
@@ -144,9 +150,7 @@ func (h *secretRotateHandler) list(ctx context.Context, org *envelope.Org) ([]ap
 
 	cgs := newCredentialGraphSet()
 	for _, project := range projects {
-		graphs, err := h.engine.client.CredentialGraph.Search(ctx,
-			"/"+org.Body.Name+"/"+project.Body.Name+"/*/*/*/*",
-			h.engine.session.AuthID())
+		graphs, err := projectCredentialGraphs(ctx, h.engine, org, &project)
 		if err != nil {
 			return nil, err
 		}
@@ -185,6 +189,162 @@ func (h *secretRotateHandler) resolve(ctx context.Context, n *observer.Notifier,
 	}, nil
 }
 
+// projectCredentialGraphs returns all of the credential graphs the current
+// user can access within the given project.
+func projectCredentialGraphs(ctx context.Context, e *Engine, org *envelope.Org,
+	project *envelope.Project) ([]registry.CredentialGraph, error) {
+	return e.client.CredentialGraph.Search(ctx,
+		"/"+org.Body.Name+"/"+project.Body.Name+"/*/*/*/*", e.session.AuthID())
+}
+
+type secretExpiryHandler struct {
+	engine *Engine
+}
+
+func (secretExpiryHandler) resolveErr() string {
+	// Like rotation, replacing an expiring secret must be done manually.
+	return "Error replacing expiring secret"
+}
+
+func (h *secretExpiryHandler) list(ctx context.Context, org *envelope.Org) ([]apitypes.WorklogItem, error) {
+	projects, err := h.engine.client.Projects.List(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var items []apitypes.WorklogItem
+	for _, project := range projects {
+		thresholds := h.engine.config.Rotation.For(org.Body.Name, project.Body.Name)
+
+		graphs, err := projectCredentialGraphs(ctx, h.engine, org, &project)
+		if err != nil {
+			return nil, err
+		}
+
+		cgs := newCredentialGraphSet()
+		err = cgs.Add(graphs...)
+		if err != nil {
+			return nil, err
+		}
+
+		expiring, err := cgs.Expiring(now.Add(thresholds.ExpiryWarning))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cred := range expiring {
+			expires := cred.Metadata().Expires
+			summary := "This secret expires on %s. Its value should be changed before then."
+			if !expires.After(now) {
+				summary = "This secret expired on %s. Its value should be changed."
+			}
+
+			item := apitypes.WorklogItem{
+				Subject: cred.PathExp().String() + "/" + cred.Name(),
+				Summary: fmt.Sprintf(summary, expires.Format("2006-01-02")),
+			}
+			item.CreateID(apitypes.SecretExpiryWorklogType)
+
+			items = append(items, item)
+		}
+	}
+
+	sort.Sort(worklogItemSorter(items))
+	return items, nil
+}
+
+func (h *secretExpiryHandler) resolve(ctx context.Context, n *observer.Notifier,
+	orgID *identity.ID, item *apitypes.WorklogItem) (*apitypes.WorklogResult, error) {
+	return &apitypes.WorklogResult{
+		ID:    item.ID,
+		State: apitypes.ManualWorklogResult,
+		Message: "Please set a new value and expiry date (--expires) for the secret at " +
+			item.Subject,
+	}, nil
+}
+
+type secretAgeHandler struct {
+	engine *Engine
+}
+
+func (secretAgeHandler) resolveErr() string {
+	// Like rotation, replacing an old secret must be done manually.
+	return "Error replacing old secret"
+}
+
+func (h *secretAgeHandler) list(ctx context.Context, org *envelope.Org) ([]apitypes.WorklogItem, error) {
+	projects, err := h.engine.client.Projects.List(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var items []apitypes.WorklogItem
+	for _, project := range projects {
+		thresholds := h.engine.config.Rotation.For(org.Body.Name, project.Body.Name)
+		if thresholds.MaxAge == 0 {
+			continue
+		}
+
+		graphs, err := projectCredentialGraphs(ctx, h.engine, org, &project)
+		if err != nil {
+			return nil, err
+		}
+
+		cgs := newCredentialGraphSet()
+		err = cgs.Add(graphs...)
+		if err != nil {
+			return nil, err
+		}
+
+		old, err := cgs.CreatedBefore(now.Add(-thresholds.MaxAge))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cred := range old {
+			age := now.Sub(*cred.Created())
+			summary := fmt.Sprintf("This secret's value is %d days old, past the maximum "+
+				"age of %d days for project %s. Its value should be changed.",
+				days(age), days(thresholds.MaxAge), project.Body.Name)
+
+			item := apitypes.WorklogItem{
+				Subject: cred.PathExp().String() + "/" + cred.Name(),
+				Summary: summary,
+			}
+			item.CreateID(apitypes.SecretAgeWorklogType)
+
+			items = append(items, item)
+		}
+	}
+
+	sort.Sort(worklogItemSorter(items))
+	return items, nil
+}
+
+func (h *secretAgeHandler) resolve(ctx context.Context, n *observer.Notifier,
+	orgID *identity.ID, item *apitypes.WorklogItem) (*apitypes.WorklogResult, error) {
+	return &apitypes.WorklogResult{
+		ID:      item.ID,
+		State:   apitypes.ManualWorklogResult,
+		Message: "Please set a new value for the secret at " + item.Subject,
+	}, nil
+}
+
+// days returns the number of whole days in the duration.
+func days(d time.Duration) int {
+	return int(d / (24 * time.Hour))
+}
+
+// worklogItemSorter implements sort.Interface, for returning worklog items
+// in a consistent order.
+type worklogItemSorter []apitypes.WorklogItem
+
+func (w worklogItemSorter) Len() int           { return len(w) }
+func (w worklogItemSorter) Swap(i, j int)      { w[i], w[j] = w[j], w[i] }
+func (w worklogItemSorter) Less(i, j int) bool { return w[i].Subject < w[j].Subject }
+
 type missingKeypairsHandler struct {
 	engine *Engine
 }
@@ -287,6 +447,80 @@ func (h *inviteApproveHandler) resolve(ctx context.Context, n *observer.Notifier
 	}, nil
 }
 
+type expiredInviteHandler struct {
+	engine *Engine
+}
+
+func (expiredInviteHandler) resolveErr() string {
+	return "Error revoking invite"
+}
+
+func (h *expiredInviteHandler) list(ctx context.Context, org *envelope.Org) ([]apitypes.WorklogItem, error) {
+	expireAfter := h.engine.config.InviteExpireAfter
+	if expireAfter <= 0 {
+		return nil, nil
+	}
+
+	states := []string{primitive.OrgInvitePendingState, primitive.OrgInviteAssociatedState}
+	invites, err := h.engine.client.OrgInvites.List(ctx, org.ID, states, "")
+	if err != nil {
+		// As with approving invites, the user may not have access to them.
+		if apitypes.IsUnauthorizedError(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	cutoff := time.Now().UTC().Add(-expireAfter)
+
+	var items []apitypes.WorklogItem
+	for _, invite := range invites {
+		if !inviteExpired(invite.Body, cutoff) {
+			continue
+		}
+
+		summary := fmt.Sprintf("The invite for %s to org %s was sent on %s, and has not been accepted.",
+			invite.Body.Email, org.Body.Name, invite.Body.Created.Format("2006-01-02"))
+		item := apitypes.WorklogItem{
+			Subject:   invite.Body.Email,
+			Summary:   summary,
+			SubjectID: invite.ID,
+		}
+		item.CreateID(apitypes.ExpiredInviteWorklogType)
+
+		items = append(items, item)
+	}
+
+	sort.Sort(worklogItemSorter(items))
+	return items, nil
+}
+
+// inviteExpired returns whether the invite was sent before the cutoff and is
+// still waiting to be accepted.
+func inviteExpired(invite *primitive.OrgInvite, cutoff time.Time) bool {
+	if invite.State != primitive.OrgInvitePendingState &&
+		invite.State != primitive.OrgInviteAssociatedState {
+		return false
+	}
+
+	return invite.Created != nil && invite.Created.Before(cutoff)
+}
+
+func (h *expiredInviteHandler) resolve(ctx context.Context, n *observer.Notifier,
+	orgID *identity.ID, item *apitypes.WorklogItem) (*apitypes.WorklogResult, error) {
+	err := h.engine.client.OrgInvites.Revoke(ctx, item.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apitypes.WorklogResult{
+		ID:      item.ID,
+		State:   apitypes.SuccessWorklogResult,
+		Message: "Expired invite for " + item.Subject + " revoked.",
+	}, nil
+}
+
 type keyringMembersHandler struct {
 	engine *Engine
 }
@@ -342,7 +576,8 @@ func (h *keyringMembersHandler) list(ctx context.Context, org *envelope.Org) ([]
 		return nil, err
 	}
 
-	missing := make(map[string]apitypes.WorklogItem)
+	// The members missing from each keyring path, across its active versions.
+	missing := make(map[string]map[identity.ID]bool)
 	for _, graph := range graphs {
 		for _, member := range members {
 			m, _, err := graph.FindMember(&member)
@@ -356,15 +591,9 @@ func (h *keyringMembersHandler) list(ctx context.Context, org *envelope.Org) ([]
 
 			path := graph.GetKeyring().PathExp().String()
 			if _, ok := missing[path]; !ok {
-				item := apitypes.WorklogItem{
-					Subject: path,
-					Summary: "One or more users are missing access to these secrets.",
-				}
-				item.CreateID(apitypes.KeyringMembersWorklogType)
-				missing[path] = item
+				missing[path] = make(map[identity.ID]bool)
 			}
-			// at least one member is missing. we can stop looking here.
-			break
+			missing[path][member] = true
 		}
 	}
 
@@ -377,7 +606,22 @@ func (h *keyringMembersHandler) list(ctx context.Context, org *envelope.Org) ([]
 
 	items := make([]apitypes.WorklogItem, 0, len(missing))
 	for _, k := range keys {
-		items = append(items, missing[k])
+		item := apitypes.WorklogItem{
+			Subject: k,
+			Summary: "One or more users are missing access to these secrets.",
+		}
+		item.CreateID(apitypes.KeyringMembersWorklogType)
+
+		// When only one member is missing, say who, so the item can be
+		// resolved on their behalf alone.
+		if len(missing[k]) == 1 {
+			for member := range missing[k] {
+				id := member
+				item.SubjectID = &id
+			}
+		}
+
+		items = append(items, item)
 	}
 
 	return items, nil
@@ -505,3 +749,174 @@ func (h *keyringMembersHandler) resolve(ctx context.Context, n *observer.Notifie
 		Message: "Missing user(s) added to keyring.",
 	}, nil
 }
+
+type staleMachineHandler struct {
+	engine *Engine
+}
+
+func (staleMachineHandler) resolveErr() string {
+	return "Error destroying machine"
+}
+
+func (h *staleMachineHandler) list(ctx context.Context, org *envelope.Org) ([]apitypes.WorklogItem, error) {
+	state := primitive.MachineActiveState
+	machines, err := h.engine.client.Machines.List(ctx, org.ID, &state, nil, nil)
+	if err != nil {
+		// Like invites, the user may not have access to the org's machines.
+		if apitypes.IsUnauthorizedError(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	teams, err := h.engine.client.Teams.GetByOrg(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[identity.ID]bool)
+	for _, t := range teams {
+		if t.Body.TeamType == primitive.MachineTeamType {
+			roles[*t.ID] = true
+		}
+	}
+
+	var cutoff *time.Time
+	if staleAfter := h.engine.config.MachineStaleAfter; staleAfter > 0 {
+		t := time.Now().UTC().Add(-staleAfter)
+		cutoff = &t
+	}
+
+	var items []apitypes.WorklogItem
+	for _, machine := range machines {
+		reason := staleMachineReason(machine, roles, cutoff)
+		if reason == "" {
+			continue
+		}
+
+		item := apitypes.WorklogItem{
+			Subject:   machine.Machine.Body.Name,
+			Summary:   "This machine " + reason + ". It may no longer be needed.",
+			SubjectID: machine.Machine.ID,
+		}
+		item.CreateID(apitypes.StaleMachineWorklogType)
+
+		items = append(items, item)
+	}
+
+	sort.Sort(worklogItemSorter(items))
+	return items, nil
+}
+
+// staleMachineReason returns why the machine is considered stale, or an empty
+// string if it is not. A machine is stale if it does not belong to any of the
+// given machine roles, if it has no active tokens, or if none of its active
+// tokens have been used since the cutoff. Tokens that have never logged in are
+// judged by when they were created. Without a cutoff, token use isn't checked.
+func staleMachineReason(machine *apitypes.MachineSegment, roles map[identity.ID]bool,
+	cutoff *time.Time) string {
+
+	inRole := false
+	for _, m := range machine.Memberships {
+		if roles[*m.Body.TeamID] {
+			inRole = true
+			break
+		}
+	}
+	if !inRole {
+		return "does not belong to any machine role"
+	}
+
+	var lastUsed *time.Time
+	for _, t := range machine.Tokens {
+		if t.Token.Body.State != primitive.MachineTokenActiveState {
+			continue
+		}
+
+		used := t.Token.Body.Created
+		if t.LastLogin != nil {
+			used = *t.LastLogin
+		}
+		if lastUsed == nil || used.After(*lastUsed) {
+			lastUsed = &used
+		}
+	}
+
+	if lastUsed == nil {
+		return "has no active tokens"
+	}
+
+	if cutoff != nil && lastUsed.Before(*cutoff) {
+		return fmt.Sprintf("has not logged in since %s", lastUsed.Format("2006-01-02"))
+	}
+
+	return ""
+}
+
+// resolve destroys the machine. Its keyring memberships are removed by the
+// next keyring members pass.
+func (h *staleMachineHandler) resolve(ctx context.Context, n *observer.Notifier,
+	orgID *identity.ID, item *apitypes.WorklogItem) (*apitypes.WorklogResult, error) {
+	err := h.engine.client.Machines.Destroy(ctx, item.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apitypes.WorklogResult{
+		ID:      item.ID,
+		State:   apitypes.SuccessWorklogResult,
+		Message: "Machine " + item.Subject + " destroyed.",
+	}, nil
+}
+
+type claimTreeHandler struct {
+	engine *Engine
+}
+
+func (claimTreeHandler) resolveErr() string {
+	// This won't happen, because untrusted keys must be replaced by their
+	// owner.
+	return "Error verifying keys"
+}
+
+func (h *claimTreeHandler) list(ctx context.Context, org *envelope.Org) ([]apitypes.WorklogItem, error) {
+	owners, err := h.engine.VerifyClaimTree(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []apitypes.WorklogItem
+	for _, owner := range owners {
+		if owner.Trust != apitypes.UntrustedKeys {
+			continue
+		}
+
+		problems := owner.Problems()
+		summary := "The keys of " + owner.Name + " could not be verified: " + problems[0]
+		if len(problems) > 1 {
+			summary += fmt.Sprintf(" (and %d more problems)", len(problems)-1)
+		}
+
+		item := apitypes.WorklogItem{
+			Subject:   owner.Name,
+			Summary:   summary + ".",
+			SubjectID: owner.OwnerID,
+		}
+		item.CreateID(apitypes.ClaimTreeWorklogType)
+
+		items = append(items, item)
+	}
+
+	sort.Sort(worklogItemSorter(items))
+	return items, nil
+}
+
+func (h *claimTreeHandler) resolve(ctx context.Context, n *observer.Notifier,
+	orgID *identity.ID, item *apitypes.WorklogItem) (*apitypes.WorklogResult, error) {
+	return &apitypes.WorklogResult{
+		ID:      item.ID,
+		State:   apitypes.ManualWorklogResult,
+		Message: "Keys for " + item.Subject + " must be replaced by their owner with 'torus keypairs rotate'.",
+	}, nil
+}
@@ -0,0 +1,107 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestStaleMachineReason(t *testing.T) {
+	now := time.Now().UTC()
+	cutoff := now.Add(-30 * 24 * time.Hour)
+	old := now.Add(-60 * 24 * time.Hour)
+
+	roleID, err := identity.NewMutable(&primitive.Team{Name: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	goneID, err := identity.NewMutable(&primitive.Team{Name: "gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := map[identity.ID]bool{roleID: true}
+
+	machine := func(teamID identity.ID, tokens ...apitypes.MachineTokenSegment) *apitypes.MachineSegment {
+		return &apitypes.MachineSegment{
+			Memberships: []envelope.Membership{
+				{Body: &primitive.Membership{TeamID: &teamID}},
+			},
+			Tokens: tokens,
+		}
+	}
+	token := func(state string, created time.Time, lastLogin *time.Time) apitypes.MachineTokenSegment {
+		return apitypes.MachineTokenSegment{
+			Token: &envelope.MachineToken{Body: &primitive.MachineToken{
+				State:   state,
+				Created: created,
+			}},
+			LastLogin: lastLogin,
+		}
+	}
+
+	active := primitive.MachineTokenActiveState
+	destroyed := primitive.MachineTokenDestroyedState
+
+	tcs := []struct {
+		name    string
+		machine *apitypes.MachineSegment
+		cutoff  *time.Time
+		stale   bool
+	}{
+		{"recent login", machine(roleID, token(active, old, &now)), &cutoff, false},
+		{"old login", machine(roleID, token(active, old, &old)), &cutoff, true},
+		{"never logged in, new", machine(roleID, token(active, now, nil)), &cutoff, false},
+		{"never logged in, old", machine(roleID, token(active, old, nil)), &cutoff, true},
+		{"old login, check disabled", machine(roleID, token(active, old, &old)), nil, false},
+		{"role removed", machine(goneID, token(active, now, &now)), &cutoff, true},
+		{"role removed, check disabled", machine(goneID, token(active, now, &now)), nil, true},
+		{"no active tokens", machine(roleID, token(destroyed, now, &now)), &cutoff, true},
+		{"no active tokens, check disabled", machine(roleID, token(destroyed, now, &now)), nil, true},
+		{
+			"one recent token",
+			machine(roleID, token(active, old, &old), token(active, old, &now)),
+			&cutoff, false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := staleMachineReason(tc.machine, roles, tc.cutoff)
+			if stale := reason != ""; stale != tc.stale {
+				t.Errorf("stale = %t (%q), want %t", stale, reason, tc.stale)
+			}
+		})
+	}
+}
+
+func TestInviteExpired(t *testing.T) {
+	now := time.Now().UTC()
+	cutoff := now.Add(-14 * 24 * time.Hour)
+	old := now.Add(-30 * 24 * time.Hour)
+
+	tcs := []struct {
+		name    string
+		state   string
+		created *time.Time
+		expired bool
+	}{
+		{"recent pending", primitive.OrgInvitePendingState, &now, false},
+		{"old pending", primitive.OrgInvitePendingState, &old, true},
+		{"old associated", primitive.OrgInviteAssociatedState, &old, true},
+		{"old accepted", primitive.OrgInviteAcceptedState, &old, false},
+		{"no creation date", primitive.OrgInvitePendingState, nil, false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			invite := &primitive.OrgInvite{State: tc.state, Created: tc.created}
+			if expired := inviteExpired(invite, cutoff); expired != tc.expired {
+				t.Errorf("expired = %t, want %t", expired, tc.expired)
+			}
+		})
+	}
+}
@@ -109,8 +109,13 @@ func (t *transaction) start() {
 	}()
 }
 
-// Notifier creates a child notifier to this Notifier
+// Notifier creates a child notifier to this Notifier. The child of a nil
+// Notifier is nil.
 func (n *Notifier) Notifier(total uint) *Notifier {
+	if n == nil {
+		return nil
+	}
+
 	notifier := &Notifier{
 		total:          total,
 		current:        0,
@@ -127,7 +132,14 @@ func (n *Notifier) Notifier(total uint) *Notifier {
 
 // Notify publishes an event to all SSE observers. This function panics when it
 // is called more often than it is supposed to have been called.
+//
+// Notify on a nil Notifier does nothing, for work done outside of a request,
+// such as logging in when the daemon starts.
 func (n *Notifier) Notify(eventType EventType, message string, increment bool) {
+	if n == nil {
+		return
+	}
+
 	notif := &notification{
 		Type:      eventType,
 		Message:   message,
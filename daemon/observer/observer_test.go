@@ -97,6 +97,18 @@ func TestNotifier(t *testing.T) {
 
 		parent.Notify(Progress, "haha", true)
 	})
+
+	t.Run("nil notifier does nothing", func(t *testing.T) {
+		var parent *Notifier
+
+		child := parent.Notifier(2)
+		if child != nil {
+			t.Errorf("Expected nil child notifier, got %v", child)
+		}
+
+		child.Notify(Progress, "helo", true)
+		parent.Notify(Progress, "helo", true)
+	})
 }
 
 type CloseNotifyResponseRecorder struct {
@@ -34,11 +34,13 @@ func credentialsGetRoute(engine *logic.Engine, o *observer.Observer) http.Handle
 			return
 		}
 
+		verify := q.Get("verify") == "true"
+
 		var creds []logic.PlaintextCredentialEnvelope
 		if path != "" {
-			creds, err = engine.RetrieveCredentials(ctx, n, &path, nil)
+			creds, err = engine.RetrieveCredentials(ctx, n, &path, nil, verify)
 		} else {
-			creds, err = engine.RetrieveCredentials(ctx, n, nil, &pathexp)
+			creds, err = engine.RetrieveCredentials(ctx, n, nil, &pathexp, verify)
 		}
 		if err != nil {
 			// Rely on logs inside engine for debugging
@@ -95,3 +95,127 @@ func keypairsRevokeRoute(engine *logic.Engine, o *observer.Observer) http.Handle
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+func keypairsRotateRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		dec := json.NewDecoder(r.Body)
+		rotReq := keyPairRequest{}
+		err := dec.Decode(&rotReq)
+		if err != nil {
+			encodeResponseErr(w, err)
+			return
+		}
+
+		if rotReq.OrgID == nil {
+			encodeResponseErr(w, &apitypes.Error{
+				Type: apitypes.BadRequestError,
+				Err:  []string{"missing or invalid OrgID provided"},
+			})
+			return
+		}
+
+		n, err := o.Notifier(ctx, 0)
+		if err != nil {
+			log.Printf("Error creating Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		err = engine.RotateKeypairs(ctx, n, rotReq.OrgID)
+		if err != nil {
+			// Rely on engine for debug logging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func keypairsVerifyRoute(engine *logic.Engine) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		orgID, err := identity.DecodeFromString(r.URL.Query().Get("org_id"))
+		if err != nil {
+			encodeResponseErr(w, &apitypes.Error{
+				Type: apitypes.BadRequestError,
+				Err:  []string{"missing or invalid org_id provided"},
+			})
+			return
+		}
+
+		owners, err := engine.VerifyClaimTree(ctx, &orgID)
+		if err != nil {
+			log.Printf("Error verifying claim tree: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(owners)
+		if err != nil {
+			log.Printf("Error encoding claim tree verification: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
+func keypairsBackupRoute(engine *logic.Engine) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		dec := json.NewDecoder(r.Body)
+		req := apitypes.RecoveryBackupRequest{}
+		err := dec.Decode(&req)
+		if err != nil {
+			encodeResponseErr(w, err)
+			return
+		}
+
+		backup, err := engine.BackupMasterKey(ctx, &req)
+		if err != nil {
+			// Rely on engine for debug logging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(backup)
+		if err != nil {
+			log.Printf("Error encoding recovery bundle: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
+func keypairsRestoreRoute(engine *logic.Engine) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		dec := json.NewDecoder(r.Body)
+		req := apitypes.RecoveryRestoreRequest{}
+		err := dec.Decode(&req)
+		if err != nil {
+			encodeResponseErr(w, err)
+			return
+		}
+
+		err = engine.RestoreMasterKey(ctx, &req)
+		if err != nil {
+			// Rely on engine for debug logging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/go-zoo/bone"
+
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
@@ -86,6 +88,229 @@ func machinesCreateRoute(client *registry.Client, session session.Session,
 	}
 }
 
+func machineTokensCreateRoute(client *registry.Client, engine *logic.Engine,
+	o *observer.Observer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		machineID, err := identity.DecodeFromString(bone.GetValue(r, "id"))
+		if err != nil {
+			log.Printf("Could not create machine token; invalid id: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		dec := json.NewDecoder(r.Body)
+		req := apitypes.MachineTokensCreateRequest{}
+		err = dec.Decode(&req)
+		if err != nil {
+			log.Printf("Error decoding request: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n, err := o.Notifier(ctx, 3)
+		if err != nil {
+			log.Printf("Error creating Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		machineSegment, err := client.Machines.Get(ctx, &machineID)
+		if err != nil {
+			log.Printf("Error retrieving machine %s: %s", machineID, err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		machine := machineSegment.Machine
+		if machine.Body.State != primitive.MachineActiveState {
+			encodeResponseErr(w, &apitypes.Error{
+				StatusCode: http.StatusBadRequest,
+				Type:       apitypes.BadRequestError,
+				Err:        []string{"Tokens can only be created for active machines"},
+			})
+			return
+		}
+
+		msg := fmt.Sprintf("Creating token for machine \"%s\"", machine.Body.Name)
+		n.Notify(observer.Progress, msg, true)
+
+		token, err := engine.Machine.CreateToken(ctx, n, machine, req.Secret)
+		if err != nil {
+			log.Printf("Error creating machine token: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Progress, "Uploading token keypairs", true)
+
+		segment, err := client.Machines.CreateToken(ctx, &machineID, token)
+		if err != nil {
+			log.Printf("Error creating machine token with registry: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		err = engine.Machine.EncodeToken(ctx, n, token.Token)
+		if err != nil {
+			log.Printf("Error encoding token into keyrings: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Finished, "Machine token created", true)
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(segment)
+		if err != nil {
+			log.Printf("Error encoding MachineTokenSegment: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
+func machineTokenRevocationRoute(client *registry.Client, engine *logic.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		machineID, err := identity.DecodeFromString(bone.GetValue(r, "id"))
+		if err != nil {
+			log.Printf("Could not schedule token revocation; invalid id: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		tokenID, err := identity.DecodeFromString(bone.GetValue(r, "token_id"))
+		if err != nil {
+			log.Printf("Could not schedule token revocation; invalid token id: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		dec := json.NewDecoder(r.Body)
+		req := apitypes.MachineTokenRevocationRequest{}
+		err = dec.Decode(&req)
+		if err != nil {
+			log.Printf("Error decoding request: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		machineSegment, err := client.Machines.Get(ctx, &machineID)
+		if err != nil {
+			log.Printf("Error retrieving machine %s: %s", machineID, err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		found := false
+		for _, t := range machineSegment.Tokens {
+			if *t.Token.ID == tokenID && t.Token.Body.State == primitive.MachineTokenActiveState {
+				found = true
+			}
+		}
+		if !found {
+			encodeResponseErr(w, &apitypes.Error{
+				StatusCode: http.StatusNotFound,
+				Type:       apitypes.NotFoundError,
+				Err:        []string{"Active token not found for machine"},
+			})
+			return
+		}
+
+		err = engine.Machine.ScheduleTokenRevocation(&machineID, &tokenID, req.RevokeAt)
+		if err != nil {
+			log.Printf("Error scheduling revocation of token %s: %s", tokenID, err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func machinesEnrollRoute(client *registry.Client, engine *logic.Engine,
+	o *observer.Observer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		dec := json.NewDecoder(r.Body)
+		req := apitypes.MachineEnrollRequest{}
+		err := dec.Decode(&req)
+		if err != nil {
+			log.Printf("Error decoding request: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n, err := o.Notifier(ctx, 3)
+		if err != nil {
+			log.Printf("Error creating Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Progress, "Checking enrollment code", true)
+
+		code, err := client.Machines.GetEnrollmentCode(ctx, req.Code)
+		if err != nil {
+			log.Printf("Error retrieving enrollment code: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		if code.Uses < 1 || time.Now().After(code.Expires) {
+			encodeResponseErr(w, &apitypes.Error{
+				StatusCode: http.StatusBadRequest,
+				Type:       apitypes.BadRequestError,
+				Err:        []string{"Enrollment code has expired or has been used up"},
+			})
+			return
+		}
+
+		msg := fmt.Sprintf("Enrolling machine \"%s\"", req.Name)
+		n.Notify(observer.Progress, msg, true)
+
+		machine, memberships, err := createMachine(code.OrgID, code.TeamID, code.CreatedBy, req.Name)
+		if err != nil {
+			log.Printf("Error creating machine %s: %s", req.Name, err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		token, err := engine.Machine.CreateEnrollmentToken(ctx, n, machine, req.Secret,
+			code.CreatedBy)
+		if err != nil {
+			log.Printf("Error creating machine token: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Progress, "Uploading token keypairs", true)
+
+		// Keyring memberships for the token can only be encoded by someone
+		// with access to the keyrings; they are left to the worklog.
+		segment, err := client.Machines.Enroll(ctx, req.Code, machine, memberships, token)
+		if err != nil {
+			log.Printf("Error enrolling machine with registry: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Finished, "Machine enrolled", true)
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(segment)
+		if err != nil {
+			log.Printf("Error encoding MachineSegment: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
 // createMachine generates a Machine object and associated Membership objects
 // to be uploaded to the registry in the future.
 func createMachine(orgID, teamID, creatorID *identity.ID, name string) (
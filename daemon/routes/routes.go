@@ -26,16 +26,24 @@ func NewRouteMux(c *config.Config, s session.Session, db *db.DB,
 	mux.Get("/observe", o)
 
 	mux.PostFunc("/signup", signupRoute(client, s, db))
-	mux.PostFunc("/login", loginRoute(lEngine))
+	mux.PostFunc("/login", loginRoute(lEngine, o))
 	mux.PostFunc("/logout", logoutRoute(lEngine))
 	mux.GetFunc("/session", sessionRoute(s))
 	mux.GetFunc("/self", selfRoute(s))
 	mux.PatchFunc("/self", updateSelfRoute(client, s, lEngine))
 
 	mux.PostFunc("/machines", machinesCreateRoute(client, s, lEngine, o))
+	mux.PostFunc("/machines/enroll", machinesEnrollRoute(client, lEngine, o))
+	mux.PostFunc("/machines/:id/tokens", machineTokensCreateRoute(client, lEngine, o))
+	mux.PostFunc("/machines/:id/tokens/:token_id/revocation",
+		machineTokenRevocationRoute(client, lEngine))
 
 	mux.PostFunc("/keypairs/generate", keypairsGenerateRoute(lEngine, o))
 	mux.PostFunc("/keypairs/revoke", keypairsRevokeRoute(lEngine, o))
+	mux.PostFunc("/keypairs/rotate", keypairsRotateRoute(lEngine, o))
+	mux.GetFunc("/keypairs/verify", keypairsVerifyRoute(lEngine))
+	mux.PostFunc("/keypairs/backup", keypairsBackupRoute(lEngine))
+	mux.PostFunc("/keypairs/restore", keypairsRestoreRoute(lEngine))
 
 	mux.GetFunc("/credentials", credentialsGetRoute(lEngine, o))
 	mux.PostFunc("/credentials", credentialsPostRoute(lEngine, o))
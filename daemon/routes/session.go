@@ -16,10 +16,11 @@ import (
 	"github.com/manifoldco/torus-cli/daemon/crypto"
 	"github.com/manifoldco/torus-cli/daemon/db"
 	"github.com/manifoldco/torus-cli/daemon/logic"
+	"github.com/manifoldco/torus-cli/daemon/observer"
 	"github.com/manifoldco/torus-cli/daemon/session"
 )
 
-func loginRoute(engine *logic.Engine) http.HandlerFunc {
+func loginRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		dec := json.NewDecoder(r.Body)
@@ -51,7 +52,13 @@ func loginRoute(engine *logic.Engine) http.HandlerFunc {
 			return
 		}
 
-		err = engine.Session.Login(ctx, creds)
+		n, err := o.Notifier(ctx, 0)
+		if err != nil {
+			encodeResponseErr(w, err)
+			return
+		}
+
+		err = engine.Session.Login(ctx, n, creds)
 		if err != nil {
 			log.Printf("Could not complete login: %s", err)
 			encodeResponseErr(w, err)
@@ -0,0 +1,49 @@
+package socket
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+// ActivationListener returns the socket passed to the process through systemd
+// socket activation, or nil if the process was not socket activated.
+//
+// See sd_listen_fds(3) for details of the protocol.
+func ActivationListener() (net.Listener, error) {
+	return activationListener(listenFDsStart)
+}
+
+func activationListener(start int) (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, nil
+	}
+
+	// The variables are only meant for this process, not its children.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	if count != 1 {
+		return nil, fmt.Errorf("expected a single activation socket, received %d", count)
+	}
+
+	syscall.CloseOnExec(start)
+
+	f := os.NewFile(uintptr(start), "LISTEN_FD_"+strconv.Itoa(start))
+	defer f.Close()
+
+	// FileListener duplicates the descriptor, so the file can be closed.
+	return net.FileListener(f)
+}
@@ -0,0 +1,78 @@
+package socket
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestActivationListener(t *testing.T) {
+	t.Run("not activated", func(t *testing.T) {
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+		os.Setenv("LISTEN_FDS", "1")
+		defer os.Unsetenv("LISTEN_PID")
+		defer os.Unsetenv("LISTEN_FDS")
+
+		l, err := ActivationListener()
+		if err != nil || l != nil {
+			t.Errorf("expected no listener, got %v, %v", l, err)
+		}
+	})
+
+	t.Run("activated", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "torus-socket")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "test.socket")
+		orig, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer orig.Close()
+
+		// Stand in for systemd by passing a duplicate of the socket's
+		// descriptor, which the activation listener takes ownership of.
+		f, err := orig.(*net.UnixListener).File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd, err := syscall.Dup(int(f.Fd()))
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		os.Setenv("LISTEN_FDS", "1")
+
+		l, err := activationListener(fd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		if os.Getenv("LISTEN_FDS") != "" {
+			t.Error("LISTEN_FDS was not unset")
+		}
+
+		go func() {
+			c, err := net.Dial("unix", path)
+			if err == nil {
+				c.Close()
+			}
+		}()
+
+		c, err := l.Accept()
+		if err != nil {
+			t.Fatal("could not accept on activation socket:", err)
+		}
+		c.Close()
+	})
+}
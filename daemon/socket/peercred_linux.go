@@ -0,0 +1,38 @@
+package socket
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// peerUID returns the uid of the process on the other end of the domain
+// socket connection, as recorded by the kernel when it connected.
+func peerUID(c net.Conn) (uint32, error) {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return 0, errors.New("connection is not a domain socket")
+	}
+
+	f, err := uc.File()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	fd := int(f.Fd())
+
+	// File puts the shared descriptor into blocking mode; restore it so the
+	// connection keeps using the runtime's poller.
+	err = syscall.SetNonblock(fd, true)
+	if err != nil {
+		return 0, err
+	}
+
+	cred, err := syscall.GetsockoptUcred(fd, syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	if err != nil {
+		return 0, err
+	}
+
+	return cred.Uid, nil
+}
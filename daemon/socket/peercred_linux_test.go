@@ -0,0 +1,50 @@
+package socket
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPeerUID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "torus-socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := net.Listen("unix", filepath.Join(dir, "test.socket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	client, err := net.Dial("unix", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	uid, err := peerUID(c)
+	if err != nil {
+		t.Fatal("could not read peer credentials:", err)
+	}
+	if int(uid) != os.Getuid() {
+		t.Errorf("peerUID() = %d, want %d", uid, os.Getuid())
+	}
+
+	// The connection must remain usable afterwards.
+	go client.Write([]byte("ok"))
+	buf := make([]byte, 2)
+	if _, err := c.Read(buf); err != nil || string(buf) != "ok" {
+		t.Errorf("could not read from connection: %v", err)
+	}
+}
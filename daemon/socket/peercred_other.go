@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package socket
+
+import (
+	"errors"
+	"net"
+)
+
+// peerUID is only supported on linux, where the kernel provides the
+// credentials of domain socket peers through SO_PEERCRED.
+func peerUID(c net.Conn) (uint32, error) {
+	return 0, errors.New("per user sessions are only supported on linux")
+}
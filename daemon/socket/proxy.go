@@ -2,7 +2,6 @@ package socket
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"log"
 	"net"
@@ -11,7 +10,6 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/facebookgo/httpdown"
@@ -56,11 +54,19 @@ type AuthProxy struct {
 func NewAuthProxy(c *config.Config, sess session.Session, db *db.DB, t *http.Transport,
 	client *registry.Client, logic *logic.Engine, groupShared bool) (*AuthProxy, error) {
 
-	l, err := makeSocket(c.SocketPath, groupShared)
+	l, err := MakeSocket(c.SocketPath, groupShared)
 	if err != nil {
 		return nil, err
 	}
 
+	return NewAuthProxyForListener(c, l, sess, db, t, client, logic), nil
+}
+
+// NewAuthProxyForListener returns a new AuthProxy serving requests accepted
+// from the given listener, rather than creating its own domain socket.
+func NewAuthProxyForListener(c *config.Config, l net.Listener, sess session.Session,
+	db *db.DB, t *http.Transport, client *registry.Client, logic *logic.Engine) *AuthProxy {
+
 	return &AuthProxy{
 		u:      c.RegistryURI,
 		l:      l,
@@ -71,20 +77,18 @@ func NewAuthProxy(c *config.Config, sess session.Session, db *db.DB, t *http.Tra
 		t:      t,
 		client: client,
 		logic:  logic,
-	}, nil
-}
-
-// CreateHTTPTransport creates and configures the
-func CreateHTTPTransport(cfg *config.Config) *http.Transport {
-	return &http.Transport{TLSClientConfig: &tls.Config{
-		ServerName: strings.Split(cfg.RegistryURI.Host, ":")[0],
-		RootCAs:    cfg.CABundle,
-	}}
+	}
 }
 
 // Listen starts the main loop of the AuthProxy. It returns on error, or when
 // the AuthProxy is closed.
 func (p *AuthProxy) Listen() error {
+	p.start()
+	return p.s.Wait()
+}
+
+// start begins serving requests in the background.
+func (p *AuthProxy) start() {
 	mux := bone.New()
 	proxy := &httputil.ReverseProxy{
 		Transport: p.t,
@@ -111,8 +115,6 @@ func (p *AuthProxy) Listen() error {
 
 	h := httpdown.HTTP{}
 	p.s = h.Serve(&http.Server{Handler: requestIDHandler(loggingHandler(mux))}, p.l)
-
-	return p.s.Wait()
 }
 
 // Close gracefully closes the socket, ensuring all requests are finished
@@ -148,7 +150,10 @@ func requestIDHandler(next http.Handler) http.Handler {
 	})
 }
 
-func makeSocket(socketPath string, groupShared bool) (net.Listener, error) {
+// MakeSocket creates and listens on a domain socket at the given path,
+// replacing any existing socket. If groupShared is true, the socket is
+// readable and writable by the user's group.
+func MakeSocket(socketPath string, groupShared bool) (net.Listener, error) {
 	absPath, err := filepath.Abs(socketPath)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,174 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// SOCKS5 protocol values, from RFC 1928 and RFC 1929.
+const (
+	socks5Version = 0x05
+
+	socks5NoAuth       = 0x00
+	socks5PasswordAuth = 0x02
+	socks5NoAcceptable = 0xff
+
+	socks5PasswordAuthVersion = 0x01
+
+	socks5ConnectCmd = 0x01
+
+	socks5IPv4   = 0x01
+	socks5Domain = 0x03
+	socks5IPv6   = 0x04
+)
+
+var socks5Errors = map[byte]string{
+	0x01: "general failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socks5Connect asks a SOCKS5 proxy to open a connection to addr over conn,
+// authenticating with the username and password in the proxy url, if any.
+// Host names are resolved by the proxy.
+func socks5Connect(conn net.Conn, proxy *url.URL, addr string) error {
+	host, rawPort, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	port, err := strconv.Atoi(rawPort)
+	if err != nil || port < 1 || port > 0xffff {
+		return fmt.Errorf("invalid port in %s", addr)
+	}
+
+	methods := []byte{socks5NoAuth}
+	if proxy.User != nil {
+		methods = append(methods, socks5PasswordAuth)
+	}
+
+	_, err = conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...))
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 2)
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		return err
+	}
+	if buf[0] != socks5Version {
+		return errors.New("proxy is not a SOCKS5 proxy")
+	}
+
+	switch buf[1] {
+	case socks5NoAuth:
+	case socks5PasswordAuth:
+		if proxy.User == nil {
+			return errors.New("SOCKS5 proxy requires a username and password")
+		}
+
+		err = socks5Authenticate(conn, proxy.User)
+		if err != nil {
+			return err
+		}
+	case socks5NoAcceptable:
+		return errors.New("SOCKS5 proxy did not accept any authentication method")
+	default:
+		return fmt.Errorf("SOCKS5 proxy chose unsupported authentication method %d", buf[1])
+	}
+
+	req := []byte{socks5Version, socks5ConnectCmd, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %s", host)
+		}
+		req = append(req, socks5Domain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5IPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5IPv6)
+		req = append(req, ip...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+
+	_, err = conn.Write(req)
+	if err != nil {
+		return err
+	}
+
+	// The reply holds the address bound by the proxy, which we don't need,
+	// but must read past.
+	buf = make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		return err
+	}
+	if buf[1] != 0x00 {
+		msg, ok := socks5Errors[buf[1]]
+		if !ok {
+			msg = "unknown error " + strconv.Itoa(int(buf[1]))
+		}
+		return fmt.Errorf("SOCKS5 proxy could not connect to %s: %s", addr, msg)
+	}
+
+	var skip int
+	switch buf[3] {
+	case socks5IPv4:
+		skip = net.IPv4len
+	case socks5IPv6:
+		skip = net.IPv6len
+	case socks5Domain:
+		_, err = io.ReadFull(conn, buf[:1])
+		if err != nil {
+			return err
+		}
+		skip = int(buf[0])
+	default:
+		return errors.New("SOCKS5 proxy replied with an unknown address type")
+	}
+
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
+
+// socks5Authenticate sends the username and password to the proxy.
+func socks5Authenticate(conn net.Conn, user *url.Userinfo) error {
+	username := user.Username()
+	password, _ := user.Password()
+	if len(username) > 255 || len(password) > 255 {
+		return errors.New("SOCKS5 username and password must be under 256 bytes")
+	}
+
+	req := []byte{socks5PasswordAuthVersion, byte(len(username))}
+	req = append(req, username...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+
+	_, err := conn.Write(req)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 2)
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		return err
+	}
+	if buf[1] != 0x00 {
+		return errors.New("SOCKS5 proxy rejected the username and password")
+	}
+
+	return nil
+}
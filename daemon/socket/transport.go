@@ -0,0 +1,209 @@
+package socket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/torus-cli/config"
+)
+
+// CreateHTTPTransport creates the transport used for requests to the
+// registry.
+//
+// Connections are made through the proxy set in core.proxy, or in the proxy
+// environment variables. If a client certificate is configured it is
+// presented to the registry, and if the public key file holds cert pins, the
+// registry's certificate chain must match one of them.
+func CreateHTTPTransport(cfg *config.Config) (*http.Transport, error) {
+	tlsConfig := &tls.Config{
+		ServerName: strings.Split(cfg.RegistryURI.Host, ":")[0],
+		RootCAs:    cfg.CABundle,
+	}
+	if cfg.ClientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*cfg.ClientCert}
+	}
+
+	proxy, err := registryProxy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &dialer{
+		dialer:    &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		proxy:     proxy,
+		tlsConfig: tlsConfig,
+	}
+	if cfg.PublicKey != nil {
+		for _, pin := range cfg.PublicKey.CertPins {
+			d.pins = append(d.pins, []byte(pin))
+		}
+	}
+
+	t := &http.Transport{TLSClientConfig: tlsConfig}
+	switch {
+	case len(d.pins) > 0:
+		// The transport doesn't use DialTLS for connections through a
+		// proxy, so the pins could not be checked. Instead, the dialer
+		// handles proxies itself.
+		t.Dial = d.Dial
+		t.DialTLS = d.DialTLS
+	case proxy == nil:
+	case proxy.Scheme == "http":
+		t.Proxy = http.ProxyURL(proxy)
+	default:
+		t.Dial = d.Dial
+	}
+
+	return t, nil
+}
+
+// registryProxy returns the proxy to reach the registry through, or nil if it
+// should be reached directly.
+func registryProxy(cfg *config.Config) (*url.URL, error) {
+	proxy := cfg.Proxy
+	if proxy == nil {
+		fromEnv, err := http.ProxyFromEnvironment(&http.Request{URL: cfg.RegistryURI})
+		if err != nil || fromEnv == nil {
+			return nil, err
+		}
+
+		proxy, err = config.ParseProxy(fromEnv.String())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	redacted := *proxy
+	if redacted.User != nil {
+		redacted.User = url.User(redacted.User.Username())
+	}
+	log.Printf("Connecting to the registry through %s", redacted.String())
+
+	return proxy, nil
+}
+
+// dialer makes connections to the registry, through a proxy if one is set.
+type dialer struct {
+	dialer    *net.Dialer
+	proxy     *url.URL
+	tlsConfig *tls.Config
+	pins      [][]byte
+}
+
+// Dial connects to the given address, tunnelling through the proxy.
+func (d *dialer) Dial(network, addr string) (net.Conn, error) {
+	if d.proxy == nil {
+		return d.dialer.Dial(network, addr)
+	}
+
+	conn, err := d.dialer.Dial(network, proxyAddr(d.proxy))
+	if err != nil {
+		return nil, err
+	}
+
+	if d.proxy.Scheme == "http" {
+		err = httpConnect(conn, d.proxy, addr)
+	} else {
+		err = socks5Connect(conn, d.proxy, addr)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// DialTLS connects to the given address, completes the TLS handshake, and
+// checks the verified certificate chain against the pins.
+func (d *dialer) DialTLS(network, addr string) (net.Conn, error) {
+	conn, err := d.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, d.tlsConfig)
+	err = tlsConn.Handshake()
+	if err == nil {
+		err = checkPins(tlsConn.ConnectionState(), d.pins)
+	}
+	if err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// checkPins returns an error unless a certificate in one of the verified
+// chains has a public key matching one of the pins.
+func checkPins(state tls.ConnectionState, pins [][]byte) error {
+	for _, chain := range state.VerifiedChains {
+		for _, cert := range chain {
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			for _, pin := range pins {
+				if bytes.Equal(sum[:], pin) {
+					return nil
+				}
+			}
+		}
+	}
+
+	return errors.New("registry certificate does not match any pinned key")
+}
+
+func proxyAddr(proxy *url.URL) string {
+	if _, _, err := net.SplitHostPort(proxy.Host); err == nil {
+		return proxy.Host
+	}
+
+	if proxy.Scheme == "http" {
+		return net.JoinHostPort(proxy.Host, "80")
+	}
+	return net.JoinHostPort(proxy.Host, "1080")
+}
+
+// httpConnect asks an HTTP proxy to open a tunnel to addr over conn.
+func httpConnect(conn net.Conn, proxy *url.URL, addr string) error {
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		auth := proxy.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+
+	err := req.Write(conn)
+	if err != nil {
+		return err
+	}
+
+	// The proxy sends nothing more until the client starts talking through
+	// the tunnel, so the buffered reader can be dropped afterwards.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused connection to %s: %s", addr, resp.Status)
+	}
+
+	return nil
+}
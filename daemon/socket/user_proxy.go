@@ -0,0 +1,250 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Backoff before a user whose session could not be created is retried. The
+// delay doubles with every failure, up to the max.
+const (
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+// ProxyFactory creates the AuthProxy serving the user with the given uid,
+// accepting that user's connections from the given listener.
+type ProxyFactory func(uid uint32, l net.Listener) (*AuthProxy, error)
+
+// UserProxy accepts connections on a socket shared by many users, and hands
+// each one to an AuthProxy belonging to the connecting user. Every user gets
+// their own AuthProxy, and so their own session, the first time they connect.
+type UserProxy struct {
+	l       net.Listener
+	factory ProxyFactory
+
+	mutex   sync.Mutex
+	proxies map[uint32]*userConns
+	closed  bool
+}
+
+// userConns is the AuthProxy for a user, along with the listener its
+// connections are delivered through. ready is closed once the AuthProxy has
+// been created, or creating it failed; the other fields must not be read
+// before then.
+type userConns struct {
+	proxy *AuthProxy
+	l     *connListener
+	ready chan struct{}
+
+	err      error
+	failures uint
+	retryAt  time.Time
+}
+
+// NewUserProxy returns a new UserProxy accepting connections from l.
+func NewUserProxy(l net.Listener, factory ProxyFactory) *UserProxy {
+	return &UserProxy{
+		l:       l,
+		factory: factory,
+		proxies: make(map[uint32]*userConns),
+	}
+}
+
+// Listen accepts connections until the UserProxy is closed.
+func (p *UserProxy) Listen() error {
+	for {
+		c, err := p.l.Accept()
+		if err != nil {
+			p.mutex.Lock()
+			closed := p.closed
+			p.mutex.Unlock()
+
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		// Creating a user's session logs them in, so it's done off the
+		// accept loop, leaving other users unaffected while it runs.
+		go p.serve(c)
+	}
+}
+
+// serve hands the connection to the AuthProxy of the user that made it.
+func (p *UserProxy) serve(c net.Conn) {
+	uid, err := peerUID(c)
+	if err != nil {
+		log.Printf("Rejecting connection; could not identify peer: %s", err)
+		c.Close()
+		return
+	}
+
+	u, err := p.forUser(uid)
+	if err != nil {
+		log.Printf("Rejecting connection; could not create session for uid %d: %s", uid, err)
+		c.Close()
+		return
+	}
+
+	u.l.deliver(c)
+}
+
+// forUser returns the AuthProxy for the given uid, creating it if needed.
+// Concurrent calls for the same uid wait on a single creation, and a uid whose
+// creation failed is not retried until its backoff has passed.
+func (p *UserProxy) forUser(uid uint32) (*userConns, error) {
+	p.mutex.Lock()
+
+	var failures uint
+	if u, ok := p.proxies[uid]; ok {
+		select {
+		case <-u.ready:
+		default:
+			p.mutex.Unlock()
+			<-u.ready
+			return u.result()
+		}
+
+		if u.err == nil || time.Now().Before(u.retryAt) {
+			p.mutex.Unlock()
+			return u.result()
+		}
+		failures = u.failures
+	}
+
+	if p.closed {
+		p.mutex.Unlock()
+		return nil, errListenerClosed
+	}
+
+	u := &userConns{l: newConnListener(p.l.Addr()), ready: make(chan struct{})}
+	p.proxies[uid] = u
+	p.mutex.Unlock()
+
+	proxy, err := p.factory(uid, u.l)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	defer close(u.ready)
+
+	switch {
+	case err != nil:
+		u.failures = failures + 1
+		u.retryAt = time.Now().Add(retryDelay(u.failures))
+		u.err = err
+	case p.closed:
+		proxy.Close()
+		u.err = errListenerClosed
+	default:
+		log.Printf("Created session for uid %d", uid)
+		proxy.start()
+		u.proxy = proxy
+	}
+
+	return u.result()
+}
+
+// result returns the userConns, or the error creating its AuthProxy.
+func (u *userConns) result() (*userConns, error) {
+	if u.err != nil {
+		if !u.retryAt.IsZero() {
+			return nil, fmt.Errorf("%s (retrying after %s)", u.err, u.retryAt.Format(time.RFC3339))
+		}
+		return nil, u.err
+	}
+
+	return u, nil
+}
+
+// retryDelay returns how long to wait before creating a session again, after
+// the given number of failures in a row.
+func retryDelay(failures uint) time.Duration {
+	delay := minRetryDelay
+	for i := uint(1); i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay
+}
+
+// Close stops accepting connections, and gracefully closes every user's
+// AuthProxy.
+func (p *UserProxy) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+	err := p.l.Close()
+
+	for uid, u := range p.proxies {
+		// Sessions still being created are closed once created, and
+		// those that failed have nothing to close.
+		if u.proxy == nil {
+			continue
+		}
+		if cErr := u.proxy.Close(); cErr != nil {
+			log.Printf("Error closing session for uid %d: %s", uid, cErr)
+		}
+	}
+
+	return err
+}
+
+// Addr returns the socket the UserProxy is listening on.
+func (p *UserProxy) Addr() string {
+	return p.l.Addr().String()
+}
+
+var errListenerClosed = errors.New("listener closed")
+
+// connListener is a net.Listener whose connections are accepted elsewhere,
+// and delivered to it.
+type connListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{
+		addr:  addr,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *connListener) deliver(c net.Conn) {
+	select {
+	case l.conns <- c:
+	case <-l.done:
+		c.Close()
+	}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, errListenerClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
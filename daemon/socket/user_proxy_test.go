@@ -0,0 +1,74 @@
+package socket
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUserProxyFailedLogin(t *testing.T) {
+	release := make(chan struct{})
+	var mutex sync.Mutex
+	calls := 0
+	factory := func(uid uint32, l net.Listener) (*AuthProxy, error) {
+		mutex.Lock()
+		calls++
+		mutex.Unlock()
+
+		<-release
+		return nil, errors.New("login failed")
+	}
+
+	p := NewUserProxy(newConnListener(nil), factory)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.forUser(1000)
+			errs <- err
+		}()
+	}
+
+	// Give every caller a chance to find the session being created.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err == nil {
+			t.Error("expected an error creating the session")
+		}
+	}
+
+	if _, err := p.forUser(1000); err == nil {
+		t.Error("expected an error during the retry backoff")
+	}
+
+	if calls != 1 {
+		t.Errorf("factory called %d times, want 1", calls)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tcs := []struct {
+		failures uint
+		delay    time.Duration
+	}{
+		{1, minRetryDelay},
+		{2, 2 * minRetryDelay},
+		{3, 4 * minRetryDelay},
+		{100, maxRetryDelay},
+	}
+
+	for _, tc := range tcs {
+		if delay := retryDelay(tc.failures); delay != tc.delay {
+			t.Errorf("retryDelay(%d) = %s, want %s", tc.failures, delay, tc.delay)
+		}
+	}
+}
@@ -3,17 +3,52 @@ package dirprefs
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 )
 
+var envNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
 // DirPreferences holds preferences for arguments set in .torus.json files
 type DirPreferences struct {
 	Organization string `json:"org,omitempty"`
 	Project      string `json:"project,omitempty"`
-	Path         string `json:"-"`
+	Environment  string `json:"environment,omitempty"`
+	Service      string `json:"service,omitempty"`
+	Instance     string `json:"instance,omitempty"`
+
+	// Mappings exposes secrets to 'torus run' under a different name in the
+	// environment, keyed by secret name.
+	Mappings map[string]string `json:"mappings,omitempty"`
+
+	// Required lists the names of secrets that must be found for 'torus run'
+	// to start its command.
+	Required []string `json:"required,omitempty"`
+
+	// Secrets is the manifest of secrets the project needs, checked by
+	// 'torus check' and 'torus run --strict'.
+	Secrets []Secret `json:"secrets,omitempty"`
+
+	Path string `json:"-"`
 }
 
+// Secret describes a secret in the manifest of a .torus.json file. If Type is
+// set, the secret must hold a value of that type, and if Pattern is set, its
+// value must match the regular expression.
+type Secret struct {
+	Name    string `json:"name"`
+	Type    string `json:"type,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// SecretTypes are the types a secret in the manifest can be required to
+// have.
+var SecretTypes = []string{"string", "number", "boolean", "object", "binary"}
+
 // Load loads DirPreferences. It starts in the current working directory,
 // looking for a readable '.torus.json' file, and walks up the directory
 // hierarchy until it finds one, or reaches the root of the fs.
@@ -52,10 +87,93 @@ func Load(recurse bool) (*DirPreferences, error) {
 		return nil, err
 	}
 
+	for secret, name := range prefs.Mappings {
+		if !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment variable name %q for %s in %s", name, secret, f.Name())
+		}
+	}
+
+	for _, secret := range prefs.Secrets {
+		err = secret.validate()
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s", err, f.Name())
+		}
+	}
+
 	prefs.Path = f.Name()
 	return prefs, nil
 }
 
+func (s *Secret) validate() error {
+	if s.Name == "" {
+		return errors.New("secret without a name in manifest")
+	}
+
+	if s.Type != "" {
+		valid := false
+		for _, t := range SecretTypes {
+			valid = valid || s.Type == t
+		}
+		if !valid {
+			return fmt.Errorf("invalid type %q for secret %s, use one of %s",
+				s.Type, s.Name, strings.Join(SecretTypes, ", "))
+		}
+	}
+
+	_, err := regexp.Compile(s.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern for secret %s: %s", s.Name, err)
+	}
+
+	return nil
+}
+
+// Manifest returns the secrets the project needs: those in the manifest, and
+// any required secrets not already in it.
+func (d *DirPreferences) Manifest() []Secret {
+	manifest := append([]Secret{}, d.Secrets...)
+	for _, name := range d.Required {
+		found := false
+		for _, s := range d.Secrets {
+			found = found || strings.EqualFold(s.Name, name)
+		}
+		if !found {
+			manifest = append(manifest, Secret{Name: name})
+		}
+	}
+
+	return manifest
+}
+
+// EnvName returns the name of the environment variable holding the named
+// secret, which is the upper case secret name unless it is mapped to
+// another name.
+func (d *DirPreferences) EnvName(secret string) string {
+	if name, ok := d.Mappings[secret]; ok {
+		return name
+	}
+
+	return strings.ToUpper(secret)
+}
+
+// Missing returns the required secrets that are not in names, in the order
+// they are listed.
+func (d *DirPreferences) Missing(names []string) []string {
+	found := make(map[string]bool, len(names))
+	for _, name := range names {
+		found[strings.ToLower(name)] = true
+	}
+
+	var missing []string
+	for _, name := range d.Required {
+		if !found[strings.ToLower(name)] {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing
+}
+
 // Save writes the DirPreferences values to the file in the struct's Path
 // field
 func (d *DirPreferences) Save() error {
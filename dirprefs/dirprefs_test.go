@@ -0,0 +1,45 @@
+package dirprefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	d := &DirPreferences{Mappings: map[string]string{"db_url": "DATABASE_URL"}}
+
+	if name := d.EnvName("db_url"); name != "DATABASE_URL" {
+		t.Errorf("EnvName(db_url) = %s, want DATABASE_URL", name)
+	}
+	if name := d.EnvName("port"); name != "PORT" {
+		t.Errorf("EnvName(port) = %s, want PORT", name)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	d := &DirPreferences{Required: []string{"db_url", "port", "API_KEY"}}
+
+	missing := d.Missing([]string{"port", "api_key"})
+	if !reflect.DeepEqual(missing, []string{"db_url"}) {
+		t.Errorf("Missing() = %v, want [db_url]", missing)
+	}
+
+	if missing := d.Missing([]string{"db_url", "port", "api_key"}); missing != nil {
+		t.Errorf("Missing() = %v, want none", missing)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	d := &DirPreferences{
+		Required: []string{"DB_URL", "port"},
+		Secrets:  []Secret{{Name: "db_url", Type: "string", Pattern: "^postgres://"}},
+	}
+
+	want := []Secret{
+		{Name: "db_url", Type: "string", Pattern: "^postgres://"},
+		{Name: "port"},
+	}
+	if manifest := d.Manifest(); !reflect.DeepEqual(manifest, want) {
+		t.Errorf("Manifest() = %v, want %v", manifest, want)
+	}
+}
@@ -3,6 +3,8 @@
 package envelope
 
 import (
+	"time"
+
 	"github.com/manifoldco/torus-cli/base64"
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/pathexp"
@@ -48,6 +50,7 @@ type KeyringInf interface {
 	PathExp() *pathexp.PathExp
 	OrgID() *identity.ID
 	GetVersion() uint8 // Return the schema version of the keyring
+	Created() time.Time
 }
 
 // GetVersion returns the schema version of this Keyring.
@@ -55,6 +58,11 @@ func (k *KeyringV1) GetVersion() uint8 {
 	return k.Version
 }
 
+// Created returns the time at which this version of the Keyring was created.
+func (k *KeyringV1) Created() time.Time {
+	return k.Body.Created
+}
+
 // OrgID returns the ID of the Org that this Keyring belongs to.
 func (k *KeyringV1) OrgID() *identity.ID {
 	return k.Body.OrgID
@@ -71,6 +79,11 @@ func (k *Keyring) GetVersion() uint8 {
 	return k.Version
 }
 
+// Created returns the time at which this version of the Keyring was created.
+func (k *Keyring) Created() time.Time {
+	return k.Body.Created
+}
+
 // OrgID returns the ID of the Org that this Keyring belongs to.
 func (k *Keyring) OrgID() *identity.ID {
 	return k.Body.OrgID
@@ -99,6 +112,9 @@ type CredentialInf interface {
 
 	OrgID() *identity.ID
 	ProjectID() *identity.ID
+
+	Metadata() *primitive.CredentialMetadata
+	Created() *time.Time
 }
 
 // GetVersion returns the schema version of this Credential.
@@ -154,6 +170,82 @@ func (c *CredentialV1) ProjectID() *identity.ID {
 	return c.Body.ProjectID
 }
 
+// Metadata returns the metadata describing this Credential.
+// Version 1 credentials do not have metadata, so it is always nil.
+func (CredentialV1) Metadata() *primitive.CredentialMetadata {
+	return nil
+}
+
+// Created returns the time at which this Credential was created.
+// Version 1 credentials do not record this, so it is always nil.
+func (CredentialV1) Created() *time.Time {
+	return nil
+}
+
+// GetVersion returns the schema version of this Credential.
+func (c *CredentialV2) GetVersion() uint8 {
+	return c.Version
+}
+
+// Previous returns the ID of the previous versino of this Credential, or nil
+// if this Credential has no previous version.
+func (c *CredentialV2) Previous() *identity.ID {
+	return c.Body.Previous
+}
+
+// CredentialVersion returns the monotomically incremented version of the
+// Credential for this PathExp/Name pair.
+func (c *CredentialV2) CredentialVersion() int {
+	return c.Body.CredentialVersion
+}
+
+// PathExp returns the path expression for this Credential's location.
+func (c *CredentialV2) PathExp() *pathexp.PathExp {
+	return c.Body.PathExp
+}
+
+// Name returns this Credential's name.
+func (c *CredentialV2) Name() string {
+	return c.Body.Name
+}
+
+// Unset returns a bool indicating if this Credential has been explicitly unset.
+func (c *CredentialV2) Unset() bool {
+	return c.Body.State != nil && *c.Body.State == "unset"
+}
+
+// Nonce returns the Nonce for this Credential's encrypted value.
+func (c *CredentialV2) Nonce() *base64.Value {
+	return c.Body.Nonce
+}
+
+// Credential returns the encrypted CredentialValue for this Credential.
+func (c *CredentialV2) Credential() *primitive.CredentialValue {
+	return c.Body.Credential
+}
+
+// OrgID returns the ID of the Org that this Credential belongs to.
+func (c *CredentialV2) OrgID() *identity.ID {
+	return c.Body.OrgID
+}
+
+// ProjectID returns the ID of the Project that this Credential belongs to.
+func (c *CredentialV2) ProjectID() *identity.ID {
+	return c.Body.ProjectID
+}
+
+// Metadata returns the metadata describing this Credential.
+// Version 2 credentials do not have metadata, so it is always nil.
+func (CredentialV2) Metadata() *primitive.CredentialMetadata {
+	return nil
+}
+
+// Created returns the time at which this Credential was created.
+// Version 2 credentials do not record this, so it is always nil.
+func (CredentialV2) Created() *time.Time {
+	return nil
+}
+
 // GetVersion returns the schema version of this Credential.
 func (c *Credential) GetVersion() uint8 {
 	return c.Version
@@ -205,3 +297,14 @@ func (c *Credential) OrgID() *identity.ID {
 func (c *Credential) ProjectID() *identity.ID {
 	return c.Body.ProjectID
 }
+
+// Metadata returns the metadata describing this Credential, or nil if it has
+// none.
+func (c *Credential) Metadata() *primitive.CredentialMetadata {
+	return c.Body.Metadata
+}
+
+// Created returns the time at which this Credential was created.
+func (c *Credential) Created() *time.Time {
+	return c.Body.Created
+}
@@ -31,7 +31,9 @@ func main() {
 	app.HelpName = "torus"
 	app.Usage = "A secure, shared workspace for secrets"
 	app.Version = config.Version
-	app.Commands = cmd.Cmds
+	app.Commands = cmd.WithProfileFlag(cmd.Cmds)
+	app.Flags = []cli.Flag{cmd.ProfileFlag}
+	app.Before = cmd.SetProfileEnv
 	app.Run(os.Args)
 }
 
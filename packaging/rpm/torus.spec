@@ -20,9 +20,12 @@ cp %{_sourcedir}/builds/bin/%{REAL_VERSION}/linux/%{ARCH}/torus $RPM_BUILD_ROOT%
 
 mkdir -p $RPM_BUILD_ROOT%{_usr}/lib/systemd/system
 cp %{_sourcedir}/contrib/systemd/torus.service $RPM_BUILD_ROOT%{_usr}/lib/systemd/system/
+cp %{_sourcedir}/contrib/systemd/torus-system.service $RPM_BUILD_ROOT%{_usr}/lib/systemd/system/
+cp %{_sourcedir}/contrib/systemd/torus-system.socket $RPM_BUILD_ROOT%{_usr}/lib/systemd/system/
 
 mkdir -p $RPM_BUILD_ROOT%{_sysconfdir}/torus
 cp %{_sourcedir}/contrib/systemd/token.environment $RPM_BUILD_ROOT%{_sysconfdir}/torus/
+mkdir -p $RPM_BUILD_ROOT%{_sysconfdir}/torus/tokens
 
 mkdir -p $RPM_BUILD_ROOT%{_var}/run/torus
 
@@ -37,9 +40,12 @@ exit 0
 %doc
 %{_bindir}/torus
 %{_usr}/lib/systemd/system/torus.service
+%{_usr}/lib/systemd/system/torus-system.service
+%{_usr}/lib/systemd/system/torus-system.socket
 
 %attr(700, torus, torus) %{_sysconfdir}/torus
 %config(noreplace) %attr(600, root, root) %{_sysconfdir}/torus/token.environment
+%attr(700, torus, torus) %{_sysconfdir}/torus/tokens
 
 %attr(770, torus, torus) %{_var}/run/torus
 
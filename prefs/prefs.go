@@ -1,11 +1,15 @@
 package prefs
 
 import (
+	"errors"
 	"os"
 	"os/user"
 	"path"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/manifoldco/torus-cli/errs"
 
@@ -16,12 +20,43 @@ import (
 const (
 	rcFilename  = ".torusrc"
 	registryURI = "https://registry.arigato.sh"
+
+	rotationSectionPrefix = "rotation "
+	profileSectionPrefix  = "profile "
+
+	// ProfileEnv is the environment variable used to select a profile,
+	// taking precedence over core.profile.
+	ProfileEnv = "TORUS_PROFILE"
+
+	// DefaultProfile is the name used for the preferences outside of any
+	// profile.
+	DefaultProfile = "default"
 )
 
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$`)
+
 // Preferences represents the configuration as user has in their torusrc file
 type Preferences struct {
 	Core     Core     `ini:"core"`
 	Defaults Defaults `ini:"defaults"`
+	Rotation Rotation `ini:"rotation"`
+	Machines Machines `ini:"machines"`
+	Invites  Invites  `ini:"invites"`
+	Registry Registry `ini:"registry"`
+
+	// RotationOverrides holds the rotation thresholds for specific orgs and
+	// projects, keyed by "org" or "org/project". They are read from
+	// [rotation org] and [rotation org/project] sections.
+	RotationOverrides map[string]Rotation `ini:"-"`
+
+	// Profiles holds the names of the profiles defined with [profile name]
+	// sections. A profile can set any [core] or [defaults] preference,
+	// overriding the values outside of it while it is active.
+	Profiles []string `ini:"-"`
+
+	// ActiveProfile is the name of the profile applied to these
+	// preferences, or empty if none is.
+	ActiveProfile string `ini:"-"`
 }
 
 // CountFields returns the number of defined fields on sub-field struct
@@ -46,6 +81,10 @@ type Core struct {
 	PublicKeyFile  string `ini:"public_key_file,omitempty"`
 	CABundleFile   string `ini:"ca_bundle_file,omitempty"`
 	RegistryURI    string `ini:"registry_uri,omitempty"`
+	Profile        string `ini:"profile,omitempty"`
+	Proxy          string `ini:"proxy,omitempty"`
+	ClientCertFile string `ini:"client_cert_file,omitempty"`
+	ClientKeyFile  string `ini:"client_key_file,omitempty"`
 	Context        bool   `ini:"context,omitempty"`
 	AutoConfirm    bool   `ini:"auto_confirm,omitempty"`
 	EnableProgress bool   `ini:"progress"`
@@ -61,6 +100,60 @@ type Defaults struct {
 	Service      string `ini:"service,omitempty"`
 }
 
+// Rotation contains the thresholds used to flag secrets for rotation in the
+// worklog. Values are durations given in days (90d) or hours (12h).
+type Rotation struct {
+	MaxAge        string `ini:"max_age,omitempty"`
+	ExpiryWarning string `ini:"expiry_warning,omitempty"`
+}
+
+// Machines contains the threshold used to flag machines as stale in the
+// worklog, and whether a machine logging in to an org without keypairs
+// should generate them. StaleAfter is a duration given in days (90d) or
+// hours (12h).
+type Machines struct {
+	StaleAfter       string `ini:"stale_after,omitempty"`
+	GenerateKeypairs bool   `ini:"generate_keypairs,omitempty"`
+}
+
+// Invites contains how long an org invite can go without being accepted
+// before it expires, and is flagged in the worklog to be revoked. ExpireAfter
+// is a duration given in days (14d) or hours (12h).
+type Invites struct {
+	ExpireAfter string `ini:"expire_after,omitempty"`
+}
+
+// Registry contains the timeouts and retry policy for requests to the
+// registry. Durations are given with a unit, such as 500ms or 6s.
+type Registry struct {
+	Timeout          string `ini:"timeout,omitempty"`
+	Retries          string `ini:"retries,omitempty"`
+	Backoff          string `ini:"backoff,omitempty"`
+	MaxBackoff       string `ini:"max_backoff,omitempty"`
+	BreakerThreshold string `ini:"breaker_threshold,omitempty"`
+	BreakerCooldown  string `ini:"breaker_cooldown,omitempty"`
+}
+
+// ParseDuration parses a worklog threshold. In addition to the units
+// understood by time.ParseDuration, it accepts a number of days, such as 90d.
+func ParseDuration(raw string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if strings.HasSuffix(raw, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(raw, "d"))
+		d = time.Duration(days) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(raw)
+	}
+
+	if err != nil || d < 0 {
+		return 0, errors.New("invalid duration " + raw + ", use days (90d) or hours (12h)")
+	}
+
+	return d, nil
+}
+
 // SetValue for ini key on preferences struct
 func (prefs Preferences) SetValue(key string, value string) (Preferences, error) {
 	parts := strings.Split(key, ".")
@@ -121,8 +214,50 @@ func RcPath() (string, error) {
 	return path.Join(u.HomeDir, rcFilename), nil
 }
 
-// NewPreferences returns a new instance of preferences struct
+// NewPreferences returns a new instance of preferences struct, with the
+// active profile applied.
 func NewPreferences() (*Preferences, error) {
+	prefs, f, err := loadPreferences()
+	if err != nil || f == nil {
+		return prefs, err
+	}
+
+	name := os.Getenv(ProfileEnv)
+	if name == "" {
+		name = prefs.Core.Profile
+	}
+	if name == "" || name == DefaultProfile {
+		return prefs, nil
+	}
+
+	section, err := f.GetSection(profileSectionPrefix + name)
+	if err != nil || !profileNamePattern.MatchString(name) {
+		return prefs, errors.New("unknown profile " + name)
+	}
+
+	err = section.MapTo(&prefs.Core)
+	if err != nil {
+		return prefs, err
+	}
+	err = section.MapTo(&prefs.Defaults)
+	if err != nil {
+		return prefs, err
+	}
+
+	prefs.ActiveProfile = name
+	return prefs, nil
+}
+
+// NewBasePreferences returns the preferences without any profile applied,
+// for changing and saving back to the torusrc file.
+func NewBasePreferences() (*Preferences, error) {
+	prefs, _, err := loadPreferences()
+	return prefs, err
+}
+
+// loadPreferences reads the torusrc file, returning the parsed file so
+// profiles can be applied. The file is nil if it does not exist.
+func loadPreferences() (*Preferences, *ini.File, error) {
 	prefs := &Preferences{
 		Core: Core{
 			RegistryURI:    registryURI,
@@ -135,14 +270,59 @@ func NewPreferences() (*Preferences, error) {
 	filePath, _ := RcPath()
 	_, err := os.Stat(filePath)
 	if os.IsNotExist(err) {
-		return prefs, nil
+		return prefs, nil, nil
 	}
 
 	if err != nil {
-		return prefs, err
+		return prefs, nil, err
 	}
 
 	rcPath, _ := RcPath()
-	err = ini.MapTo(prefs, rcPath)
-	return prefs, err
+	f, err := ini.Load(rcPath)
+	if err != nil {
+		return prefs, nil, err
+	}
+
+	err = f.MapTo(prefs)
+	if err != nil {
+		return prefs, nil, err
+	}
+
+	prefs.RotationOverrides = make(map[string]Rotation)
+	for _, section := range f.Sections() {
+		name := section.Name()
+		if strings.HasPrefix(name, profileSectionPrefix) {
+			prefs.Profiles = append(prefs.Profiles, strings.TrimSpace(strings.TrimPrefix(name, profileSectionPrefix)))
+			continue
+		}
+
+		if !strings.HasPrefix(name, rotationSectionPrefix) {
+			continue
+		}
+
+		r := Rotation{}
+		err = section.MapTo(&r)
+		if err != nil {
+			return prefs, nil, err
+		}
+
+		name = strings.TrimSpace(strings.TrimPrefix(name, rotationSectionPrefix))
+		prefs.RotationOverrides[name] = r
+	}
+
+	return prefs, f, nil
+}
+
+// ValidateProfileName returns an error if name can't be used for a profile.
+// Profile names are used in paths, so are limited to letters, numbers,
+// dashes and underscores.
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return errs.NewExitError("Profile names may only contain letters, numbers, dashes and underscores.")
+	}
+	if name == DefaultProfile {
+		return errs.NewExitError("The name " + DefaultProfile + " is reserved for preferences outside of a profile.")
+	}
+
+	return nil
 }
@@ -2,6 +2,7 @@ package prefs
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,8 +16,13 @@ import (
 const requiredPermissions = 0700
 
 // PublicKey is en ed25519 public key.
+//
+// CertPins optionally pins the registry's TLS certificate. Each pin is the
+// SHA-256 digest of a certificate's SubjectPublicKeyInfo; the connection is
+// trusted if any certificate in the verified chain matches any pin.
 type PublicKey struct {
-	PublicKey base64.Value `json:"public_key"`
+	PublicKey base64.Value   `json:"public_key"`
+	CertPins  []base64.Value `json:"cert_pins,omitempty"`
 }
 
 // LoadPublicKey reads the publickey file from disk and parses the json
@@ -70,6 +76,12 @@ func parsePublicKeyFile(fd io.Reader) (*PublicKey, error) {
 		return nil, err
 	}
 
+	for _, pin := range key.CertPins {
+		if len(pin) != sha256.Size {
+			return nil, fmt.Errorf("cert pins must be SHA-256 digests")
+		}
+	}
+
 	return key, nil
 }
 
@@ -39,6 +39,14 @@ func (v2Schema) Version() int {
 	return 2
 }
 
+// v3Schema embeds in other structs to indicate their schema version is 3.
+type v3Schema struct{}
+
+// Version returns the schema version of structs that embed this type.
+func (v3Schema) Version() int {
+	return 3
+}
+
 // User is the body of a user object
 type User struct { // type: 0x01
 	v1Schema
@@ -152,7 +160,21 @@ func NewClaim(orgID, ownerID, previous, pubKeyID *identity.ID, claimType ClaimTy
 
 // Credential is a secret value shared between a group of services based
 // on users identity, operating environment, project, and organization
+//
+// Version 3 Credentials record when they were created, and carry optional,
+// unencrypted metadata describing the secret.
 type Credential struct { // type: 0x0b
+	v3Schema
+	immutable
+	BaseCredential
+	State    *string             `json:"state"`
+	Metadata *CredentialMetadata `json:"metadata"`
+	Created  *time.Time          `json:"created_at"`
+}
+
+// CredentialV2 is a secret value shared between a group of services based
+// on users identity, operating environment, project, and organization
+type CredentialV2 struct { // type: 0x0b
 	v2Schema
 	immutable
 	BaseCredential
@@ -181,6 +203,15 @@ type BaseCredential struct {
 	CredentialVersion int              `json:"version"`
 }
 
+// CredentialMetadata describes a Credential. It is signed along with the
+// Credential, but is not encrypted.
+type CredentialMetadata struct {
+	Description string     `json:"description"`
+	Owner       string     `json:"owner"`
+	Tags        []string   `json:"tags"`
+	Expires     *time.Time `json:"expires_at"`
+}
+
 // CredentialValue is the secretbox encrypted value of the containing
 // Credential.
 type CredentialValue struct {
@@ -2,12 +2,12 @@
 package registry
 
 import (
+	"bytes"
 	"context"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"time"
-
-	"github.com/manifoldco/torus-cli/apitypes"
 )
 
 // TokenHolder holds an authorization token
@@ -40,9 +40,14 @@ type Client struct {
 	Version         *VersionClient
 }
 
-// NewClient returns a new Client.
+// NewClient returns a new Client. Requests are timed out and retried
+// according to the given policy, or DefaultRetryPolicy if it is nil.
 func NewClient(prefix string, apiVersion string, version string,
-	token TokenHolder, t *http.Transport) *Client {
+	token TokenHolder, t *http.Transport, policy *RetryPolicy) *Client {
+
+	if policy == nil {
+		policy = &DefaultRetryPolicy
+	}
 
 	rt := &registryRoundTripper{
 		DefaultRoundTripper: DefaultRoundTripper{
@@ -53,6 +58,8 @@ func NewClient(prefix string, apiVersion string, version string,
 		apiVersion: apiVersion,
 		version:    version,
 		holder:     token,
+		policy:     *policy,
+		breaker:    newCircuitBreaker(policy.BreakerThreshold, policy.BreakerCooldown),
 	}
 
 	return NewClientWithRoundTripper(rt)
@@ -95,6 +102,8 @@ type registryRoundTripper struct {
 	apiVersion string
 	version    string
 	holder     TokenHolder
+	policy     RetryPolicy
+	breaker    *circuitBreaker
 }
 
 // Augment the default NewRequest to set additional required headers
@@ -117,26 +126,92 @@ func (rt *registryRoundTripper) NewRequest(method, path string,
 	return req, nil
 }
 
-// Augment the default Do to set a timeout.
+// Augment the default Do to time out each attempt, retry idempotent requests
+// that fail transiently, and fail fast while the registry is unavailable.
 func (rt *registryRoundTripper) Do(ctx context.Context, r *http.Request,
 	v interface{}) (*http.Response, error) {
 
-	ctx, cancelFunc := context.WithTimeout(ctx, 6*time.Second)
-	r = r.WithContext(ctx)
-	defer cancelFunc()
+	// The body is read up front, so it can be sent again on retry.
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	resp, err := rt.DefaultRoundTripper.Do(ctx, r, v)
-	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			err = &apitypes.Error{
-				StatusCode: http.StatusRequestTimeout,
-				Type:       "request_timeout",
-				Err:        []string{"Request timed out"},
+	retries := 0
+	if isIdempotent(r.Method) {
+		retries = rt.policy.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		if !rt.breaker.Allow() {
+			return nil, unavailableError()
+		}
+
+		resp, err := rt.attempt(ctx, r, body, v)
+		if ctx.Err() != nil {
+			rt.breaker.Cancel()
+			return nil, err
+		}
+
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+
+		if err == nil || (resp != nil && !isRetryableStatus(status)) {
+			rt.breaker.Success()
+			if err != nil {
+				return nil, err
 			}
+			return resp, nil
 		}
 
-		return nil, err
+		// Rate limiting means the registry is up.
+		if status == http.StatusTooManyRequests {
+			rt.breaker.Success()
+		} else {
+			rt.breaker.Failure()
+		}
+
+		if attempt >= retries {
+			return nil, err
+		}
+
+		delay, ok := retryAfter(resp, time.Now())
+		if !ok {
+			delay = rt.policy.backoff(attempt + 1)
+		} else if delay > rt.policy.MaxBackoff {
+			return nil, err
+		}
+
+		if wait(ctx, delay) != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt makes a single attempt at the request, with its own timeout.
+func (rt *registryRoundTripper) attempt(ctx context.Context, r *http.Request,
+	body []byte, v interface{}) (*http.Response, error) {
+
+	actx, cancelFunc := context.WithTimeout(ctx, rt.policy.Timeout)
+	defer cancelFunc()
+
+	req := r.WithContext(actx)
+	req.Body = nil
+	if len(body) > 0 {
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := rt.DefaultRoundTripper.Do(actx, req, v)
+	if err != nil && ctx.Err() == nil && actx.Err() == context.DeadlineExceeded {
+		err = timeoutError()
 	}
 
-	return resp, nil
+	return resp, err
 }
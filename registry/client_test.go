@@ -0,0 +1,248 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+// flakyRegistry is a stand-in registry that fails the first failures
+// requests it receives with the given status, then succeeds.
+type flakyRegistry struct {
+	failures   int32
+	status     int
+	retryAfter string
+	delay      time.Duration
+
+	requests int32
+}
+
+func (f *flakyRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := atomic.AddInt32(&f.requests, 1)
+	if n <= f.failures {
+		if f.delay > 0 {
+			time.Sleep(f.delay)
+		}
+		if f.retryAfter != "" {
+			w.Header().Set("Retry-After", f.retryAfter)
+		}
+		w.WriteHeader(f.status)
+		return
+	}
+
+	json.NewEncoder(w).Encode(&apitypes.Version{Version: "1.0.0"})
+}
+
+func testRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		Timeout:          time.Second,
+		MaxRetries:       3,
+		Backoff:          time.Millisecond,
+		MaxBackoff:       5 * time.Millisecond,
+		BreakerThreshold: 10,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+func newTestClient(h http.Handler, p *RetryPolicy) (*Client, func()) {
+	srv := httptest.NewServer(h)
+	c := NewClient(srv.URL, "0.1.0", "test", staticToken("session"), &http.Transport{}, p)
+	return c, srv.Close
+}
+
+func TestRegistryRoundTripperRetries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		reg := &flakyRegistry{failures: 2, status: http.StatusServiceUnavailable}
+		c, done := newTestClient(reg, testRetryPolicy())
+		defer done()
+
+		v, err := c.Version.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Version != "1.0.0" {
+			t.Errorf("got version %s, want 1.0.0", v.Version)
+		}
+		if reg.requests != 3 {
+			t.Errorf("got %d requests, want 3", reg.requests)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		reg := &flakyRegistry{failures: 10, status: http.StatusBadGateway}
+		c, done := newTestClient(reg, testRetryPolicy())
+		defer done()
+
+		_, err := c.Version.Get(ctx)
+		if err == nil {
+			t.Fatal("expected an error, got none")
+		}
+		if reg.requests != 4 {
+			t.Errorf("got %d requests, want 4", reg.requests)
+		}
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		reg := &flakyRegistry{failures: 10, status: http.StatusInternalServerError}
+		c, done := newTestClient(reg, testRetryPolicy())
+		defer done()
+
+		_, err := c.Version.Get(ctx)
+		if err == nil {
+			t.Fatal("expected an error, got none")
+		}
+		if reg.requests != 1 {
+			t.Errorf("got %d requests, want 1", reg.requests)
+		}
+	})
+
+	t.Run("does not retry non-idempotent requests", func(t *testing.T) {
+		reg := &flakyRegistry{failures: 10, status: http.StatusServiceUnavailable}
+		c, done := newTestClient(reg, testRetryPolicy())
+		defer done()
+
+		_, err := c.Orgs.Create(ctx, "org")
+		if err == nil {
+			t.Fatal("expected an error, got none")
+		}
+		if reg.requests != 1 {
+			t.Errorf("got %d requests, want 1", reg.requests)
+		}
+	})
+
+	t.Run("honors retry-after", func(t *testing.T) {
+		reg := &flakyRegistry{failures: 1, status: http.StatusTooManyRequests, retryAfter: "1"}
+		p := testRetryPolicy()
+		p.MaxBackoff = 2 * time.Second
+		c, done := newTestClient(reg, p)
+		defer done()
+
+		start := time.Now()
+		_, err := c.Version.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retried after %s, want at least 1s", elapsed)
+		}
+	})
+
+	t.Run("gives up when retry-after exceeds max backoff", func(t *testing.T) {
+		reg := &flakyRegistry{failures: 1, status: http.StatusServiceUnavailable, retryAfter: "60"}
+		c, done := newTestClient(reg, testRetryPolicy())
+		defer done()
+
+		_, err := c.Version.Get(ctx)
+		if err == nil {
+			t.Fatal("expected an error, got none")
+		}
+		if reg.requests != 1 {
+			t.Errorf("got %d requests, want 1", reg.requests)
+		}
+	})
+
+	t.Run("times out each attempt", func(t *testing.T) {
+		reg := &flakyRegistry{failures: 1, status: http.StatusOK, delay: 200 * time.Millisecond}
+		p := testRetryPolicy()
+		p.Timeout = 50 * time.Millisecond
+		c, done := newTestClient(reg, p)
+		defer done()
+
+		_, err := c.Version.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reg.requests != 2 {
+			t.Errorf("got %d requests, want 2", reg.requests)
+		}
+	})
+
+	t.Run("reports timeouts", func(t *testing.T) {
+		reg := &flakyRegistry{failures: 10, status: http.StatusOK, delay: 200 * time.Millisecond}
+		p := testRetryPolicy()
+		p.Timeout = 20 * time.Millisecond
+		p.MaxRetries = 0
+		c, done := newTestClient(reg, p)
+		defer done()
+
+		_, err := c.Version.Get(ctx)
+		apiErr, ok := err.(*apitypes.Error)
+		if !ok || apiErr.StatusCode != http.StatusRequestTimeout {
+			t.Errorf("got error %v, want a request timeout", err)
+		}
+	})
+}
+
+func TestRegistryRoundTripperCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	reg := &flakyRegistry{failures: 4, status: http.StatusServiceUnavailable}
+	p := testRetryPolicy()
+	p.MaxRetries = 1
+	p.BreakerThreshold = 3
+	c, done := newTestClient(reg, p)
+	defer done()
+
+	now := time.Now()
+	rt := c.Version.client.(*registryRoundTripper)
+	rt.breaker.now = func() time.Time { return now }
+
+	// Two failed attempts, then a third that opens the breaker, which stops
+	// the retry.
+	c.Version.Get(ctx)
+	_, err := c.Version.Get(ctx)
+	if reg.requests != 3 {
+		t.Errorf("got %d requests, want 3", reg.requests)
+	}
+	apiErr, ok := err.(*apitypes.Error)
+	if !ok || apiErr.Type != "registry_unavailable" {
+		t.Errorf("got error %v, want registry_unavailable", err)
+	}
+
+	t.Run("fails fast while open", func(t *testing.T) {
+		_, err := c.Version.Get(ctx)
+		if err == nil {
+			t.Fatal("expected an error, got none")
+		}
+		if reg.requests != 3 {
+			t.Errorf("got %d requests, want 3", reg.requests)
+		}
+	})
+
+	t.Run("probes after cooldown", func(t *testing.T) {
+		now = now.Add(p.BreakerCooldown)
+
+		// The probe fails, reopening the breaker without a retry
+		_, err := c.Version.Get(ctx)
+		if err == nil {
+			t.Fatal("expected an error, got none")
+		}
+		if reg.requests != 4 {
+			t.Errorf("got %d requests, want 4", reg.requests)
+		}
+
+		now = now.Add(p.BreakerCooldown)
+		_, err = c.Version.Get(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if reg.requests != 5 {
+			t.Errorf("got %d requests, want 5", reg.requests)
+		}
+	})
+
+	t.Run("closes after success", func(t *testing.T) {
+		_, err := c.Version.Get(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
@@ -133,22 +133,7 @@ func (c *CredentialGraphClient) getGraph(ctx context.Context, query url.Values)
 	for i, g := range resp {
 		creds := make([]envelope.CredentialInf, len(g.Credentials))
 		for i, ec := range g.Credentials {
-			switch ec.Body.(type) {
-			case *primitive.CredentialV1:
-				creds[i] = &envelope.CredentialV1{
-					ID:        ec.ID,
-					Version:   ec.Version,
-					Signature: ec.Signature,
-					Body:      ec.Body.(*primitive.CredentialV1),
-				}
-			case *primitive.Credential:
-				creds[i] = &envelope.Credential{
-					ID:        ec.ID,
-					Version:   ec.Version,
-					Signature: ec.Signature,
-					Body:      ec.Body.(*primitive.Credential),
-				}
-			}
+			creds[i] = credentialFromSigned(&ec)
 		}
 
 		if g.Keyring.Version == 1 {
@@ -195,3 +180,33 @@ func (c *CredentialGraphClient) getGraph(ctx context.Context, query url.Values)
 
 	return converted, nil
 }
+
+// credentialFromSigned converts the signed envelope into the Credential
+// envelope matching its body's schema version.
+func credentialFromSigned(ec *envelope.Signed) envelope.CredentialInf {
+	switch b := ec.Body.(type) {
+	case *primitive.CredentialV1:
+		return &envelope.CredentialV1{
+			ID:        ec.ID,
+			Version:   ec.Version,
+			Signature: ec.Signature,
+			Body:      b,
+		}
+	case *primitive.CredentialV2:
+		return &envelope.CredentialV2{
+			ID:        ec.ID,
+			Version:   ec.Version,
+			Signature: ec.Signature,
+			Body:      b,
+		}
+	case *primitive.Credential:
+		return &envelope.Credential{
+			ID:        ec.ID,
+			Version:   ec.Version,
+			Signature: ec.Signature,
+			Body:      b,
+		}
+	}
+
+	return nil
+}
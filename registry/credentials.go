@@ -2,6 +2,7 @@ package registry
 
 import (
 	"context"
+	"errors"
 	"log"
 
 	"github.com/manifoldco/torus-cli/envelope"
@@ -14,18 +15,23 @@ type Credentials struct {
 }
 
 // Create creates the provided credential in the registry.
-func (c *Credentials) Create(ctx context.Context, credential *envelope.Credential) (*envelope.Credential, error) {
+func (c *Credentials) Create(ctx context.Context, credential envelope.CredentialInf) (envelope.CredentialInf, error) {
 	req, err := c.client.NewRequest("POST", "/credentials", nil, credential)
 	if err != nil {
 		log.Printf("Error building http request: %s", err)
 		return nil, err
 	}
 
-	resp := &envelope.Credential{}
+	resp := &envelope.Signed{}
 	_, err = c.client.Do(ctx, req, resp)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp, nil
+	cred := credentialFromSigned(resp)
+	if cred == nil {
+		return nil, errors.New("Unexpected primitive type in credential response")
+	}
+
+	return cred, nil
 }
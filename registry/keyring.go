@@ -28,6 +28,7 @@ type KeyringSection interface {
 	GetKeyring() envelope.KeyringInf
 	KeyringVersion() int
 	FindMember(*identity.ID) (*primitive.KeyringMember, *primitive.MEKShare, error)
+	FindMemberByKey(ownerID, publicKeyID *identity.ID) (*primitive.KeyringMember, *primitive.MEKShare, error)
 	HasRevocations() bool
 }
 
@@ -77,6 +78,30 @@ func (k *KeyringSectionV1) FindMember(id *identity.ID) (*primitive.KeyringMember
 	return krm, mekshare, nil
 }
 
+// FindMemberByKey returns the membership and mekshare for the given user id,
+// encrypted for the given public key. The data is returned in V2 format.
+func (k *KeyringSectionV1) FindMemberByKey(ownerID, publicKeyID *identity.ID) (*primitive.KeyringMember, *primitive.MEKShare, error) {
+	for _, m := range k.Members {
+		if *m.Body.OwnerID != *ownerID || *m.Body.PublicKeyID != *publicKeyID {
+			continue
+		}
+
+		krm := &primitive.KeyringMember{
+			OrgID:           m.Body.OrgID,
+			KeyringID:       m.Body.KeyringID,
+			OwnerID:         m.Body.OwnerID,
+			PublicKeyID:     m.Body.PublicKeyID,
+			EncryptingKeyID: m.Body.EncryptingKeyID,
+		}
+		mekshare := &primitive.MEKShare{
+			Key: m.Body.Key,
+		}
+		return krm, mekshare, nil
+	}
+
+	return nil, nil, ErrMemberNotFound
+}
+
 // HasRevocations indicates that a Keyring holds revoked user keys. We don't
 // track in V1 so it is always false.
 func (KeyringSectionV1) HasRevocations() bool {
@@ -140,6 +165,32 @@ outerLoop:
 	return krm, mekshare, nil
 }
 
+// FindMemberByKey returns the unrevoked membership and mekshare for the given
+// user id, encrypted for the given public key.
+func (k *KeyringSectionV2) FindMemberByKey(ownerID, publicKeyID *identity.ID) (*primitive.KeyringMember, *primitive.MEKShare, error) {
+outerLoop:
+	for _, m := range k.Members {
+		body := m.Member.Body
+		if *body.OwnerID != *ownerID || *body.PublicKeyID != *publicKeyID {
+			continue
+		}
+
+		for _, c := range k.Claims {
+			if *c.Body.KeyringMemberID == *m.Member.ID && c.Body.ClaimType == primitive.RevocationClaimType {
+				continue outerLoop
+			}
+		}
+
+		var mekshare *primitive.MEKShare
+		if m.MEKShare != nil {
+			mekshare = m.MEKShare.Body
+		}
+		return body, mekshare, nil
+	}
+
+	return nil, nil, ErrMemberNotFound
+}
+
 // HasRevocations indicates that a Keyring holds revoked user keys.
 func (k *KeyringSectionV2) HasRevocations() bool {
 	for _, claim := range k.Claims {
@@ -120,3 +120,121 @@ func (m *MachinesClient) Destroy(ctx context.Context, machineID *identity.ID) er
 
 	return err
 }
+
+// CreateToken requests the registry to add a token to an existing machine.
+func (m *MachinesClient) CreateToken(ctx context.Context, machineID *identity.ID,
+	token *MachineTokenCreationSegment) (*apitypes.MachineTokenSegment, error) {
+
+	req, err := m.client.NewRequest("POST", "/machines/"+machineID.String()+"/tokens", nil, token)
+	if err != nil {
+		log.Printf("Error building POST Machine Tokens Request: %s", err)
+		return nil, err
+	}
+
+	resp := &apitypes.MachineTokenSegment{}
+	_, err = m.client.Do(ctx, req, resp)
+	if err != nil {
+		log.Printf("Failed to create machine token: %s", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DestroyToken destroys the token with the given ID belonging to the machine,
+// moving it to the destroyed state.
+func (m *MachinesClient) DestroyToken(ctx context.Context, machineID, tokenID *identity.ID) error {
+	req, err := m.client.NewRequest("DELETE",
+		"/machines/"+machineID.String()+"/tokens/"+tokenID.String(), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.client.Do(ctx, req, nil)
+
+	return err
+}
+
+// MachineEnrollmentSegment represents the request sent to the registry to
+// create a machine and its first token using an enrollment code.
+type MachineEnrollmentSegment struct {
+	MachineCreationSegment
+	Code string `json:"code"`
+}
+
+// CreateEnrollmentCode requests the registry to create an enrollment code for
+// a machine role.
+func (m *MachinesClient) CreateEnrollmentCode(ctx context.Context,
+	ecr *apitypes.MachineEnrollmentCodeCreateRequest) (*apitypes.MachineEnrollmentCode, error) {
+
+	req, err := m.client.NewRequest("POST", "/machines/enrollment-codes", nil, ecr)
+	if err != nil {
+		log.Printf("Error building POST Enrollment Codes Request: %s", err)
+		return nil, err
+	}
+
+	resp := &apitypes.MachineEnrollmentCode{}
+	_, err = m.client.Do(ctx, req, resp)
+	if err != nil {
+		log.Printf("Failed to create enrollment code: %s", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetEnrollmentCode looks up an enrollment code. The code itself grants
+// access, so no authorization token is required.
+func (m *MachinesClient) GetEnrollmentCode(ctx context.Context,
+	code string) (*apitypes.MachineEnrollmentCode, error) {
+
+	v := &url.Values{}
+	v.Set("code", code)
+
+	req, err := m.client.NewRequest("GET", "/machines/enrollment-codes", v, nil)
+	if err != nil {
+		log.Printf("Error building GET Enrollment Codes Request: %s", err)
+		return nil, err
+	}
+
+	resp := &apitypes.MachineEnrollmentCode{}
+	_, err = m.client.Do(ctx, req, resp)
+	if err != nil {
+		log.Printf("Failed to retrieve enrollment code: %s", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Enroll requests the registry to create a machine and its first token,
+// authorized by an enrollment code rather than a session.
+func (m *MachinesClient) Enroll(ctx context.Context, code string, machine *envelope.Machine,
+	memberships []envelope.Membership, token *MachineTokenCreationSegment) (*apitypes.MachineSegment, error) {
+
+	segment := MachineEnrollmentSegment{
+		MachineCreationSegment: MachineCreationSegment{
+			MachineSegment: apitypes.MachineSegment{
+				Machine:     machine,
+				Memberships: memberships,
+			},
+			Tokens: []MachineTokenCreationSegment{*token},
+		},
+		Code: code,
+	}
+
+	req, err := m.client.NewRequest("POST", "/machines/enroll", nil, &segment)
+	if err != nil {
+		log.Printf("Error building POST Machines Enroll Request: %s", err)
+		return nil, err
+	}
+
+	resp := &apitypes.MachineSegment{}
+	_, err = m.client.Do(ctx, req, resp)
+	if err != nil {
+		log.Printf("Failed to enroll machine: %s", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+type staticToken string
+
+func (t staticToken) Token() string { return string(t) }
+
+// enrollmentRegistry is a stand-in for the registry's enrollment code
+// endpoints.
+type enrollmentRegistry struct {
+	codes map[string]*apitypes.MachineEnrollmentCode
+}
+
+func (e *enrollmentRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fail := func(status int, msg string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(&apitypes.Error{
+			StatusCode: status,
+			Type:       apitypes.BadRequestError,
+			Err:        []string{msg},
+		})
+	}
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/machines/enrollment-codes":
+		if r.Header.Get("Authorization") == "" {
+			fail(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		req := apitypes.MachineEnrollmentCodeCreateRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		code := &apitypes.MachineEnrollmentCode{
+			Code:    "0123456789",
+			OrgID:   req.OrgID,
+			TeamID:  req.TeamID,
+			Expires: req.Expires,
+			Uses:    req.Uses,
+		}
+		e.codes[code.Code] = code
+		json.NewEncoder(w).Encode(code)
+
+	case r.Method == "GET" && r.URL.Path == "/machines/enrollment-codes":
+		code, ok := e.codes[r.URL.Query().Get("code")]
+		if !ok {
+			fail(http.StatusNotFound, "enrollment code not found")
+			return
+		}
+		json.NewEncoder(w).Encode(code)
+
+	case r.Method == "POST" && r.URL.Path == "/machines/enroll":
+		if r.Header.Get("Authorization") != "" {
+			fail(http.StatusBadRequest, "enrollment does not use a session")
+			return
+		}
+
+		req := MachineEnrollmentSegment{}
+		json.NewDecoder(r.Body).Decode(&req)
+		code, ok := e.codes[req.Code]
+		if !ok || code.Uses < 1 || time.Now().After(code.Expires) {
+			fail(http.StatusBadRequest, "invalid enrollment code")
+			return
+		}
+		code.Uses--
+
+		json.NewEncoder(w).Encode(&apitypes.MachineSegment{
+			Machine:     req.Machine,
+			Memberships: req.Memberships,
+			Tokens: []apitypes.MachineTokenSegment{
+				{Token: req.Tokens[0].Token},
+			},
+		})
+
+	default:
+		fail(http.StatusNotFound, "not found")
+	}
+}
+
+func TestMachineEnrollment(t *testing.T) {
+	stand := &enrollmentRegistry{codes: make(map[string]*apitypes.MachineEnrollmentCode)}
+	srv := httptest.NewServer(stand)
+	defer srv.Close()
+
+	admin := NewClient(srv.URL, "0.1.0", "test", staticToken("session"), &http.Transport{}, nil)
+	host := NewClient(srv.URL, "0.1.0", "test", staticToken(""), &http.Transport{}, nil)
+	ctx := context.Background()
+
+	orgID, err := identity.NewMutable(&primitive.Org{Name: "org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := admin.Machines.CreateEnrollmentCode(ctx, &apitypes.MachineEnrollmentCodeCreateRequest{
+		OrgID:   &orgID,
+		Expires: time.Now().Add(time.Minute),
+		Uses:    1,
+	})
+	if err != nil {
+		t.Fatal("could not create code:", err)
+	}
+
+	code, err := host.Machines.GetEnrollmentCode(ctx, created.Code)
+	if err != nil {
+		t.Fatal("could not retrieve code:", err)
+	}
+	if *code.OrgID != orgID || code.Uses != 1 {
+		t.Errorf("unexpected code: %+v", code)
+	}
+
+	machineBody := &primitive.Machine{Name: "ci-1", OrgID: &orgID}
+	machineID, err := identity.NewMutable(machineBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := &envelope.Machine{ID: &machineID, Version: 1, Body: machineBody}
+	token := &MachineTokenCreationSegment{Token: &envelope.MachineToken{
+		Version: 1,
+		Body:    &primitive.MachineToken{OrgID: &orgID, MachineID: &machineID},
+	}}
+
+	segment, err := host.Machines.Enroll(ctx, code.Code, machine, nil, token)
+	if err != nil {
+		t.Fatal("could not enroll:", err)
+	}
+	if *segment.Machine.ID != machineID || len(segment.Tokens) != 1 {
+		t.Errorf("unexpected machine segment: %+v", segment)
+	}
+
+	_, err = host.Machines.Enroll(ctx, code.Code, machine, nil, token)
+	if err == nil {
+		t.Fatal("expected used up code to be rejected")
+	}
+	if rErr, ok := err.(*apitypes.Error); !ok || rErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	_, err = host.Machines.GetEnrollmentCode(ctx, "unknown")
+	if err == nil {
+		t.Error("expected unknown code to be rejected")
+	}
+}
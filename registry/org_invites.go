@@ -159,3 +159,30 @@ func (o *OrgInvitesClient) Associate(ctx context.Context, org, email, code strin
 	_, err = o.client.Do(ctx, req, &invite)
 	return &invite, err
 }
+
+// Revoke cancels an invite that has not yet been approved
+func (o *OrgInvitesClient) Revoke(ctx context.Context, inviteID *identity.ID) error {
+	req, err := o.client.NewRequest("DELETE", "/org-invites/"+inviteID.String(), nil, nil)
+	if err != nil {
+		log.Printf("Error building DELETE /org-invites/:id request: %s", err)
+		return err
+	}
+
+	_, err = o.client.Do(ctx, req, nil)
+	return err
+}
+
+// Resend sends the email for an invite again, with a new code. The invite's
+// expiry starts over from when it is resent.
+func (o *OrgInvitesClient) Resend(ctx context.Context, inviteID *identity.ID) (*envelope.OrgInvite, error) {
+	path := "/org-invites/" + inviteID.String() + "/resend"
+	req, err := o.client.NewRequest("POST", path, nil, nil)
+	if err != nil {
+		log.Printf("Error building POST /org-invites/:id/resend request: %s", err)
+		return nil, err
+	}
+
+	invite := envelope.OrgInvite{}
+	_, err = o.client.Do(ctx, req, &invite)
+	return &invite, err
+}
@@ -0,0 +1,384 @@
+package registrytest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
+	"github.com/manifoldco/torus-cli/registry"
+)
+
+// keyring is a keyring, along with its members, their claims, and the
+// credentials stored in it. Only one of membersV1 and members is used,
+// depending on the keyring's schema version.
+type keyring struct {
+	keyring     *envelope.Signed
+	membersV1   []envelope.KeyringMemberV1
+	members     []registry.KeyringMember
+	claims      []envelope.KeyringMemberClaim
+	credentials []envelope.Signed
+}
+
+// keyringSection is a keyring as returned by the registry. The members are
+// either v1 or v2 keyring members, matching the keyring's version.
+type keyringSection struct {
+	Keyring     *envelope.Signed              `json:"keyring"`
+	Members     interface{}                   `json:"members"`
+	Claims      []envelope.KeyringMemberClaim `json:"claims"`
+	Credentials *[]envelope.Signed            `json:"credentials,omitempty"`
+}
+
+// credentialGraph is a keyring section, and credentials, as posted by the
+// client. Members are always v2.
+type credentialGraph struct {
+	Keyring     *envelope.Signed              `json:"keyring"`
+	Members     []registry.KeyringMember      `json:"members"`
+	Claims      []envelope.KeyringMemberClaim `json:"claims"`
+	Credentials []envelope.Signed             `json:"credentials"`
+}
+
+func (k *keyring) body() *primitive.BaseKeyring {
+	switch b := k.keyring.Body.(type) {
+	case *primitive.KeyringV1:
+		return &b.BaseKeyring
+	case *primitive.Keyring:
+		return &b.BaseKeyring
+	}
+
+	panic("unknown keyring body type")
+}
+
+// hasMember returns whether the owner has a share of the keyring's master
+// encryption key.
+func (k *keyring) hasMember(ownerID *identity.ID) bool {
+	for _, m := range k.membersV1 {
+		if *m.Body.OwnerID == *ownerID {
+			return true
+		}
+	}
+
+	for _, m := range k.members {
+		if *m.Member.Body.OwnerID == *ownerID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// section returns the keyring as seen by the given session. MEKShares are only
+// included for the session's own memberships.
+func (k *keyring) section(r *request, withCredentials bool) keyringSection {
+	section := keyringSection{Keyring: k.keyring, Claims: k.claims}
+	if section.Claims == nil {
+		section.Claims = []envelope.KeyringMemberClaim{}
+	}
+
+	if k.keyring.Version == 1 {
+		members := k.membersV1
+		if members == nil {
+			members = []envelope.KeyringMemberV1{}
+		}
+		section.Members = members
+	} else {
+		members := make([]registry.KeyringMember, len(k.members))
+		for i, m := range k.members {
+			members[i].Member = m.Member
+			if *m.Member.Body.OwnerID == r.session.auth {
+				members[i].MEKShare = m.MEKShare
+			}
+		}
+		section.Members = members
+	}
+
+	if withCredentials {
+		creds := k.credentials
+		if creds == nil {
+			creds = []envelope.Signed{}
+		}
+		section.Credentials = &creds
+	}
+
+	return section
+}
+
+func (s *Server) findKeyring(id *identity.ID) *keyring {
+	for _, k := range s.keyrings {
+		if *k.keyring.ID == *id {
+			return k
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) listKeyrings(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	ownerID, err := r.queryID("owner_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	sections := []keyringSection{}
+	for _, k := range s.keyrings {
+		b := k.body()
+		if !containsID(orgIDs, b.OrgID) || !s.isMember(b.OrgID, &r.session.identity) {
+			continue
+		}
+		if ownerID != nil && !k.hasMember(ownerID) {
+			continue
+		}
+
+		sections = append(sections, k.section(r, false))
+	}
+
+	return http.StatusOK, sections, nil
+}
+
+func (s *Server) createKeyringMembers(r *request) (int, interface{}, error) {
+	id, err := r.paramID("id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	k := s.findKeyring(id)
+	if k == nil || !s.isMember(k.body().OrgID, &r.session.identity) {
+		return 0, nil, notFound("keyring not found")
+	}
+
+	if k.keyring.Version == 1 {
+		return 0, nil, badRequest("v2 members can not be added to a v1 keyring")
+	}
+
+	members := []registry.KeyringMember{}
+	err = r.decode(&members)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, m := range members {
+		if m.Member == nil || m.MEKShare == nil || *m.Member.Body.KeyringID != *id {
+			return 0, nil, badRequest("invalid keyring member")
+		}
+	}
+
+	k.members = append(k.members, members...)
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) createKeyringMembersV1(r *request) (int, interface{}, error) {
+	members := []envelope.KeyringMemberV1{}
+	err := r.decode(&members)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, m := range members {
+		k := s.findKeyring(m.Body.KeyringID)
+		if k == nil || !s.isMember(k.body().OrgID, &r.session.identity) {
+			return 0, nil, notFound("keyring not found")
+		}
+		if k.keyring.Version != 1 {
+			return 0, nil, badRequest("v1 members can only be added to a v1 keyring")
+		}
+	}
+
+	for _, m := range members {
+		k := s.findKeyring(m.Body.KeyringID)
+		k.membersV1 = append(k.membersV1, m)
+	}
+
+	return http.StatusCreated, members, nil
+}
+
+func (s *Server) createCredentialGraph(r *request) (int, interface{}, error) {
+	graph := credentialGraph{}
+	err := r.decode(&graph)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if graph.Keyring == nil {
+		return 0, nil, badRequest("keyring is required")
+	}
+
+	k := &keyring{
+		keyring: graph.Keyring,
+		members: graph.Members,
+		claims:  graph.Claims,
+	}
+
+	b, ok := graph.Keyring.Body.(*primitive.Keyring)
+	if !ok {
+		return 0, nil, badRequest("only v2 keyrings can be created")
+	}
+
+	err = s.checkOrg(r, b.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, m := range graph.Members {
+		if m.Member == nil || *m.Member.Body.KeyringID != *graph.Keyring.ID {
+			return 0, nil, badRequest("invalid keyring member")
+		}
+	}
+
+	for _, c := range graph.Credentials {
+		if credentialKeyringID(&c) == nil || *credentialKeyringID(&c) != *graph.Keyring.ID {
+			return 0, nil, badRequest("credential does not belong to the keyring")
+		}
+	}
+	k.credentials = graph.Credentials
+
+	s.keyrings = append(s.keyrings, k)
+
+	// Credentials are not returned, as the client decodes the response into a
+	// concrete keyring section.
+	return http.StatusCreated, k.section(r, false), nil
+}
+
+func (s *Server) listCredentialGraphs(r *request) (int, interface{}, error) {
+	q := r.URL.Query()
+
+	ownerID, err := r.queryID("owner_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var match func(*pathexp.PathExp) bool
+	switch {
+	case q.Get("path") != "":
+		parts := strings.Split(strings.Trim(q.Get("path"), "/"), "/")
+		if len(parts) != 6 {
+			return 0, nil, badRequest("invalid path")
+		}
+		match = func(pe *pathexp.PathExp) bool { return matchPath(pe, parts) }
+	case q.Get("pathexp") != "":
+		query, err := pathexp.ParsePartial(q.Get("pathexp"))
+		if err != nil {
+			return 0, nil, badRequest("invalid pathexp")
+		}
+
+		if q.Get("mode") == "contains" {
+			match = func(pe *pathexp.PathExp) bool { return matchContains(pe, query) }
+		} else {
+			match = func(pe *pathexp.PathExp) bool { return pe.Equal(query) }
+		}
+	default:
+		return 0, nil, badRequest("path or pathexp is required")
+	}
+
+	graphs := []keyringSection{}
+	for _, k := range s.keyrings {
+		b := k.body()
+		if !s.isMember(b.OrgID, &r.session.identity) || !match(b.PathExp) {
+			continue
+		}
+		if ownerID != nil && !k.hasMember(ownerID) {
+			continue
+		}
+
+		graphs = append(graphs, k.section(r, true))
+	}
+
+	return http.StatusOK, graphs, nil
+}
+
+func (s *Server) createCredential(r *request) (int, interface{}, error) {
+	cred := envelope.Signed{}
+	err := r.decode(&cred)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	keyringID := credentialKeyringID(&cred)
+	if keyringID == nil {
+		return 0, nil, badRequest("invalid credential")
+	}
+
+	k := s.findKeyring(keyringID)
+	if k == nil || !s.isMember(k.body().OrgID, &r.session.identity) {
+		return 0, nil, notFound("keyring not found")
+	}
+
+	k.credentials = append(k.credentials, cred)
+	return http.StatusCreated, &cred, nil
+}
+
+// credentialKeyringID returns the id of the keyring a signed credential
+// belongs to, or nil if it is not a credential.
+func credentialKeyringID(cred *envelope.Signed) *identity.ID {
+	switch b := cred.Body.(type) {
+	case *primitive.Credential:
+		return b.KeyringID
+	case *primitive.CredentialV2:
+		return b.KeyringID
+	case *primitive.CredentialV1:
+		return b.KeyringID
+	}
+
+	return nil
+}
+
+// matchPath returns whether the pathexp contains the given path, split into
+// its org, project, environment, service, identity and instance parts.
+func matchPath(pe *pathexp.PathExp, parts []string) bool {
+	return pe.Org.Contains(parts[0]) && pe.Project.Contains(parts[1]) &&
+		pe.Envs.Contains(parts[2]) && pe.Services.Contains(parts[3]) &&
+		pe.Identities.Contains(parts[4]) && pe.Instances.Contains(parts[5])
+}
+
+// matchContains returns whether everything the pathexp covers is within the
+// query. Each alternative of the pathexp's segments must be contained by the
+// matching query segment.
+func matchContains(pe, query *pathexp.PathExp) bool {
+	if pe.Org.String() != query.Org.String() || pe.Project.String() != query.Project.String() {
+		return false
+	}
+
+	pairs := [][2]string{
+		{pe.Envs.String(), query.Envs.String()},
+		{pe.Services.String(), query.Services.String()},
+		{pe.Identities.String(), query.Identities.String()},
+		{pe.Instances.String(), query.Instances.String()},
+	}
+	for _, p := range pairs {
+		if p[1] == "*" {
+			continue
+		}
+
+		for _, alt := range strings.Split(strings.Trim(p[0], "[]"), "|") {
+			if !segmentContains(p[1], alt) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// segmentContains returns whether the raw query segment contains the raw
+// subject, which may itself end in a glob.
+func segmentContains(query, subject string) bool {
+	for _, q := range strings.Split(strings.Trim(query, "[]"), "|") {
+		switch {
+		case q == "*":
+			return true
+		case strings.HasSuffix(q, "*"):
+			if strings.HasPrefix(subject, strings.TrimSuffix(q, "*")) {
+				return true
+			}
+		case q == subject:
+			return true
+		}
+	}
+
+	return false
+}
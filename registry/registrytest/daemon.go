@@ -0,0 +1,130 @@
+package registrytest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/daemon"
+	"github.com/manifoldco/torus-cli/registry"
+)
+
+// Daemon is a torus daemon talking to a fake registry, running out of its own
+// temporary torus root.
+type Daemon struct {
+	Config *config.Config
+
+	// Client talks to the daemon, and through it to the fake registry.
+	Client *api.Client
+
+	daemon *daemon.Daemon
+	done   chan error
+}
+
+// StartDaemon starts a daemon that uses the fake registry, and waits for it
+// to accept requests. The caller should call Close when finished with it.
+func (s *Server) StartDaemon() (*Daemon, error) {
+	root, err := ioutil.TempDir("", "torus")
+	if err != nil {
+		return nil, err
+	}
+
+	registryURI, err := url.Parse(s.URL)
+	if err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+
+	cfg := &config.Config{
+		APIVersion: "0.2.0",
+		Version:    Version,
+
+		TorusRoot:  root,
+		SocketPath: filepath.Join(root, "daemon.socket"),
+		PidPath:    filepath.Join(root, "daemon.pid"),
+		DBPath:     filepath.Join(root, "daemon.db"),
+
+		RegistryURI: registryURI,
+
+		// Fail fast; the fake registry is either up, or the test is over.
+		RegistryRetry: &registry.RetryPolicy{Timeout: 10 * time.Second},
+	}
+
+	d, err := daemon.New(cfg, false)
+	if err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+
+	td := &Daemon{
+		Config: cfg,
+		Client: api.NewClient(cfg),
+		daemon: d,
+		done:   make(chan error, 1),
+	}
+
+	go func() { td.done <- d.Run() }()
+
+	err = td.wait()
+	if err != nil {
+		td.Close()
+		return nil, err
+	}
+
+	return td, nil
+}
+
+// wait polls the daemon until it answers, or fails to start.
+func (d *Daemon) wait() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for {
+		_, err := d.Client.Version.GetDaemon(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case err := <-d.done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+// Close shuts the daemon down, and removes its torus root.
+func (d *Daemon) Close() error {
+	err := d.daemon.Shutdown()
+	os.RemoveAll(d.Config.TorusRoot)
+	return err
+}
+
+// Signup creates a user with the given credentials, verifies their email
+// address, and logs the daemon in as them.
+func (d *Daemon) Signup(ctx context.Context, s *Server, username, email, password string) error {
+	_, err := d.Client.Users.Create(ctx, &apitypes.Signup{
+		Name:       username,
+		Username:   username,
+		Email:      email,
+		Passphrase: password,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	err = d.Client.Session.UserLogin(ctx, email, password)
+	if err != nil {
+		return err
+	}
+
+	return d.Client.Users.VerifyEmail(ctx, s.VerificationCode(email))
+}
@@ -0,0 +1,112 @@
+package registrytest
+
+import (
+	"net/http"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/registry"
+)
+
+type claimTree struct {
+	Org        *envelope.Org               `json:"org"`
+	PublicKeys []apitypes.PublicKeySegment `json:"public_keys"`
+}
+
+func (s *Server) createKeypair(r *request) (int, interface{}, error) {
+	kp := registry.ClaimedKeyPair{}
+	err := r.decode(&kp)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if kp.PublicKey == nil || kp.PrivateKey == nil || len(kp.Claims) != 1 {
+		return 0, nil, badRequest("a public key, private key and claim are required")
+	}
+
+	pk := kp.PublicKey.Body
+	err = s.checkOrg(r, pk.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Keypairs belong to the user, or to the machine token that made them.
+	if *pk.OwnerID != r.session.auth || *kp.PrivateKey.Body.OwnerID != r.session.auth {
+		return 0, nil, badRequest("keypair owner must match the session")
+	}
+
+	s.keypairs = append(s.keypairs, &kp)
+	return http.StatusCreated, &kp, nil
+}
+
+func (s *Server) listKeypairs(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	keypairs := []*registry.ClaimedKeyPair{}
+	for _, kp := range s.keypairs {
+		pk := kp.PublicKey.Body
+		if *pk.OwnerID == r.session.auth && containsID(orgIDs, pk.OrgID) {
+			keypairs = append(keypairs, kp)
+		}
+	}
+
+	return http.StatusOK, keypairs, nil
+}
+
+func (s *Server) createClaim(r *request) (int, interface{}, error) {
+	claim := envelope.Claim{}
+	err := r.decode(&claim)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if claim.ID == nil || claim.Body == nil {
+		return 0, nil, badRequest("invalid claim")
+	}
+
+	err = s.checkOrg(r, claim.Body.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, kp := range s.keypairs {
+		if *kp.PublicKey.ID == *claim.Body.PublicKeyID {
+			kp.Claims = append(kp.Claims, claim)
+			return http.StatusCreated, &claim, nil
+		}
+	}
+
+	return 0, nil, notFound("public key not found")
+}
+
+func (s *Server) getClaimTree(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	ownerIDs, err := r.queryIDs("owner_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	trees := []claimTree{}
+	for _, o := range s.orgs {
+		if !containsID(orgIDs, o.ID) || !s.isMember(o.ID, &r.session.identity) {
+			continue
+		}
+
+		tree := claimTree{Org: o, PublicKeys: []apitypes.PublicKeySegment{}}
+		for _, kp := range s.keypairs {
+			pk := kp.PublicKey.Body
+			if *pk.OrgID == *o.ID && containsID(ownerIDs, pk.OwnerID) {
+				tree.PublicKeys = append(tree.PublicKeys, kp.PublicKeySegment)
+			}
+		}
+		trees = append(trees, tree)
+	}
+
+	return http.StatusOK, trees, nil
+}
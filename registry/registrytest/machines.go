@@ -0,0 +1,286 @@
+package registrytest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+	"github.com/manifoldco/torus-cli/registry"
+)
+
+// machine is a machine and its tokens. Memberships and token keypairs are
+// kept with the rest of the org's, and looked up when a segment is built.
+type machine struct {
+	machine *envelope.Machine
+	tokens  []*apitypes.MachineTokenSegment
+}
+
+func (s *Server) findMachine(id *identity.ID) *machine {
+	for _, m := range s.machines {
+		if *m.machine.ID == *id {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// findMachineToken returns the machine token with the given id, and the
+// machine it belongs to.
+func (s *Server) findMachineToken(id *identity.ID) (*machine, *apitypes.MachineTokenSegment) {
+	for _, m := range s.machines {
+		for _, t := range m.tokens {
+			if *t.Token.ID == *id {
+				return m, t
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// segment returns the machine along with its memberships, tokens and the
+// tokens' keypairs.
+func (s *Server) segment(m *machine) *apitypes.MachineSegment {
+	segment := &apitypes.MachineSegment{
+		Machine:     m.machine,
+		Memberships: []envelope.Membership{},
+		Tokens:      []apitypes.MachineTokenSegment{},
+	}
+
+	for _, ms := range s.memberships {
+		if *ms.Body.OwnerID == *m.machine.ID {
+			segment.Memberships = append(segment.Memberships, *ms)
+		}
+	}
+
+	for _, t := range m.tokens {
+		segment.Tokens = append(segment.Tokens, s.tokenSegment(t))
+	}
+
+	return segment
+}
+
+func (s *Server) tokenSegment(t *apitypes.MachineTokenSegment) apitypes.MachineTokenSegment {
+	segment := *t
+	segment.Keypairs = []apitypes.PublicKeySegment{}
+	for _, kp := range s.keypairs {
+		if *kp.PublicKey.Body.OwnerID == *t.Token.ID {
+			segment.Keypairs = append(segment.Keypairs, kp.PublicKeySegment)
+		}
+	}
+
+	return segment
+}
+
+// addToken stores a new machine token and its keypairs.
+func (s *Server) addToken(m *machine, t *registry.MachineTokenCreationSegment) error {
+	if t.Token == nil || t.Token.Body == nil || *t.Token.Body.MachineID != *m.machine.ID {
+		return badRequest("invalid machine token")
+	}
+
+	for _, kp := range t.Keypairs {
+		if kp.PublicKey == nil || kp.PrivateKey == nil || *kp.PublicKey.Body.OwnerID != *t.Token.ID {
+			return badRequest("machine token keypairs must be owned by the token")
+		}
+	}
+
+	m.tokens = append(m.tokens, &apitypes.MachineTokenSegment{Token: t.Token})
+	s.keypairs = append(s.keypairs, t.Keypairs...)
+	return nil
+}
+
+func (s *Server) createMachine(r *request) (int, interface{}, error) {
+	req := registry.MachineCreationSegment{}
+	err := r.decode(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if req.Machine == nil || req.Machine.Body == nil || len(req.Tokens) != 1 {
+		return 0, nil, badRequest("a machine and one token are required")
+	}
+
+	body := req.Machine.Body
+	err = s.checkOrg(r, body.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, m := range s.machines {
+		if *m.machine.Body.OrgID == *body.OrgID && m.machine.Body.Name == body.Name &&
+			m.machine.Body.State == primitive.MachineActiveState {
+			return 0, nil, conflict("machine already exists")
+		}
+	}
+
+	m := &machine{machine: req.Machine}
+	err = s.addToken(m, &req.Tokens[0])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for i := range req.Memberships {
+		ms := req.Memberships[i]
+		if *ms.Body.OwnerID != *m.machine.ID || *ms.Body.OrgID != *body.OrgID {
+			return 0, nil, badRequest("invalid machine membership")
+		}
+		s.memberships = append(s.memberships, &ms)
+	}
+
+	s.machines = append(s.machines, m)
+	return http.StatusCreated, s.segment(m), nil
+}
+
+func (s *Server) listMachines(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	teamID, err := r.queryID("team_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	names := r.URL.Query()["name"]
+	state := r.URL.Query().Get("state")
+
+	segments := []*apitypes.MachineSegment{}
+	for _, m := range s.machines {
+		b := m.machine.Body
+		if !containsID(orgIDs, b.OrgID) || !containsName(names, b.Name) ||
+			(state != "" && b.State != state) || !s.isMember(b.OrgID, &r.session.identity) {
+			continue
+		}
+
+		segment := s.segment(m)
+		if teamID != nil && !hasTeam(segment.Memberships, teamID) {
+			continue
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return http.StatusOK, segments, nil
+}
+
+func hasTeam(memberships []envelope.Membership, teamID *identity.ID) bool {
+	for _, ms := range memberships {
+		if *ms.Body.TeamID == *teamID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) getMachine(r *request) (int, interface{}, error) {
+	m, err := s.machineParam(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, s.segment(m), nil
+}
+
+// machineParam returns the machine in the id path parameter, if the session
+// can see it.
+func (s *Server) machineParam(r *request) (*machine, error) {
+	id, err := r.paramID("id")
+	if err != nil {
+		return nil, err
+	}
+
+	m := s.findMachine(id)
+	if m == nil || !s.isMember(m.machine.Body.OrgID, &r.session.identity) {
+		return nil, notFound("machine not found")
+	}
+
+	return m, nil
+}
+
+func (s *Server) destroyMachine(r *request) (int, interface{}, error) {
+	m, err := s.machineParam(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	now := time.Now().UTC()
+	b := m.machine.Body
+	b.State = primitive.MachineDestroyedState
+	b.Destroyed = &now
+	by := r.session.identity
+	b.DestroyedBy = &by
+
+	for _, t := range m.tokens {
+		destroyToken(t, &by, now)
+	}
+
+	memberships := s.memberships[:0]
+	for _, ms := range s.memberships {
+		if *ms.Body.OwnerID != *m.machine.ID {
+			memberships = append(memberships, ms)
+		}
+	}
+	s.memberships = memberships
+
+	return http.StatusNoContent, nil, nil
+}
+
+func (s *Server) createMachineToken(r *request) (int, interface{}, error) {
+	m, err := s.machineParam(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if m.machine.Body.State != primitive.MachineActiveState {
+		return 0, nil, badRequest("machine has been destroyed")
+	}
+
+	req := registry.MachineTokenCreationSegment{}
+	err = r.decode(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = s.addToken(m, &req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	segment := s.tokenSegment(m.tokens[len(m.tokens)-1])
+	return http.StatusCreated, &segment, nil
+}
+
+func (s *Server) destroyMachineToken(r *request) (int, interface{}, error) {
+	m, err := s.machineParam(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	tokenID, err := r.paramID("token_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	owner, t := s.findMachineToken(tokenID)
+	if t == nil || owner != m {
+		return 0, nil, notFound("machine token not found")
+	}
+
+	by := r.session.identity
+	destroyToken(t, &by, time.Now().UTC())
+	return http.StatusNoContent, nil, nil
+}
+
+func destroyToken(t *apitypes.MachineTokenSegment, by *identity.ID, at time.Time) {
+	if t.Token.Body.State == primitive.MachineTokenDestroyedState {
+		return
+	}
+
+	t.Token.Body.State = primitive.MachineTokenDestroyedState
+	t.Token.Body.Destroyed = &at
+	t.Token.Body.DestroyedBy = by
+}
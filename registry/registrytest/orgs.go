@@ -0,0 +1,711 @@
+package registrytest
+
+import (
+	"net/http"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+type teamSegment struct {
+	Team              *envelope.Team               `json:"team"`
+	Memberships       []*envelope.Membership       `json:"memberships"`
+	PolicyAttachments []*envelope.PolicyAttachment `json:"policy_attachments"`
+}
+
+type orgTreeSegment struct {
+	Org      *primitive.Org      `json:"org"`
+	Policies []*primitive.Policy `json:"policies"`
+	Profiles []profile           `json:"profiles"`
+	Teams    []teamSegment       `json:"teams"`
+}
+
+type projectTreeSegment struct {
+	Org      *envelope.Org           `json:"org"`
+	Envs     []*envelope.Environment `json:"envs"`
+	Services []*envelope.Service     `json:"services"`
+	Projects []*envelope.Project     `json:"projects"`
+	Profiles []profile               `json:"profiles"`
+}
+
+func (s *Server) findOrg(id *identity.ID) *envelope.Org {
+	for _, o := range s.orgs {
+		if *o.ID == *id {
+			return o
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) findOrgByName(name string) *envelope.Org {
+	for _, o := range s.orgs {
+		if o.Body.Name == name {
+			return o
+		}
+	}
+
+	return nil
+}
+
+// isMember returns whether the given user or machine is in the org.
+func (s *Server) isMember(orgID, ownerID *identity.ID) bool {
+	for _, m := range s.memberships {
+		if *m.Body.OrgID == *orgID && *m.Body.OwnerID == *ownerID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkOrg returns an error unless the org exists, and the session belongs to
+// it.
+func (s *Server) checkOrg(r *request, orgID *identity.ID) error {
+	if orgID == nil {
+		return badRequest("org_id is required")
+	}
+
+	if s.findOrg(orgID) == nil || !s.isMember(orgID, &r.session.identity) {
+		return notFound("org not found")
+	}
+
+	return nil
+}
+
+// newOrg creates an org along with its system teams, adding the owner to the
+// owner, admin and member teams.
+func (s *Server) newOrg(name string, ownerID *identity.ID) *envelope.Org {
+	body := &primitive.Org{Name: name}
+	org := &envelope.Org{ID: newMutableID(body), Version: 1, Body: body}
+	s.orgs = append(s.orgs, org)
+
+	for _, teamName := range []string{primitive.OwnerTeamName, primitive.AdminTeamName, primitive.MemberTeamName} {
+		team := s.newTeam(org.ID, teamName, primitive.SystemTeamType, nil)
+		s.newMembership(org.ID, ownerID, team.ID)
+	}
+
+	machineTeam := &primitive.Team{}
+	id := identity.DeriveMutable(machineTeam, org.ID, primitive.DerivableMachineTeamSymbol)
+	s.newTeam(org.ID, primitive.MachineTeamName, primitive.SystemTeamType, &id)
+
+	return org
+}
+
+func (s *Server) newTeam(orgID *identity.ID, name string, teamType primitive.TeamType, id *identity.ID) *envelope.Team {
+	body := &primitive.Team{Name: name, OrgID: orgID, TeamType: teamType}
+	if id == nil {
+		id = newMutableID(body)
+	}
+
+	team := &envelope.Team{ID: id, Version: 1, Body: body}
+	s.teams = append(s.teams, team)
+	return team
+}
+
+func (s *Server) newMembership(orgID, ownerID, teamID *identity.ID) *envelope.Membership {
+	body := &primitive.Membership{OrgID: orgID, OwnerID: ownerID, TeamID: teamID}
+	membership := &envelope.Membership{ID: newMutableID(body), Version: 1, Body: body}
+	s.memberships = append(s.memberships, membership)
+	return membership
+}
+
+func (s *Server) createOrg(r *request) (int, interface{}, error) {
+	req := struct {
+		Body struct {
+			Name string `json:"name"`
+		} `json:"body"`
+	}{}
+	err := r.decode(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if r.session.Type != apitypes.UserSession {
+		return 0, nil, unauthorized("only users can create orgs")
+	}
+
+	if s.findOrgByName(req.Body.Name) != nil || s.findUserByName(req.Body.Name) != nil {
+		return 0, nil, conflict("org name already taken")
+	}
+
+	return http.StatusCreated, s.newOrg(req.Body.Name, &r.session.identity), nil
+}
+
+func (s *Server) listOrgs(r *request) (int, interface{}, error) {
+	names := r.URL.Query()["name"]
+
+	orgs := []*envelope.Org{}
+	for _, o := range s.orgs {
+		if containsName(names, o.Body.Name) && s.isMember(o.ID, &r.session.identity) {
+			orgs = append(orgs, o)
+		}
+	}
+
+	return http.StatusOK, orgs, nil
+}
+
+func (s *Server) getOrg(r *request) (int, interface{}, error) {
+	id, err := r.paramID("id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = s.checkOrg(r, id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, s.findOrg(id), nil
+}
+
+func (s *Server) removeOrgMember(r *request) (int, interface{}, error) {
+	orgID, err := r.paramID("id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ownerID, err := r.paramID("owner_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = s.checkOrg(r, orgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if !s.isMember(orgID, ownerID) {
+		return 0, nil, notFound("member not found")
+	}
+
+	memberships := s.memberships[:0]
+	for _, m := range s.memberships {
+		if *m.Body.OrgID != *orgID || *m.Body.OwnerID != *ownerID {
+			memberships = append(memberships, m)
+		}
+	}
+	s.memberships = memberships
+
+	return http.StatusNoContent, nil, nil
+}
+
+func (s *Server) getOrgTree(r *request) (int, interface{}, error) {
+	orgID, err := r.queryID("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = s.checkOrg(r, orgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	segment := orgTreeSegment{Org: s.findOrg(orgID).Body}
+	for _, p := range s.policies {
+		if *p.Body.OrgID == *orgID {
+			segment.Policies = append(segment.Policies, p.Body)
+		}
+	}
+
+	for _, t := range s.teams {
+		if *t.Body.OrgID != *orgID {
+			continue
+		}
+
+		team := teamSegment{Team: t}
+		for _, m := range s.memberships {
+			if *m.Body.TeamID == *t.ID {
+				team.Memberships = append(team.Memberships, m)
+			}
+		}
+		for _, a := range s.attachments {
+			if *a.Body.OwnerID == *t.ID {
+				team.PolicyAttachments = append(team.PolicyAttachments, a)
+			}
+		}
+		segment.Teams = append(segment.Teams, team)
+	}
+
+	segment.Profiles = s.orgProfiles(orgID)
+	return http.StatusOK, []orgTreeSegment{segment}, nil
+}
+
+// orgProfiles returns the profiles of the users in the org.
+func (s *Server) orgProfiles(orgID *identity.ID) []profile {
+	profiles := []profile{}
+	for _, u := range s.users {
+		if s.isMember(orgID, u.ID) {
+			profiles = append(profiles, newProfile(u))
+		}
+	}
+
+	return profiles
+}
+
+func (s *Server) createProject(r *request) (int, interface{}, error) {
+	req := struct {
+		Body struct {
+			OrgID *identity.ID `json:"org_id"`
+			Name  string       `json:"name"`
+		} `json:"body"`
+	}{}
+	err := r.decode(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = s.checkOrg(r, req.Body.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, p := range s.projects {
+		if *p.Body.OrgID == *req.Body.OrgID && p.Body.Name == req.Body.Name {
+			return 0, nil, conflict("project already exists")
+		}
+	}
+
+	body := &primitive.Project{Name: req.Body.Name, OrgID: req.Body.OrgID}
+	project := &envelope.Project{ID: newMutableID(body), Version: 1, Body: body}
+	s.projects = append(s.projects, project)
+	return http.StatusCreated, project, nil
+}
+
+func (s *Server) listProjects(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	names := r.URL.Query()["name"]
+
+	projects := []*envelope.Project{}
+	for _, p := range s.projects {
+		if containsID(orgIDs, p.Body.OrgID) && containsName(names, p.Body.Name) &&
+			s.isMember(p.Body.OrgID, &r.session.identity) {
+			projects = append(projects, p)
+		}
+	}
+
+	return http.StatusOK, projects, nil
+}
+
+func (s *Server) getProjectTree(r *request) (int, interface{}, error) {
+	orgID, err := r.queryID("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = s.checkOrg(r, orgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	segment := projectTreeSegment{
+		Org:      s.findOrg(orgID),
+		Envs:     []*envelope.Environment{},
+		Services: []*envelope.Service{},
+		Projects: []*envelope.Project{},
+		Profiles: s.orgProfiles(orgID),
+	}
+	for _, p := range s.projects {
+		if *p.Body.OrgID == *orgID {
+			segment.Projects = append(segment.Projects, p)
+		}
+	}
+	for _, e := range s.envs {
+		if *e.Body.OrgID == *orgID {
+			segment.Envs = append(segment.Envs, e)
+		}
+	}
+	for _, svc := range s.services {
+		if *svc.Body.OrgID == *orgID {
+			segment.Services = append(segment.Services, svc)
+		}
+	}
+
+	return http.StatusOK, []projectTreeSegment{segment}, nil
+}
+
+func (s *Server) createEnv(r *request) (int, interface{}, error) {
+	env := envelope.Environment{}
+	err := r.decode(&env)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if env.ID == nil || env.Body == nil {
+		return 0, nil, badRequest("invalid environment")
+	}
+
+	err = s.checkOrg(r, env.Body.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, e := range s.envs {
+		if *e.Body.ProjectID == *env.Body.ProjectID && e.Body.Name == env.Body.Name {
+			return 0, nil, conflict("environment already exists")
+		}
+	}
+
+	s.envs = append(s.envs, &env)
+	return http.StatusCreated, &env, nil
+}
+
+func (s *Server) listEnvs(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	projectIDs, err := r.queryIDs("project_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	names := r.URL.Query()["name"]
+
+	envs := []*envelope.Environment{}
+	for _, e := range s.envs {
+		if containsID(orgIDs, e.Body.OrgID) && containsID(projectIDs, e.Body.ProjectID) &&
+			containsName(names, e.Body.Name) && s.isMember(e.Body.OrgID, &r.session.identity) {
+			envs = append(envs, e)
+		}
+	}
+
+	return http.StatusOK, envs, nil
+}
+
+func (s *Server) createService(r *request) (int, interface{}, error) {
+	service := envelope.Service{}
+	err := r.decode(&service)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if service.ID == nil || service.Body == nil {
+		return 0, nil, badRequest("invalid service")
+	}
+
+	err = s.checkOrg(r, service.Body.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, svc := range s.services {
+		if *svc.Body.ProjectID == *service.Body.ProjectID && svc.Body.Name == service.Body.Name {
+			return 0, nil, conflict("service already exists")
+		}
+	}
+
+	s.services = append(s.services, &service)
+	return http.StatusCreated, &service, nil
+}
+
+func (s *Server) listServices(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	projectIDs, err := r.queryIDs("project_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	names := r.URL.Query()["name"]
+
+	services := []*envelope.Service{}
+	for _, svc := range s.services {
+		if containsID(orgIDs, svc.Body.OrgID) && containsID(projectIDs, svc.Body.ProjectID) &&
+			containsName(names, svc.Body.Name) && s.isMember(svc.Body.OrgID, &r.session.identity) {
+			services = append(services, svc)
+		}
+	}
+
+	return http.StatusOK, services, nil
+}
+
+func (s *Server) createTeam(r *request) (int, interface{}, error) {
+	team := envelope.Team{}
+	err := r.decode(&team)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if team.ID == nil || team.Body == nil {
+		return 0, nil, badRequest("invalid team")
+	}
+
+	err = s.checkOrg(r, team.Body.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, t := range s.teams {
+		if *t.Body.OrgID == *team.Body.OrgID && t.Body.Name == team.Body.Name {
+			return 0, nil, conflict("team already exists")
+		}
+	}
+
+	s.teams = append(s.teams, &team)
+	return http.StatusCreated, &team, nil
+}
+
+// updateTeam renames a team. System teams cannot be renamed.
+func (s *Server) updateTeam(r *request) (int, interface{}, error) {
+	id, err := r.paramID("id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req := struct {
+		Name string `json:"name"`
+	}{}
+	err = r.decode(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+	if req.Name == "" {
+		return 0, nil, badRequest("invalid team name")
+	}
+
+	for _, t := range s.teams {
+		if *t.ID != *id || !s.isMember(t.Body.OrgID, &r.session.identity) {
+			continue
+		}
+
+		if t.Body.TeamType == primitive.SystemTeamType {
+			return 0, nil, badRequest("system teams cannot be renamed")
+		}
+
+		for _, o := range s.teams {
+			if *o.Body.OrgID == *t.Body.OrgID && o.Body.Name == req.Name && *o.ID != *id {
+				return 0, nil, conflict("team already exists")
+			}
+		}
+
+		t.Body.Name = req.Name
+		return http.StatusOK, t, nil
+	}
+
+	return 0, nil, notFound("team not found")
+}
+
+// deleteTeam deletes a team and its memberships. System teams cannot be
+// deleted.
+func (s *Server) deleteTeam(r *request) (int, interface{}, error) {
+	id, err := r.paramID("id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for i, t := range s.teams {
+		if *t.ID != *id || !s.isMember(t.Body.OrgID, &r.session.identity) {
+			continue
+		}
+
+		if t.Body.TeamType == primitive.SystemTeamType {
+			return 0, nil, badRequest("system teams cannot be deleted")
+		}
+
+		s.teams = append(s.teams[:i], s.teams[i+1:]...)
+
+		memberships := s.memberships[:0]
+		for _, m := range s.memberships {
+			if *m.Body.TeamID != *id {
+				memberships = append(memberships, m)
+			}
+		}
+		s.memberships = memberships
+
+		return http.StatusNoContent, nil, nil
+	}
+
+	return 0, nil, notFound("team not found")
+}
+
+func (s *Server) listTeams(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	names := r.URL.Query()["name"]
+	teamType := primitive.TeamType(r.URL.Query().Get("type"))
+
+	teams := []*envelope.Team{}
+	for _, t := range s.teams {
+		if containsID(orgIDs, t.Body.OrgID) && containsName(names, t.Body.Name) &&
+			(teamType == primitive.AnyTeamType || t.Body.TeamType == teamType) &&
+			s.isMember(t.Body.OrgID, &r.session.identity) {
+			teams = append(teams, t)
+		}
+	}
+
+	return http.StatusOK, teams, nil
+}
+
+func (s *Server) createMembership(r *request) (int, interface{}, error) {
+	membership := envelope.Membership{}
+	err := r.decode(&membership)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if membership.ID == nil || membership.Body == nil {
+		return 0, nil, badRequest("invalid membership")
+	}
+
+	err = s.checkOrg(r, membership.Body.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, m := range s.memberships {
+		if *m.Body.TeamID == *membership.Body.TeamID && *m.Body.OwnerID == *membership.Body.OwnerID {
+			return 0, nil, conflict("membership already exists")
+		}
+	}
+
+	s.memberships = append(s.memberships, &membership)
+	return http.StatusCreated, &membership, nil
+}
+
+func (s *Server) listMemberships(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	teamIDs, err := r.queryIDs("team_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	ownerIDs, err := r.queryIDs("owner_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	memberships := []*envelope.Membership{}
+	for _, m := range s.memberships {
+		if containsID(orgIDs, m.Body.OrgID) && containsID(teamIDs, m.Body.TeamID) &&
+			containsID(ownerIDs, m.Body.OwnerID) && s.isMember(m.Body.OrgID, &r.session.identity) {
+			memberships = append(memberships, m)
+		}
+	}
+
+	return http.StatusOK, memberships, nil
+}
+
+func (s *Server) deleteMembership(r *request) (int, interface{}, error) {
+	id, err := r.paramID("id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for i, m := range s.memberships {
+		if *m.ID == *id && s.isMember(m.Body.OrgID, &r.session.identity) {
+			s.memberships = append(s.memberships[:i], s.memberships[i+1:]...)
+			return http.StatusNoContent, nil, nil
+		}
+	}
+
+	return 0, nil, notFound("membership not found")
+}
+
+func (s *Server) createPolicy(r *request) (int, interface{}, error) {
+	policy := envelope.Policy{}
+	err := r.decode(&policy)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if policy.ID == nil || policy.Body == nil {
+		return 0, nil, badRequest("invalid policy")
+	}
+
+	err = s.checkOrg(r, policy.Body.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	s.policies = append(s.policies, &policy)
+	return http.StatusCreated, &policy, nil
+}
+
+func (s *Server) listPolicies(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	names := r.URL.Query()["name"]
+
+	policies := []*envelope.Policy{}
+	for _, p := range s.policies {
+		if containsID(orgIDs, p.Body.OrgID) && containsName(names, p.Body.Policy.Name) &&
+			s.isMember(p.Body.OrgID, &r.session.identity) {
+			policies = append(policies, p)
+		}
+	}
+
+	return http.StatusOK, policies, nil
+}
+
+func (s *Server) createAttachment(r *request) (int, interface{}, error) {
+	attachment := envelope.PolicyAttachment{}
+	err := r.decode(&attachment)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if attachment.ID == nil || attachment.Body == nil {
+		return 0, nil, badRequest("invalid policy attachment")
+	}
+
+	err = s.checkOrg(r, attachment.Body.OrgID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	s.attachments = append(s.attachments, &attachment)
+	return http.StatusCreated, &attachment, nil
+}
+
+func (s *Server) listAttachments(r *request) (int, interface{}, error) {
+	orgIDs, err := r.queryIDs("org_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	ownerIDs, err := r.queryIDs("owner_id")
+	if err != nil {
+		return 0, nil, err
+	}
+	policyIDs, err := r.queryIDs("policy_id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	attachments := []*envelope.PolicyAttachment{}
+	for _, a := range s.attachments {
+		if containsID(orgIDs, a.Body.OrgID) && containsID(ownerIDs, a.Body.OwnerID) &&
+			containsID(policyIDs, a.Body.PolicyID) && s.isMember(a.Body.OrgID, &r.session.identity) {
+			attachments = append(attachments, a)
+		}
+	}
+
+	return http.StatusOK, attachments, nil
+}
+
+func (s *Server) deleteAttachment(r *request) (int, interface{}, error) {
+	id, err := r.paramID("id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for i, a := range s.attachments {
+		if *a.ID == *id && s.isMember(a.Body.OrgID, &r.session.identity) {
+			s.attachments = append(s.attachments[:i], s.attachments[i+1:]...)
+			return http.StatusNoContent, nil, nil
+		}
+	}
+
+	return 0, nil, notFound("policy attachment not found")
+}
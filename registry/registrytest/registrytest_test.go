@@ -0,0 +1,275 @@
+package registrytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func noProgress(*api.Event, error) {}
+
+// setupProject signs up jo, and creates the web project with a dev
+// environment and api service in jo's org. The returned func stops the
+// daemon and server.
+func setupProject(t *testing.T) (*Daemon, *identity.ID, *identity.ID, func()) {
+	ctx := context.Background()
+
+	s := NewServer()
+	d, err := s.StartDaemon()
+	if err != nil {
+		s.Close()
+		t.Fatal("could not start daemon:", err)
+	}
+	done := func() {
+		d.Close()
+		s.Close()
+	}
+
+	c := d.Client
+	err = d.Signup(ctx, s, "jo", "jo@example.com", "a long enough password")
+	if err != nil {
+		done()
+		t.Fatal("signup failed:", err)
+	}
+
+	org, err := c.Orgs.GetByName(ctx, "jo")
+	if err != nil || org == nil {
+		done()
+		t.Fatal("could not find personal org:", err)
+	}
+
+	err = c.KeyPairs.Create(ctx, org.ID, noProgress)
+	if err != nil {
+		done()
+		t.Fatal("could not generate keypairs:", err)
+	}
+
+	project, err := c.Projects.Create(ctx, org.ID, "web")
+	if err != nil {
+		done()
+		t.Fatal("could not create project:", err)
+	}
+
+	err = c.Environments.Create(ctx, org.ID, project.ID, "dev")
+	if err != nil {
+		done()
+		t.Fatal("could not create environment:", err)
+	}
+
+	err = c.Services.Create(ctx, org.ID, project.ID, "api")
+	if err != nil {
+		done()
+		t.Fatal("could not create service:", err)
+	}
+
+	return d, org.ID, project.ID, done
+}
+
+// setCredential sets a credential in the api service of the web project.
+func setCredential(t *testing.T, c *api.Client, orgID, projectID *identity.ID,
+	rawPathExp, name string, value *apitypes.CredentialValue) {
+
+	pe, err := pathexp.Parse(rawPathExp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cred apitypes.Credential = &apitypes.CredentialV2{
+		BaseCredential: apitypes.BaseCredential{
+			OrgID:     orgID,
+			ProjectID: projectID,
+			Name:      name,
+			PathExp:   pe,
+			Value:     value,
+		},
+		State: "set",
+	}
+	_, err = c.Credentials.Create(context.Background(), &cred, noProgress)
+	if err != nil {
+		t.Fatal("could not set credential:", err)
+	}
+}
+
+func TestSetAndGetCredential(t *testing.T) {
+	ctx := context.Background()
+
+	d, orgID, projectID, done := setupProject(t)
+	defer done()
+
+	c := d.Client
+	setCredential(t, c, orgID, projectID, "/jo/web/dev/api/*/*", "port",
+		apitypes.NewStringCredentialValue("8080"))
+
+	creds, err := c.Credentials.Get(ctx, "/jo/web/dev/api/jo/1")
+	if err != nil {
+		t.Fatal("could not get credentials:", err)
+	}
+
+	if len(creds) != 1 {
+		t.Fatalf("expected 1 credential, got %d", len(creds))
+	}
+
+	body := *creds[0].Body
+	if body.GetName() != "port" || body.GetValue().String() != "8080" {
+		t.Errorf("unexpected credential %s=%s", body.GetName(), body.GetValue())
+	}
+}
+
+func TestCredentialReferences(t *testing.T) {
+	ctx := context.Background()
+
+	d, orgID, projectID, done := setupProject(t)
+	defer done()
+
+	// The port is set for every identity and instance, so the reference to
+	// the first instance only finds it through the wildcards.
+	c := d.Client
+	setCredential(t, c, orgID, projectID, "/jo/web/dev/api/*/*", "port",
+		apitypes.NewStringCredentialValue("8080"))
+	setCredential(t, c, orgID, projectID, "/jo/web/dev/api/*/*", "alias",
+		apitypes.NewReferenceCredentialValue("/jo/web/dev/api/*/1/port"))
+	setCredential(t, c, orgID, projectID, "/jo/web/dev/api/*/*", "broken",
+		apitypes.NewReferenceCredentialValue("/jo/web/dev/api/*/1/missing"))
+
+	creds, err := c.Credentials.Get(ctx, "/jo/web/dev/api/jo/1")
+	if err != nil {
+		t.Fatal("could not get credentials:", err)
+	}
+
+	found := make(map[string]*apitypes.CredentialV2)
+	for _, cred := range creds {
+		body := (*cred.Body).(*apitypes.CredentialV2)
+		found[body.GetName()] = body
+	}
+
+	alias := found["alias"]
+	if alias == nil || alias.GetValue().String() != "8080" {
+		t.Errorf("alias not resolved: %+v", alias)
+	}
+
+	broken := found["broken"]
+	if broken == nil || broken.ReferenceError == "" {
+		t.Errorf("broken reference not reported: %+v", broken)
+	}
+}
+
+func TestCredentialMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	d, orgID, projectID, done := setupProject(t)
+	defer done()
+
+	c := d.Client
+	pe, err := pathexp.Parse("/jo/web/dev/api/*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// set sets the password, returning the metadata it is then read with.
+	set := func(metadata *primitive.CredentialMetadata, clearMetadata bool) *primitive.CredentialMetadata {
+		var cred apitypes.Credential = &apitypes.CredentialV2{
+			BaseCredential: apitypes.BaseCredential{
+				OrgID:     orgID,
+				ProjectID: projectID,
+				Name:      "password",
+				PathExp:   pe,
+				Value:     apitypes.NewStringCredentialValue("hunter2"),
+			},
+			State:         "set",
+			Metadata:      metadata,
+			ClearMetadata: clearMetadata,
+		}
+		_, err := c.Credentials.Create(ctx, &cred, noProgress)
+		if err != nil {
+			t.Fatal("could not set credential:", err)
+		}
+
+		creds, err := c.Credentials.Get(ctx, "/jo/web/dev/api/jo/1")
+		if err != nil || len(creds) != 1 {
+			t.Fatalf("could not get credential: %v", err)
+		}
+
+		return (*creds[0].Body).(*apitypes.CredentialV2).Metadata
+	}
+
+	expires := time.Date(2017, 6, 30, 0, 0, 0, 0, time.UTC)
+	set(&primitive.CredentialMetadata{
+		Description: "primary database",
+		Owner:       "ops",
+		Tags:        []string{"db"},
+		Expires:     &expires,
+	}, false)
+
+	t.Run("merges the fields given", func(t *testing.T) {
+		md := set(&primitive.CredentialMetadata{Tags: []string{"foo"}}, false)
+		if md == nil {
+			t.Fatal("metadata was dropped")
+		}
+		if md.Description != "primary database" || md.Owner != "ops" ||
+			md.Expires == nil || !md.Expires.Equal(expires) {
+			t.Errorf("metadata not carried forward: %+v", md)
+		}
+		if len(md.Tags) != 1 || md.Tags[0] != "foo" {
+			t.Errorf("got tags %v, want [foo]", md.Tags)
+		}
+	})
+
+	t.Run("keeps metadata when none is given", func(t *testing.T) {
+		md := set(nil, false)
+		if md == nil || md.Owner != "ops" {
+			t.Errorf("metadata not carried forward: %+v", md)
+		}
+	})
+
+	t.Run("clears metadata", func(t *testing.T) {
+		md := set(&primitive.CredentialMetadata{Owner: "dev"}, true)
+		if md == nil || md.Owner != "dev" || md.Description != "" || md.Expires != nil {
+			t.Errorf("metadata not replaced: %+v", md)
+		}
+
+		md = set(nil, true)
+		if md != nil {
+			t.Errorf("metadata not cleared: %+v", md)
+		}
+	})
+}
+
+func TestUnverifiedUserIsRejected(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewServer()
+	defer s.Close()
+
+	d, err := s.StartDaemon()
+	if err != nil {
+		t.Fatal("could not start daemon:", err)
+	}
+	defer d.Close()
+
+	c := d.Client
+	_, err = c.Users.Create(ctx, &apitypes.Signup{
+		Username:   "sam",
+		Email:      "sam@example.com",
+		Passphrase: "a long enough password",
+	}, nil)
+	if err != nil {
+		t.Fatal("signup failed:", err)
+	}
+
+	err = c.Session.UserLogin(ctx, "sam@example.com", "a long enough password")
+	if err != nil {
+		t.Fatal("login failed:", err)
+	}
+
+	_, err = c.Orgs.List(ctx)
+	apiErr, ok := apitypes.FormatError(err).(*apitypes.Error)
+	if !ok || apiErr.Error() != apitypes.NewUnverifiedError().Error() {
+		t.Errorf("expected an unverified error, got %v", err)
+	}
+}
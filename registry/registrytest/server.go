@@ -0,0 +1,429 @@
+// Package registrytest provides an in-memory implementation of the Torus
+// registry, for testing the daemon and cli end to end.
+//
+// The fake registry serves the endpoints used by registry.Client, and keeps
+// enough state to sign up, log in, manage orgs and their members, and store
+// and retrieve credentials. It checks logins and org membership, but does
+// not enforce access control policies, and does not add system signatures to
+// claims.
+package registrytest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/registry"
+)
+
+// Version is the release version reported by the fake registry.
+const Version = "registrytest"
+
+// Server is a fake registry, listening on a local address.
+type Server struct {
+	// URL is the base url of the registry, of the form http://ipaddr:port
+	URL string
+
+	srv    *httptest.Server
+	routes []route
+
+	mutex       sync.Mutex
+	users       []*envelope.User
+	verifyCodes map[string]identity.ID
+	logins      map[string]*pendingLogin
+	sessions    map[string]*session
+
+	orgs        []*envelope.Org
+	projects    []*envelope.Project
+	envs        []*envelope.Environment
+	services    []*envelope.Service
+	teams       []*envelope.Team
+	memberships []*envelope.Membership
+	policies    []*envelope.Policy
+	attachments []*envelope.PolicyAttachment
+
+	keypairs []*registry.ClaimedKeyPair
+	keyrings []*keyring
+	machines []*machine
+}
+
+// session is a logged in user or machine. For a user, the identity and
+// auth ids are both the user's id. For a machine, they are the ids of the
+// machine and the token it logged in with.
+type session struct {
+	Type     apitypes.SessionType
+	identity identity.ID
+	auth     identity.ID
+}
+
+// authLevel is the kind of session a route requires.
+type authLevel int
+
+const (
+	activeSession authLevel = iota // a verified user, or a machine
+	anySession
+	noSession
+)
+
+type handlerFunc func(r *request) (int, interface{}, error)
+
+type route struct {
+	method  string
+	path    []string
+	auth    authLevel
+	handler handlerFunc
+}
+
+// request is a request made to the fake registry, along with the session
+// that made it, and the values of its path parameters.
+type request struct {
+	*http.Request
+
+	token   string
+	session *session
+	params  map[string]string
+}
+
+// NewServer starts and returns a new fake registry. The caller should call
+// Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		verifyCodes: make(map[string]identity.ID),
+		logins:      make(map[string]*pendingLogin),
+		sessions:    make(map[string]*session),
+	}
+	s.registerRoutes()
+
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the fake registry, blocking until all outstanding
+// requests have completed.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+func (s *Server) registerRoutes() {
+	s.handle("GET", "/version", noSession, s.getVersion)
+
+	s.handle("POST", "/users", noSession, s.createUser)
+	s.handle("POST", "/users/verify", anySession, s.verifyUser)
+	s.handle("PATCH", "/users/self", anySession, s.updateSelf)
+	s.handle("POST", "/tokens", noSession, s.createToken)
+	s.handle("DELETE", "/tokens/:token", anySession, s.deleteToken)
+	s.handle("GET", "/self", anySession, s.getSelf)
+	s.handle("GET", "/profiles", activeSession, s.listProfiles)
+	s.handle("GET", "/profiles/:username", activeSession, s.getProfile)
+
+	s.handle("POST", "/orgs", activeSession, s.createOrg)
+	s.handle("GET", "/orgs", activeSession, s.listOrgs)
+	s.handle("GET", "/orgs/:id", activeSession, s.getOrg)
+	s.handle("DELETE", "/orgs/:id/members/:owner_id", activeSession, s.removeOrgMember)
+	s.handle("GET", "/orgtree", activeSession, s.getOrgTree)
+	s.handle("POST", "/projects", activeSession, s.createProject)
+	s.handle("GET", "/projects", activeSession, s.listProjects)
+	s.handle("GET", "/projecttree", activeSession, s.getProjectTree)
+	s.handle("POST", "/envs", activeSession, s.createEnv)
+	s.handle("GET", "/envs", activeSession, s.listEnvs)
+	s.handle("POST", "/services", activeSession, s.createService)
+	s.handle("GET", "/services", activeSession, s.listServices)
+	s.handle("POST", "/teams", activeSession, s.createTeam)
+	s.handle("GET", "/teams", activeSession, s.listTeams)
+	s.handle("PATCH", "/teams/:id", activeSession, s.updateTeam)
+	s.handle("DELETE", "/teams/:id", activeSession, s.deleteTeam)
+	s.handle("POST", "/memberships", activeSession, s.createMembership)
+	s.handle("GET", "/memberships", activeSession, s.listMemberships)
+	s.handle("DELETE", "/memberships/:id", activeSession, s.deleteMembership)
+	s.handle("POST", "/policies", activeSession, s.createPolicy)
+	s.handle("GET", "/policies", activeSession, s.listPolicies)
+	s.handle("POST", "/policy-attachments", activeSession, s.createAttachment)
+	s.handle("GET", "/policy-attachments", activeSession, s.listAttachments)
+	s.handle("DELETE", "/policy-attachments/:id", activeSession, s.deleteAttachment)
+
+	s.handle("POST", "/keypairs", activeSession, s.createKeypair)
+	s.handle("GET", "/keypairs", activeSession, s.listKeypairs)
+	s.handle("POST", "/claims", activeSession, s.createClaim)
+	s.handle("GET", "/claimtree", activeSession, s.getClaimTree)
+
+	s.handle("GET", "/keyrings", activeSession, s.listKeyrings)
+	s.handle("POST", "/keyrings/:id/members", activeSession, s.createKeyringMembers)
+	s.handle("POST", "/keyring-members", activeSession, s.createKeyringMembersV1)
+	s.handle("POST", "/credentialgraph", activeSession, s.createCredentialGraph)
+	s.handle("GET", "/credentialgraph", activeSession, s.listCredentialGraphs)
+	s.handle("POST", "/credentials", activeSession, s.createCredential)
+
+	s.handle("POST", "/machines", activeSession, s.createMachine)
+	s.handle("GET", "/machines", activeSession, s.listMachines)
+	s.handle("GET", "/machines/:id", activeSession, s.getMachine)
+	s.handle("DELETE", "/machines/:id", activeSession, s.destroyMachine)
+	s.handle("POST", "/machines/:id/tokens", activeSession, s.createMachineToken)
+	s.handle("DELETE", "/machines/:id/tokens/:token_id", activeSession, s.destroyMachineToken)
+}
+
+func (s *Server) handle(method, path string, auth authLevel, h handlerFunc) {
+	s.routes = append(s.routes, route{
+		method:  method,
+		path:    splitPath(path),
+		auth:    auth,
+		handler: h,
+	})
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// match returns the route for the request, and the values of its path
+// parameters. Static path segments take precedence over parameters.
+func (s *Server) match(r *http.Request) (*route, map[string]string) {
+	parts := splitPath(r.URL.Path)
+
+	var best *route
+	var bestParams map[string]string
+	bestStatic := -1
+	for i := range s.routes {
+		rt := &s.routes[i]
+		if rt.method != r.Method || len(rt.path) != len(parts) {
+			continue
+		}
+
+		params := make(map[string]string)
+		static := 0
+		matched := true
+		for j, p := range rt.path {
+			switch {
+			case strings.HasPrefix(p, ":"):
+				params[p[1:]] = parts[j]
+			case p == parts[j]:
+				static++
+			default:
+				matched = false
+			}
+			if !matched {
+				break
+			}
+		}
+
+		if matched && static > bestStatic {
+			best, bestParams, bestStatic = rt, params, static
+		}
+	}
+
+	return best, bestParams
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt, params := s.match(r)
+	if rt == nil {
+		writeError(w, &apitypes.Error{
+			StatusCode: http.StatusNotFound,
+			Type:       apitypes.NotFoundError,
+			Err:        []string{r.Method + " " + r.URL.Path + " is not implemented by the fake registry"},
+		})
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	req := &request{Request: r, params: params}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		req.token = strings.TrimPrefix(auth, "Bearer ")
+		req.session = s.sessions[req.token]
+	}
+
+	if rt.auth != noSession {
+		if req.session == nil {
+			writeError(w, unauthorized("invalid auth token"))
+			return
+		}
+
+		if rt.auth == activeSession && req.session.Type == apitypes.UserSession {
+			if u := s.findUser(&req.session.identity); u != nil && u.Body.State != userActiveState {
+				writeError(w, unauthorized("wrong identity state: "+u.Body.State))
+				return
+			}
+		}
+	}
+
+	status, body, err := rt.handler(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.Printf("Error encoding fake registry response: %s", err)
+	}
+}
+
+func (s *Server) getVersion(r *request) (int, interface{}, error) {
+	return http.StatusOK, &apitypes.Version{Version: Version}, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	apiErr, ok := err.(*apitypes.Error)
+	if !ok {
+		apiErr = &apitypes.Error{
+			StatusCode: http.StatusInternalServerError,
+			Type:       apitypes.InternalServerError,
+			Err:        []string{err.Error()},
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.StatusCode)
+	json.NewEncoder(w).Encode(apiErr)
+}
+
+func badRequest(msg string) error {
+	return &apitypes.Error{
+		StatusCode: http.StatusBadRequest,
+		Type:       apitypes.BadRequestError,
+		Err:        []string{msg},
+	}
+}
+
+func unauthorized(msg string) error {
+	return &apitypes.Error{
+		StatusCode: http.StatusUnauthorized,
+		Type:       apitypes.UnauthorizedError,
+		Err:        []string{msg},
+	}
+}
+
+func notFound(msg string) error {
+	return &apitypes.Error{
+		StatusCode: http.StatusNotFound,
+		Type:       apitypes.NotFoundError,
+		Err:        []string{msg},
+	}
+}
+
+func conflict(msg string) error {
+	return &apitypes.Error{
+		StatusCode: http.StatusConflict,
+		Type:       "conflict",
+		Err:        []string{msg},
+	}
+}
+
+// decode reads the request's json body into v.
+func (r *request) decode(v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		return badRequest("invalid request body: " + err.Error())
+	}
+
+	return nil
+}
+
+// queryID returns the id in the named query parameter, or nil if there is
+// none.
+func (r *request) queryID(name string) (*identity.ID, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	id, err := identity.DecodeFromString(raw)
+	if err != nil {
+		return nil, badRequest("invalid " + name)
+	}
+
+	return &id, nil
+}
+
+// queryIDs returns the ids in each of the named query parameters.
+func (r *request) queryIDs(name string) ([]identity.ID, error) {
+	var ids []identity.ID
+	for _, raw := range r.URL.Query()[name] {
+		id, err := identity.DecodeFromString(raw)
+		if err != nil {
+			return nil, badRequest("invalid " + name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// paramID returns the id in the named path parameter.
+func (r *request) paramID(name string) (*identity.ID, error) {
+	id, err := identity.DecodeFromString(r.params[name])
+	if err != nil {
+		return nil, badRequest("invalid " + name)
+	}
+
+	return &id, nil
+}
+
+// randomToken returns a new random value, for use as a token or code.
+func randomToken() string {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newMutableID(body identity.Mutable) *identity.ID {
+	id, err := identity.NewMutable(body)
+	if err != nil {
+		panic(err)
+	}
+
+	return &id
+}
+
+// containsID returns whether id is in ids. An empty list matches any id.
+func containsID(ids []identity.ID, id *identity.ID) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, i := range ids {
+		if id != nil && i == *id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsName returns whether name is in names. An empty list matches any
+// name.
+func containsName(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
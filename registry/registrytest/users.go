@@ -0,0 +1,354 @@
+package registrytest
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	b64 "github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+	"github.com/manifoldco/torus-cli/registry"
+)
+
+// User states
+const (
+	userUnverifiedState = "unverified"
+	userActiveState     = "active"
+)
+
+// pendingLogin is a session waiting for its login token to be exchanged for
+// an auth token. Users prove their password by HMACing the login token, and
+// machines by signing it with their token's keypair.
+type pendingLogin struct {
+	session *session
+	user    *envelope.User
+	token   *envelope.MachineToken
+}
+
+type tokenRequest struct {
+	Type      string       `json:"type"`
+	Email     string       `json:"email"`
+	TokenID   *identity.ID `json:"machine_token_id"`
+	TokenHMAC string       `json:"login_token_hmac"`
+	TokenSig  *b64.Value   `json:"login_token_sig"`
+}
+
+type loginTokenResponse struct {
+	Salt  *b64.Value `json:"salt"`
+	Token string     `json:"login_token"`
+}
+
+type authTokenResponse struct {
+	Token string `json:"auth_token"`
+}
+
+type selfResponse struct {
+	Type     apitypes.SessionType `json:"type"`
+	Identity envelope.Envelope    `json:"identity"`
+	Auth     envelope.Envelope    `json:"auth"`
+}
+
+type profileBody struct {
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
+type profile struct {
+	ID   *identity.ID `json:"id"`
+	Body profileBody  `json:"body"`
+}
+
+// VerificationCode returns the code sent to verify the given email address,
+// or an empty string if there is none outstanding.
+func (s *Server) VerificationCode(email string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for code, id := range s.verifyCodes {
+		if u := s.findUser(&id); u != nil && u.Body.Email == email {
+			return code
+		}
+	}
+
+	return ""
+}
+
+func (s *Server) findUser(id *identity.ID) *envelope.User {
+	for _, u := range s.users {
+		if *u.ID == *id {
+			return u
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) findUserByEmail(email string) *envelope.User {
+	for _, u := range s.users {
+		if u.Body.Email == email {
+			return u
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) findUserByName(username string) *envelope.User {
+	for _, u := range s.users {
+		if u.Body.Username == username {
+			return u
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) createUser(r *request) (int, interface{}, error) {
+	signup := registry.SignupEnvelope{}
+	err := r.decode(&signup)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if signup.ID == nil || signup.Body == nil {
+		return 0, nil, badRequest("invalid user")
+	}
+
+	user := envelope.User{ID: signup.ID, Version: signup.Version, Body: &signup.Body.User}
+	switch {
+	case user.Body.Password == nil || user.Body.Master == nil:
+		return 0, nil, badRequest("password and master key are required")
+	case s.findUserByEmail(user.Body.Email) != nil:
+		return 0, nil, conflict("email already registered")
+	case s.findUserByName(user.Body.Username) != nil || s.findOrgByName(user.Body.Username) != nil:
+		return 0, nil, conflict("username already taken")
+	}
+
+	user.Body.State = userUnverifiedState
+	s.users = append(s.users, &user)
+	s.verifyCodes[randomToken()] = *user.ID
+
+	// Every user gets a personal org, named after them.
+	s.newOrg(user.Body.Username, user.ID)
+
+	return http.StatusCreated, &user, nil
+}
+
+func (s *Server) verifyUser(r *request) (int, interface{}, error) {
+	req := apitypes.VerifyEmail{}
+	err := r.decode(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	id, ok := s.verifyCodes[req.Code]
+	if !ok || id != r.session.identity {
+		return 0, nil, badRequest("invalid verification code")
+	}
+
+	delete(s.verifyCodes, req.Code)
+	s.findUser(&id).Body.State = userActiveState
+	return http.StatusNoContent, nil, nil
+}
+
+func (s *Server) updateSelf(r *request) (int, interface{}, error) {
+	if r.session.Type != apitypes.UserSession {
+		return 0, nil, unauthorized("only users can update their profile")
+	}
+
+	req := struct {
+		Name     string                  `json:"name"`
+		Email    string                  `json:"email"`
+		Password *primitive.UserPassword `json:"password"`
+		Master   *primitive.MasterKey    `json:"master"`
+	}{}
+	err := r.decode(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	user := s.findUser(&r.session.identity)
+	if req.Name != "" {
+		user.Body.Name = req.Name
+	}
+
+	if req.Email != "" && req.Email != user.Body.Email {
+		if s.findUserByEmail(req.Email) != nil {
+			return 0, nil, conflict("email already registered")
+		}
+
+		user.Body.Email = req.Email
+		user.Body.State = userUnverifiedState
+		s.verifyCodes[randomToken()] = *user.ID
+	}
+
+	if req.Password != nil || req.Master != nil {
+		if req.Password == nil || req.Master == nil {
+			return 0, nil, badRequest("password and master key must be changed together")
+		}
+
+		user.Body.Password = req.Password
+		user.Body.Master = req.Master
+	}
+
+	return http.StatusOK, user, nil
+}
+
+func (s *Server) createToken(r *request) (int, interface{}, error) {
+	req := tokenRequest{}
+	err := r.decode(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch req.Type {
+	case "login":
+		return s.createLoginToken(&req)
+	case "auth":
+		return s.createAuthToken(r, &req)
+	default:
+		return 0, nil, badRequest("unknown token type")
+	}
+}
+
+func (s *Server) createLoginToken(req *tokenRequest) (int, interface{}, error) {
+	login := &pendingLogin{}
+	var salt *b64.Value
+
+	if req.TokenID != nil {
+		m, t := s.findMachineToken(req.TokenID)
+		if t == nil || t.Token.Body.State != primitive.MachineTokenActiveState {
+			return 0, nil, unauthorized("invalid login")
+		}
+
+		login.token = t.Token
+		login.session = &session{
+			Type:     apitypes.MachineSession,
+			identity: *m.machine.ID,
+			auth:     *t.Token.ID,
+		}
+		salt = t.Token.Body.PublicKey.Salt
+	} else {
+		user := s.findUserByEmail(req.Email)
+		if user == nil {
+			return 0, nil, unauthorized("invalid login")
+		}
+
+		raw, err := b64.NewValueFromString(user.Body.Password.Salt)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		login.user = user
+		login.session = &session{
+			Type:     apitypes.UserSession,
+			identity: *user.ID,
+			auth:     *user.ID,
+		}
+		salt = raw
+	}
+
+	token := randomToken()
+	s.logins[token] = login
+	return http.StatusCreated, &loginTokenResponse{Salt: salt, Token: token}, nil
+}
+
+func (s *Server) createAuthToken(r *request, req *tokenRequest) (int, interface{}, error) {
+	login, ok := s.logins[r.token]
+	if !ok {
+		return 0, nil, unauthorized("invalid login token")
+	}
+	delete(s.logins, r.token)
+
+	if login.user != nil {
+		// The HMAC is keyed with the base64url encoded password hash.
+		key := base64.RawURLEncoding.EncodeToString(*login.user.Body.Password.Value)
+		mac := hmac.New(sha512.New, []byte(key))
+		mac.Write([]byte(r.token))
+		expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+		if !hmac.Equal([]byte(expected), []byte(req.TokenHMAC)) {
+			return 0, nil, unauthorized("invalid login")
+		}
+	} else {
+		pk := login.token.Body.PublicKey.Value
+		if req.TokenSig == nil || !ed25519.Verify(ed25519.PublicKey(*pk), []byte(r.token), *req.TokenSig) {
+			return 0, nil, unauthorized("invalid login")
+		}
+
+		now := time.Now().UTC()
+		_, t := s.findMachineToken(&login.session.auth)
+		t.LastLogin = &now
+	}
+
+	token := randomToken()
+	s.sessions[token] = login.session
+	return http.StatusCreated, &authTokenResponse{Token: token}, nil
+}
+
+func (s *Server) deleteToken(r *request) (int, interface{}, error) {
+	token := r.params["token"]
+	if _, ok := s.sessions[token]; !ok || token != r.token {
+		return 0, nil, notFound("token not found")
+	}
+
+	delete(s.sessions, token)
+	return http.StatusNoContent, nil, nil
+}
+
+func (s *Server) getSelf(r *request) (int, interface{}, error) {
+	self := selfResponse{Type: r.session.Type}
+
+	switch r.session.Type {
+	case apitypes.UserSession:
+		user := s.findUser(&r.session.identity)
+		self.Identity = user
+		self.Auth = user
+	case apitypes.MachineSession:
+		m, t := s.findMachineToken(&r.session.auth)
+		self.Identity = m.machine
+		self.Auth = t.Token
+	}
+
+	return http.StatusOK, &self, nil
+}
+
+func (s *Server) listProfiles(r *request) (int, interface{}, error) {
+	ids, err := r.queryIDs("id")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	profiles := []profile{}
+	for _, u := range s.users {
+		if len(ids) > 0 && containsID(ids, u.ID) {
+			profiles = append(profiles, newProfile(u))
+		}
+	}
+
+	return http.StatusOK, profiles, nil
+}
+
+func (s *Server) getProfile(r *request) (int, interface{}, error) {
+	user := s.findUserByName(r.params["username"])
+	if user == nil {
+		return 0, nil, notFound("user not found")
+	}
+
+	p := newProfile(user)
+	return http.StatusOK, &p, nil
+}
+
+func newProfile(u *envelope.User) profile {
+	return profile{
+		ID:   u.ID,
+		Body: profileBody{Name: u.Body.Name, Username: u.Body.Username},
+	}
+}
@@ -0,0 +1,229 @@
+package registry
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+// RetryPolicy controls how requests to the registry are timed out and
+// retried, and when the registry is treated as unavailable.
+type RetryPolicy struct {
+	// Timeout is how long each attempt at a request may take.
+	Timeout time.Duration
+
+	// MaxRetries is how many times a failed idempotent request is retried.
+	MaxRetries int
+
+	// Backoff is the longest wait before the first retry. It doubles for
+	// each retry after that, up to MaxBackoff. The actual wait is a random
+	// duration up to that limit.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// After BreakerThreshold consecutive failures, requests fail without
+	// being attempted until BreakerCooldown has passed. A single request is
+	// then let through; if it succeeds, requests are attempted again. A zero
+	// BreakerThreshold disables the breaker.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultRetryPolicy is used when no RetryPolicy is given.
+var DefaultRetryPolicy = RetryPolicy{
+	Timeout:          6 * time.Second,
+	MaxRetries:       3,
+	Backoff:          250 * time.Millisecond,
+	MaxBackoff:       5 * time.Second,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// backoff returns a random duration to wait before the given retry, from 1,
+// using full jitter.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	limit := p.Backoff
+	for i := 1; i < retry && limit < p.MaxBackoff; i++ {
+		limit *= 2
+	}
+	if limit > p.MaxBackoff {
+		limit = p.MaxBackoff
+	}
+	if limit <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(limit) + 1))
+}
+
+// isIdempotent returns whether requests with the given method can be safely
+// sent more than once.
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryableStatus returns whether a response with the given status code
+// indicates a transient failure.
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the wait requested by a response's Retry-After header,
+// given either in seconds or as a date, and whether there was one.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	raw := resp.Header.Get("Retry-After")
+	if raw == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(raw); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(raw); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// circuitBreaker fails requests fast once the registry has failed too many
+// times in a row, until its cooldown has passed.
+type circuitBreaker struct {
+	mutex sync.Mutex
+
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow returns whether a request may be attempted. Once the cooldown of an
+// open breaker has passed, a single request is allowed through to probe the
+// registry.
+func (b *circuitBreaker) Allow() bool {
+	if b == nil || b.threshold <= 0 {
+		return true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// Success records a request that reached the registry, closing the breaker.
+func (b *circuitBreaker) Success() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records a request that failed because of the registry, opening the
+// breaker once the threshold is reached.
+func (b *circuitBreaker) Failure() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
+
+// Cancel records a request that was abandoned before it completed, so it
+// counts as neither a success nor a failure.
+func (b *circuitBreaker) Cancel() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.probing = false
+}
+
+func unavailableError() error {
+	return &apitypes.Error{
+		StatusCode: http.StatusServiceUnavailable,
+		Type:       "registry_unavailable",
+		Err:        []string{"The registry is unavailable, please try again later"},
+	}
+}
+
+func timeoutError() error {
+	return &apitypes.Error{
+		StatusCode: http.StatusRequestTimeout,
+		Type:       "request_timeout",
+		Err:        []string{"Request timed out"},
+	}
+}
+
+// wait blocks for d, or until ctx is done.
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
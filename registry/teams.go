@@ -103,3 +103,30 @@ func (t *TeamsClient) Create(ctx context.Context, orgID *identity.ID, name strin
 	_, err = t.client.Do(ctx, req, teamResult)
 	return teamResult, err
 }
+
+// Delete deletes the team with the given id, along with its memberships
+func (t *TeamsClient) Delete(ctx context.Context, teamID *identity.ID) error {
+	req, err := t.client.NewRequest("DELETE", "/teams/"+teamID.String(), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.Do(ctx, req, nil)
+	return err
+}
+
+// Rename changes the name of the team with the given id
+func (t *TeamsClient) Rename(ctx context.Context, teamID *identity.ID, name string) (*envelope.Team, error) {
+	delta := struct {
+		Name string `json:"name"`
+	}{Name: name}
+
+	req, err := t.client.NewRequest("PATCH", "/teams/"+teamID.String(), nil, &delta)
+	if err != nil {
+		return nil, err
+	}
+
+	team := &envelope.Team{}
+	_, err = t.client.Do(ctx, req, team)
+	return team, err
+}
@@ -167,6 +167,8 @@ func (pp *primitiveParser) gatherFields(reachable map[string]*data, s *ast.Struc
 				version = 1
 			case "v2Schema":
 				version = 2
+			case "v3Schema":
+				version = 3
 			}
 			embedded := pp.structmap[typeName]
 			embImmutable, embVersion, embeddedFields := pp.gatherFields(reachable, embedded, visible || immutable)